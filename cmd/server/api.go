@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/web"
+)
+
+// registerAPIRoutes подключает REST API /api/v1. Все методы, кроме выдачи токена
+// и описания OpenAPI, требуют заголовка Authorization: Bearer <token>.
+func registerAPIRoutes(r *gin.Engine, dbConn *sql.DB) {
+	v1 := r.Group("/api/v1")
+	v1.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", web.OpenAPISpec)
+	})
+	v1.POST("/auth/token", func(c *gin.Context) {
+		handlers.CreateAPITokenHandler(c, dbConn)
+	})
+	v1.POST("/auth/token/totp", func(c *gin.Context) {
+		handlers.CreateAPITokenTOTPHandler(c, dbConn)
+	})
+	v1.POST("/auth/refresh", func(c *gin.Context) {
+		handlers.RefreshAPITokenHandler(c, dbConn)
+	})
+	v1.POST("/auth/revoke", func(c *gin.Context) {
+		handlers.RevokeAPITokenHandler(c, dbConn)
+	})
+	v1.POST("/auth/password-reset", func(c *gin.Context) {
+		handlers.RequestPasswordResetAPIHandler(c, dbConn)
+	})
+	v1.POST("/auth/password-reset/confirm", func(c *gin.Context) {
+		handlers.ResetPasswordAPIHandler(c, dbConn)
+	})
+
+	api := v1.Group("/")
+	api.Use(middleware.Authenticate(dbConn))
+	{
+		api.GET("/schedule", func(c *gin.Context) {
+			handlers.ListScheduleAPIHandler(c, dbConn)
+		})
+		api.GET("/schedule/:id", func(c *gin.Context) {
+			handlers.GetScheduleJSON(c, dbConn)
+		})
+		api.GET("/groups", func(c *gin.Context) {
+			handlers.ListGroupsAPIHandler(c, dbConn)
+		})
+		api.GET("/teachers", func(c *gin.Context) {
+			handlers.ListTeachersAPIHandler(c, dbConn)
+		})
+		api.GET("/classrooms", func(c *gin.Context) {
+			handlers.ListClassroomsAPIHandler(c, dbConn)
+		})
+		api.GET("/subjects", func(c *gin.Context) {
+			handlers.ListSubjectsAPIHandler(c, dbConn)
+		})
+		api.GET("/departments", func(c *gin.Context) {
+			handlers.ListDepartmentsAPIHandler(c, dbConn)
+		})
+		api.GET("/requests", func(c *gin.Context) {
+			handlers.ListRequestsAPIHandler(c, dbConn)
+		})
+		api.POST("/requests", func(c *gin.Context) {
+			handlers.CreateRequestHandler(c, dbConn)
+		})
+		api.GET("/requests/:id", func(c *gin.Context) {
+			handlers.GetRequestAPIHandler(c, dbConn)
+		})
+		api.POST("/requests/:id/comments", func(c *gin.Context) {
+			handlers.CreateRequestCommentAPIHandler(c, dbConn)
+		})
+		api.GET("/comments", func(c *gin.Context) {
+			handlers.ListCommentsAPIHandler(c, dbConn)
+		})
+		api.GET("/sessions", func(c *gin.Context) {
+			handlers.ListSessionsAPIHandler(c, dbConn)
+		})
+		api.DELETE("/sessions", func(c *gin.Context) {
+			handlers.RevokeAllSessionsAPIHandler(c, dbConn)
+		})
+		api.DELETE("/sessions/:id", func(c *gin.Context) {
+			handlers.RevokeSessionAPIHandler(c, dbConn)
+		})
+	}
+
+	admin := v1.Group("/")
+	admin.Use(middleware.Authenticate(dbConn), middleware.RoleMiddleware("admin"))
+	{
+		admin.POST("/schedule", func(c *gin.Context) {
+			handlers.CreateScheduleHandler(c, dbConn)
+		})
+		admin.PUT("/schedule/:id", func(c *gin.Context) {
+			handlers.UpdateScheduleHandler(c, dbConn)
+		})
+		admin.DELETE("/schedule/:id", func(c *gin.Context) {
+			handlers.DeleteScheduleAPIHandler(c, dbConn)
+		})
+		admin.POST("/subjects", func(c *gin.Context) {
+			handlers.SaveSubjectAPIHandler(c, dbConn)
+		})
+		admin.PUT("/subjects/:id", func(c *gin.Context) {
+			handlers.SaveSubjectAPIHandler(c, dbConn)
+		})
+		admin.DELETE("/subjects/:id", func(c *gin.Context) {
+			handlers.DeleteSubjectAPIHandler(c, dbConn)
+		})
+		admin.POST("/classrooms", func(c *gin.Context) {
+			handlers.SaveClassroomAPIHandler(c, dbConn)
+		})
+		admin.PUT("/classrooms/:id", func(c *gin.Context) {
+			handlers.SaveClassroomAPIHandler(c, dbConn)
+		})
+		admin.DELETE("/classrooms/:id", func(c *gin.Context) {
+			handlers.DeleteClassroomAPIHandler(c, dbConn)
+		})
+		admin.POST("/groups", func(c *gin.Context) {
+			handlers.SaveGroupAPIHandler(c, dbConn)
+		})
+		admin.PUT("/groups/:id", func(c *gin.Context) {
+			handlers.SaveGroupAPIHandler(c, dbConn)
+		})
+		admin.DELETE("/groups/:id", func(c *gin.Context) {
+			handlers.DeleteGroupAPIHandler(c, dbConn)
+		})
+		admin.POST("/departments", func(c *gin.Context) {
+			handlers.SaveDepartmentAPIHandler(c, dbConn)
+		})
+		admin.PUT("/departments/:id", func(c *gin.Context) {
+			handlers.SaveDepartmentAPIHandler(c, dbConn)
+		})
+		admin.DELETE("/departments/:id", func(c *gin.Context) {
+			handlers.DeleteDepartmentAPIHandler(c, dbConn)
+		})
+		admin.DELETE("/users/:id/sessions", func(c *gin.Context) {
+			handlers.RevokeUserSessionsAPIHandler(c, dbConn)
+		})
+		admin.DELETE("/users/:id/two-factor", func(c *gin.Context) {
+			handlers.ResetUserTwoFactorAPIHandler(c, dbConn)
+		})
+		admin.GET("/requests/all", func(c *gin.Context) {
+			handlers.GetAllRequestsHandler(c, dbConn)
+		})
+		admin.POST("/requests/:id/approve", func(c *gin.Context) {
+			handlers.ProcessRequestAPIHandler(c, dbConn, "approve")
+		})
+		admin.POST("/requests/:id/reject", func(c *gin.Context) {
+			handlers.ProcessRequestAPIHandler(c, dbConn, "reject")
+		})
+	}
+
+	teacher := v1.Group("/")
+	teacher.Use(middleware.Authenticate(dbConn), middleware.RoleMiddleware("teacher"))
+	{
+		teacher.POST("/comments", func(c *gin.Context) {
+			handlers.CreateCommentAPIHandler(c, dbConn)
+		})
+	}
+
+	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			handlers.APINotFoundHandler(c)
+			return
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+	})
+}
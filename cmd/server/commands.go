@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/db"
+	"scheduleApp/internal/handlers"
+)
+
+const usage = `Использование:
+  scheduleApp                      запуск веб-сервера (миграции применяются автоматически)
+  scheduleApp migrate up           применить все новые миграции
+  scheduleApp migrate down [N]     откатить N последних миграций (по умолчанию 1)
+  scheduleApp migrate status       показать состояние миграций
+  scheduleApp import [--from ДАТА --to ДАТА] [--commit] ФАЙЛ
+                                   проверить файл CSV/XLSX с расписанием; с --commit — импортировать
+  scheduleApp user create --username ИМЯ --role student|teacher|admin [--password П]
+                          [--email E] [--name ФИО] [--group ГРУППА] [--department КАФЕДРА]
+                                   создать учётную запись; без --password пароль генерируется
+  scheduleApp user reset-password --username ИМЯ [--password П]
+                                   задать новый пароль; без --password он генерируется
+  scheduleApp user promote ИМЯ     назначить пользователю роль администратора
+  scheduleApp seed ФАЙЛ            добавить или обновить группы, предметы, аудитории, кафедры
+                                   и звонки из файла YAML/JSON
+  scheduleApp demo [--week ДАТА]   заполнить базу демонстрационными данными
+
+Команды user, seed и demo перед выполнением применяют новые миграции.`
+
+func runCommand(dbConn *sql.DB, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(dbConn, args[1:])
+	case "import":
+		return runImport(dbConn, args[1:])
+	case "user":
+		return withMigrations(dbConn, func() error { return runUser(dbConn, args[1:]) })
+	case "seed":
+		return withMigrations(dbConn, func() error { return runSeed(dbConn, args[1:]) })
+	case "demo":
+		return withMigrations(dbConn, func() error { return runDemo(dbConn, args[1:]) })
+	default:
+		return fmt.Errorf("неизвестная команда %q\n%s", args[0], usage)
+	}
+}
+
+func runMigrate(dbConn *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указано действие\n%s", usage)
+	}
+
+	switch args[0] {
+	case "up":
+		return db.MigrateUp(dbConn)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("неверное число шагов: %q", args[1])
+			}
+			steps = n
+		}
+		return db.MigrateDown(dbConn, steps)
+	case "status":
+		statuses, err := db.GetMigrationStatus(dbConn)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			if st.Applied {
+				fmt.Printf("%04d_%-30s применена %s\n", st.Version, st.Name, st.AppliedAt.Format("02.01.2006 15:04:05"))
+			} else {
+				fmt.Printf("%04d_%-30s ожидает\n", st.Version, st.Name)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестное действие migrate %q\n%s", args[0], usage)
+	}
+}
+
+func runImport(dbConn *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	from := fs.String("from", "", "начало периода для строк с днём недели (2006-01-02)")
+	to := fs.String("to", "", "конец периода для строк с днём недели (2006-01-02)")
+	commit := fs.Bool("commit", false, "сохранить занятия, если в файле нет ошибок")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("укажите один файл для импорта\n%s", usage)
+	}
+
+	var period handlers.ImportPeriod
+	var err error
+	if *from != "" {
+		if period.From, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("неверная дата --from: %q", *from)
+		}
+	}
+	if *to != "" {
+		if period.To, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("неверная дата --to: %q", *to)
+		}
+	}
+
+	path := fs.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rows, err := handlers.ParseTimetableFile(path, f)
+	if err != nil {
+		return err
+	}
+	report, err := handlers.ImportTimetable(dbConn, rows, period, *commit)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range report.Rows {
+		if len(r.Errors) > 0 {
+			fmt.Printf("строка %d: ОШИБКА: %s\n", r.Row.Line, strings.Join(r.Errors, "; "))
+		} else {
+			fmt.Printf("строка %d: %s, %s — занятий: %d\n", r.Row.Line, r.Row.Subject, r.Row.Groups, r.Lessons)
+		}
+	}
+	switch {
+	case report.Committed:
+		fmt.Printf("Импортировано занятий: %d\n", report.LessonCount)
+	case report.ErrorCount > 0:
+		return fmt.Errorf("строк с ошибками: %d, ничего не сохранено", report.ErrorCount)
+	default:
+		fmt.Printf("Проверка пройдена, занятий к импорту: %d. Запустите с --commit, чтобы сохранить.\n", report.LessonCount)
+	}
+	return nil
+}
+
+// withMigrations применяет новые миграции перед командой, которой нужна актуальная схема.
+func withMigrations(dbConn *sql.DB, run func() error) error {
+	if err := db.MigrateUp(dbConn); err != nil {
+		return fmt.Errorf("ошибка применения миграций: %v", err)
+	}
+	return run()
+}
+
+// generatePassword возвращает случайный пароль для новой или сброшенной учётной записи.
+func generatePassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func runUser(dbConn *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указано действие\n%s", usage)
+	}
+
+	switch args[0] {
+	case "create":
+		var a handlers.NewAccount
+		fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+		fs.StringVar(&a.Username, "username", "", "имя пользователя для входа")
+		fs.StringVar(&a.Password, "password", "", "пароль (по умолчанию генерируется)")
+		fs.StringVar(&a.Email, "email", "", "адрес электронной почты")
+		fs.StringVar(&a.Role, "role", "student", "роль: student, teacher или admin")
+		fs.StringVar(&a.Name, "name", "", "ФИО студента или преподавателя")
+		fs.StringVar(&a.Group, "group", "", "название группы студента")
+		fs.StringVar(&a.Department, "department", "", "название кафедры преподавателя")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		generated := a.Password == ""
+		if generated {
+			var err error
+			if a.Password, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+		id, err := handlers.CreateAccount(dbConn, a)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Создан пользователь %s (id %d, роль %s)\n", a.Username, id, a.Role)
+		if generated {
+			fmt.Printf("Пароль: %s\n", a.Password)
+		}
+		return nil
+	case "reset-password":
+		fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+		username := fs.String("username", "", "имя пользователя")
+		password := fs.String("password", "", "новый пароль (по умолчанию генерируется)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *username == "" {
+			return fmt.Errorf("укажите --username\n%s", usage)
+		}
+		generated := *password == ""
+		if generated {
+			var err error
+			if *password, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+		if err := handlers.SetAccountPassword(dbConn, *username, *password); err != nil {
+			return err
+		}
+		fmt.Printf("Пароль пользователя %s изменён\n", *username)
+		if generated {
+			fmt.Printf("Новый пароль: %s\n", *password)
+		}
+		return nil
+	case "promote":
+		if len(args) != 2 {
+			return fmt.Errorf("укажите одного пользователя\n%s", usage)
+		}
+		if err := handlers.PromoteToAdmin(dbConn, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Пользователь %s теперь администратор\n", args[1])
+		return nil
+	default:
+		return fmt.Errorf("неизвестное действие user %q\n%s", args[0], usage)
+	}
+}
+
+func runSeed(dbConn *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("укажите один файл со справочниками\n%s", usage)
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := handlers.ParseSeedFile(args[0], f)
+	if err != nil {
+		return err
+	}
+	report, err := handlers.SeedReferenceData(dbConn, data)
+	if err != nil {
+		return err
+	}
+	printSeedReport(report)
+	return nil
+}
+
+func printSeedReport(report handlers.SeedReport) {
+	kinds := make(map[string]bool)
+	for k := range report.Created {
+		kinds[k] = true
+	}
+	for k := range report.Updated {
+		kinds[k] = true
+	}
+	var names []string
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Printf("%s: добавлено %d, обновлено %d\n", k, report.Created[k], report.Updated[k])
+	}
+}
+
+func runDemo(dbConn *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	week := fs.String("week", "", "любая дата недели с демонстрационными занятиями (по умолчанию текущая)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	day := time.Now()
+	if *week != "" {
+		var err error
+		if day, err = time.Parse("2006-01-02", *week); err != nil {
+			return fmt.Errorf("неверная дата --week: %q", *week)
+		}
+	}
+	weekStart := calendar.MondayOf(day)
+
+	report, err := handlers.SeedDemoData(dbConn, weekStart)
+	if err != nil {
+		return err
+	}
+	printSeedReport(report.Reference)
+	if len(report.Accounts) > 0 {
+		fmt.Printf("Созданы учётные записи (пароль %q): %s\n", handlers.DemoPassword, strings.Join(report.Accounts, ", "))
+	}
+	if report.Lessons > 0 {
+		fmt.Printf("Занятий на неделю с %s: %d\n", weekStart.Format("02.01.2006"), report.Lessons)
+	} else {
+		fmt.Printf("На неделе с %s уже есть занятия, новые не добавлены\n", weekStart.Format("02.01.2006"))
+	}
+	return nil
+}
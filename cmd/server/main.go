@@ -4,11 +4,13 @@ import (
 	"embed"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/gin-gonic/gin"
 
 	"scheduleApp/internal/db"
 	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/mailer"
 	"scheduleApp/internal/middleware"
 	"scheduleApp/internal/web"
 )
@@ -26,14 +28,34 @@ func main() {
 	}
 	defer dbConn.Close()
 
-	if err := db.CreateTables(dbConn); err != nil {
-		log.Fatalf("Ошибка при создании таблиц: %v", err)
+	if len(os.Args) > 1 {
+		if err := runCommand(dbConn, os.Args[1:]); err != nil {
+			log.Fatalf("Ошибка: %v", err)
+		}
+		return
+	}
+
+	if err := db.MigrateUp(dbConn); err != nil {
+		log.Fatalf("Ошибка применения миграций: %v", err)
+	}
+	if err := middleware.LoadAuthConfig(); err != nil {
+		log.Fatalf("Ошибка настройки токенов: %v", err)
+	}
+	m, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("Ошибка настройки почты: %v", err)
+	}
+	if err := handlers.ConfigureMail(m, os.Getenv("APP_BASE_URL")); err != nil {
+		log.Fatalf("Ошибка настройки почты: %v", err)
 	}
 
 	web.InitTemplates()
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.Default()
+	if err := middleware.SetTrustedProxies(r); err != nil {
+		log.Fatalf("Ошибка настройки TRUSTED_PROXIES: %v", err)
+	}
 	r.SetHTMLTemplate(web.Tmpl)
 
 	r.GET("/", func(c *gin.Context) {
@@ -47,27 +69,84 @@ func main() {
 	r.POST("/login", func(c *gin.Context) {
 		handlers.LoginFormHandler(c, dbConn)
 	})
+	r.POST("/login/totp", func(c *gin.Context) {
+		handlers.LoginTOTPHandler(c, dbConn)
+	})
 	r.GET("/register", func(c *gin.Context) {
 		handlers.RenderRegisterPage(c, dbConn)
 	})
 	r.POST("/register", func(c *gin.Context) {
 		handlers.RegisterFormHandler(c, dbConn)
 	})
+	r.GET("/verify-email", func(c *gin.Context) {
+		handlers.VerifyEmailHandler(c, dbConn)
+	})
+	r.GET("/forgot-password", func(c *gin.Context) {
+		handlers.RenderForgotPasswordPage(c)
+	})
+	r.POST("/forgot-password", func(c *gin.Context) {
+		handlers.ForgotPasswordHandler(c, dbConn)
+	})
+	r.GET("/reset-password", func(c *gin.Context) {
+		handlers.RenderResetPasswordPage(c, dbConn)
+	})
+	r.POST("/reset-password", func(c *gin.Context) {
+		handlers.ResetPasswordHandler(c, dbConn)
+	})
+
+	// Ленты iCalendar открываются приложениями календаря без входа в систему:
+	// личная защищена секретным токеном в адресе, ленты групп и аудиторий общедоступны.
+	r.GET("/ical/user/:file", func(c *gin.Context) {
+		handlers.UserCalendarFeedHandler(c, dbConn)
+	})
+	r.GET("/ical/group/:file", func(c *gin.Context) {
+		handlers.GroupCalendarFeedHandler(c, dbConn)
+	})
+	r.GET("/ical/classroom/:file", func(c *gin.Context) {
+		handlers.ClassroomCalendarFeedHandler(c, dbConn)
+	})
 
 	user := r.Group("/")
-	user.Use(middleware.AuthMiddleware)
+	user.Use(middleware.Authenticate(dbConn))
 	{
 		user.GET("/logout", func(c *gin.Context) {
-			handlers.LogoutHandler(c)
+			handlers.LogoutHandler(c, dbConn)
+		})
+		user.GET("/sessions", func(c *gin.Context) {
+			handlers.RenderSessionsPage(c, dbConn)
+		})
+		user.POST("/sessions/revoke-all", func(c *gin.Context) {
+			handlers.RevokeAllSessionsHandler(c, dbConn)
+		})
+		user.POST("/sessions/:id/revoke", func(c *gin.Context) {
+			handlers.RevokeSessionHandler(c, dbConn)
+		})
+		user.GET("/two-factor", func(c *gin.Context) {
+			handlers.RenderTwoFactorPage(c, dbConn)
+		})
+		user.POST("/two-factor/setup", func(c *gin.Context) {
+			handlers.SetupTwoFactorHandler(c, dbConn)
+		})
+		user.POST("/two-factor/enable", func(c *gin.Context) {
+			handlers.EnableTwoFactorHandler(c, dbConn)
+		})
+		user.POST("/two-factor/recovery-codes", func(c *gin.Context) {
+			handlers.RegenerateRecoveryCodesHandler(c, dbConn)
+		})
+		user.POST("/two-factor/disable", func(c *gin.Context) {
+			handlers.DisableTwoFactorHandler(c, dbConn)
 		})
 	}
 
 	student := r.Group("/student")
-	student.Use(middleware.AuthMiddleware, middleware.RoleMiddleware("student"))
+	student.Use(middleware.Authenticate(dbConn), middleware.RoleMiddleware("student"))
 	{
 		student.GET("/comments", func(c *gin.Context) {
 			handlers.RenderStudentComments(c, dbConn)
 		})
+		student.POST("/calendar-token", func(c *gin.Context) {
+			handlers.ResetCalendarTokenHandler(c, dbConn)
+		})
 		student.GET("/schedules", func(c *gin.Context) {
 			handlers.RenderStudentSchedule(c, dbConn)
 		})
@@ -75,14 +154,19 @@ func main() {
 			handlers.RenderUserRequestsPage(c, dbConn)
 		})
 		student.POST("/requests", func(c *gin.Context) {
-			handlers.CreateRequestHandler(c, dbConn)
-			c.Redirect(http.StatusSeeOther, "/user/requests")
+			handlers.CreateUserRequestFormHandler(c, dbConn)
+		})
+		student.POST("/requests/:id/comments", func(c *gin.Context) {
+			handlers.AddRequestCommentHandler(c, dbConn)
+		})
+		student.POST("/requests/:id/withdraw", func(c *gin.Context) {
+			handlers.WithdrawRequestHandler(c, dbConn)
 		})
 	}
 
 	// Группа для админа
 	admin := r.Group("/admin")
-	admin.Use(middleware.AuthMiddleware, middleware.RoleMiddleware("admin"))
+	admin.Use(middleware.Authenticate(dbConn), middleware.RoleMiddleware("admin"))
 	{
 
 		admin.GET("/schedules", func(c *gin.Context) {
@@ -101,16 +185,34 @@ func main() {
 			}
 			c.Redirect(http.StatusSeeOther, "/admin/schedules")
 		})
+		admin.POST("/series", func(c *gin.Context) {
+			handlers.CreateLessonSeriesFormHandler(c, dbConn)
+			c.Redirect(http.StatusSeeOther, "/admin/schedules")
+		})
+		admin.POST("/series/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteLessonSeriesHandler(c, dbConn)
+			}
+			c.Redirect(http.StatusSeeOther, "/admin/schedules")
+		})
 		admin.GET("/schedules/:id/json", func(c *gin.Context) {
 			handlers.GetScheduleJSON(c, dbConn)
 		})
+		admin.GET("/schedules/export", func(c *gin.Context) {
+			handlers.ExportSchedulesHandler(c, dbConn)
+		})
 		admin.GET("/requests", func(c *gin.Context) {
 			handlers.RenderAdminRequestsPage(c, dbConn)
 		})
+		admin.POST("/requests/bulk", func(c *gin.Context) {
+			handlers.BulkProcessRequestsHandler(c, dbConn, c.Query("_action"))
+		})
+		admin.POST("/requests/:id/comments", func(c *gin.Context) {
+			handlers.AddRequestCommentHandler(c, dbConn)
+		})
 		admin.POST("/requests/:id", func(c *gin.Context) {
 			action := c.Query("_action")
 			handlers.ProcessRequestFormHandler(c, dbConn, action)
-			c.Redirect(http.StatusSeeOther, "/admin/requests")
 		})
 		admin.GET("/users", func(c *gin.Context) {
 			handlers.RenderManageUserRolesPage(c, dbConn)
@@ -118,21 +220,157 @@ func main() {
 		admin.POST("/users/:id", func(c *gin.Context) {
 			handlers.UpdateUserRoleHandler(c, dbConn)
 		})
+		admin.POST("/users/:id/review", func(c *gin.Context) {
+			action := c.Query("_action")
+			handlers.ReviewAccountHandler(c, dbConn, action)
+		})
+		admin.POST("/users/:id/department", func(c *gin.Context) {
+			handlers.UpdateTeacherDepartmentHandler(c, dbConn)
+		})
 		admin.POST("/users/:id/group", func(c *gin.Context) {
 			handlers.UpdateStudentGroupHandler(c, dbConn)
 		})
+		admin.POST("/users/:id/sessions/revoke", func(c *gin.Context) {
+			handlers.RevokeUserSessionsHandler(c, dbConn)
+		})
+		admin.POST("/users/:id/two-factor/reset", func(c *gin.Context) {
+			handlers.ResetUserTwoFactorHandler(c, dbConn)
+		})
+		admin.POST("/two-factor-policy", func(c *gin.Context) {
+			handlers.UpdateTwoFactorPolicyHandler(c, dbConn)
+		})
+		admin.POST("/login-lockouts/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.ClearLoginLockoutHandler(c, dbConn)
+			}
+		})
+		admin.GET("/calendar", func(c *gin.Context) {
+			handlers.RenderAdminCalendarPage(c, dbConn)
+		})
+		admin.POST("/calendar/terms", func(c *gin.Context) {
+			handlers.CreateAcademicTermHandler(c, dbConn)
+		})
+		admin.POST("/calendar/terms/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteAcademicTermHandler(c, dbConn)
+			}
+		})
+		admin.POST("/calendar/bells", func(c *gin.Context) {
+			handlers.CreateBellPeriodHandler(c, dbConn)
+		})
+		admin.POST("/calendar/bells/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteBellPeriodHandler(c, dbConn)
+			}
+		})
+		admin.POST("/calendar/periods", func(c *gin.Context) {
+			handlers.CreateCalendarPeriodHandler(c, dbConn)
+		})
+		admin.POST("/calendar/periods/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteCalendarPeriodHandler(c, dbConn)
+			}
+		})
+		admin.GET("/reference", func(c *gin.Context) {
+			handlers.RenderAdminReferencePage(c, dbConn)
+		})
+		admin.POST("/subjects", func(c *gin.Context) {
+			handlers.SaveSubjectFormHandler(c, dbConn)
+		})
+		admin.POST("/subjects/:id", func(c *gin.Context) {
+			switch c.Query("_method") {
+			case "PUT":
+				handlers.SaveSubjectFormHandler(c, dbConn)
+			case "DELETE":
+				handlers.DeleteSubjectFormHandler(c, dbConn)
+			}
+		})
+		admin.POST("/classrooms", func(c *gin.Context) {
+			handlers.SaveClassroomFormHandler(c, dbConn)
+		})
+		admin.POST("/classrooms/:id", func(c *gin.Context) {
+			switch c.Query("_method") {
+			case "PUT":
+				handlers.SaveClassroomFormHandler(c, dbConn)
+			case "DELETE":
+				handlers.DeleteClassroomFormHandler(c, dbConn)
+			}
+		})
+		admin.POST("/groups", func(c *gin.Context) {
+			handlers.SaveGroupFormHandler(c, dbConn)
+		})
+		admin.POST("/groups/:id", func(c *gin.Context) {
+			switch c.Query("_method") {
+			case "PUT":
+				handlers.SaveGroupFormHandler(c, dbConn)
+			case "DELETE":
+				handlers.DeleteGroupFormHandler(c, dbConn)
+			}
+		})
+		admin.POST("/departments", func(c *gin.Context) {
+			handlers.SaveDepartmentFormHandler(c, dbConn)
+		})
+		admin.POST("/departments/:id", func(c *gin.Context) {
+			switch c.Query("_method") {
+			case "PUT":
+				handlers.SaveDepartmentFormHandler(c, dbConn)
+			case "DELETE":
+				handlers.DeleteDepartmentFormHandler(c, dbConn)
+			}
+		})
+		admin.GET("/import", func(c *gin.Context) {
+			handlers.RenderAdminImportPage(c, dbConn)
+		})
+		admin.POST("/import", func(c *gin.Context) {
+			handlers.ImportPreviewHandler(c, dbConn)
+		})
+		admin.POST("/import/commit", func(c *gin.Context) {
+			handlers.ImportCommitHandler(c, dbConn)
+		})
+		admin.GET("/generator", func(c *gin.Context) {
+			handlers.RenderAdminGeneratorPage(c, dbConn)
+		})
+		admin.POST("/generator/curriculum", func(c *gin.Context) {
+			handlers.CreateCurriculumItemHandler(c, dbConn)
+		})
+		admin.POST("/generator/curriculum/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteCurriculumItemHandler(c, dbConn)
+			}
+		})
+		admin.POST("/generator/preferences", func(c *gin.Context) {
+			handlers.CreateTeacherPreferenceHandler(c, dbConn)
+		})
+		admin.POST("/generator/preferences/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteTeacherPreferenceHandler(c, dbConn)
+			}
+		})
+		admin.POST("/generator/drafts", func(c *gin.Context) {
+			handlers.GenerateTimetableHandler(c, dbConn)
+		})
+		admin.POST("/generator/drafts/:id", func(c *gin.Context) {
+			if c.Query("_action") == "apply" {
+				handlers.ApplyTimetableDraftHandler(c, dbConn)
+			} else if c.Query("_method") == "DELETE" {
+				handlers.DeleteTimetableDraftHandler(c, dbConn)
+			}
+		})
 	}
 
 	// Группа для учителя
 	teacher := r.Group("/teacher")
-	teacher.Use(middleware.AuthMiddleware, middleware.RoleMiddleware("teacher"))
+	teacher.Use(middleware.Authenticate(dbConn), middleware.RoleMiddleware("teacher"))
 	{
 		teacher.GET("/logout", func(c *gin.Context) {
-			handlers.LogoutHandler(c)
+			handlers.LogoutHandler(c, dbConn)
 		})
 		teacher.GET("/schedule", func(c *gin.Context) {
 			handlers.RenderTeacherSchedule(c, dbConn)
 		})
+		teacher.POST("/calendar-token", func(c *gin.Context) {
+			handlers.ResetCalendarTokenHandler(c, dbConn)
+		})
 		teacher.GET("/comments", func(c *gin.Context) {
 			handlers.RenderTeacherComments(c, dbConn)
 		})
@@ -144,13 +382,46 @@ func main() {
 		})
 		teacher.POST("/requests", func(c *gin.Context) {
 			handlers.CreateTeacherRequest(c, dbConn)
-			c.Redirect(http.StatusSeeOther, "/teacher/requests")
+		})
+		teacher.POST("/requests/:id/comments", func(c *gin.Context) {
+			handlers.AddRequestCommentHandler(c, dbConn)
+		})
+		teacher.POST("/requests/:id/withdraw", func(c *gin.Context) {
+			handlers.WithdrawRequestHandler(c, dbConn)
+		})
+		teacher.GET("/availability", func(c *gin.Context) {
+			handlers.RenderTeacherAvailabilityPage(c, dbConn)
+		})
+		teacher.POST("/availability", func(c *gin.Context) {
+			handlers.CreateTeacherUnavailabilityHandler(c, dbConn)
+		})
+		teacher.POST("/availability/:id", func(c *gin.Context) {
+			if c.Query("_method") == "DELETE" {
+				handlers.DeleteTeacherUnavailabilityHandler(c, dbConn)
+			}
+		})
+		teacher.GET("/department", func(c *gin.Context) {
+			handlers.RenderDepartmentPage(c, dbConn)
+		})
+		teacher.POST("/department/lessons/:id", func(c *gin.Context) {
+			switch c.Query("_method") {
+			case "PUT":
+				handlers.UpdateDepartmentLessonHandler(c, dbConn)
+			case "DELETE":
+				handlers.DeleteDepartmentLessonHandler(c, dbConn)
+			}
+		})
+		teacher.POST("/department/requests/:id", func(c *gin.Context) {
+			action := c.Query("_action")
+			handlers.ProcessDepartmentRequestHandler(c, dbConn, action)
 		})
 		teacher.GET("/", func(c *gin.Context) {
 			handlers.RenderIndexTeacher(c, dbConn)
 		})
 	}
 
+	registerAPIRoutes(r, dbConn)
+
 	log.Println("Сервер запущен на :8080")
 	r.Run(":8080")
 }
@@ -0,0 +1,90 @@
+package calendar
+
+import (
+	"time"
+
+	"scheduleApp/internal/models"
+)
+
+const (
+	KindHoliday  = "holiday"
+	KindVacation = "vacation"
+	KindExams    = "exams"
+	KindWorkday  = "workday"
+)
+
+var kindNames = map[string]string{
+	KindHoliday:  "Праздник",
+	KindVacation: "Каникулы",
+	KindExams:    "Сессия",
+	KindWorkday:  "Рабочий день (перенос)",
+}
+
+// KindName возвращает название типа записи учебного календаря.
+func KindName(kind string) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return kind
+}
+
+// ValidKind сообщает, известен ли тип записи учебного календаря.
+func ValidKind(kind string) bool {
+	_, ok := kindNames[kind]
+	return ok
+}
+
+// dayOf отбрасывает время, оставляя дату в UTC.
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func covers(p models.CalendarPeriod, d time.Time) bool {
+	d = dayOf(d)
+	return !d.Before(p.StartDate) && !d.After(p.EndDate)
+}
+
+// NonTeachingPeriod возвращает праздник, каникулы или сессию, на которые приходится дата d,
+// либо nil, если в этот день можно проводить занятия. Перенесённый рабочий день
+// отменяет выходной на ту же дату.
+func NonTeachingPeriod(periods []models.CalendarPeriod, d time.Time) *models.CalendarPeriod {
+	var found *models.CalendarPeriod
+	for i := range periods {
+		if !covers(periods[i], d) {
+			continue
+		}
+		if periods[i].Kind == KindWorkday {
+			return nil
+		}
+		if found == nil {
+			found = &periods[i]
+		}
+	}
+	return found
+}
+
+// TransferTarget возвращает дату, на которую перенесены занятия дня d, если для d задан
+// перенос рабочего дня.
+func TransferTarget(periods []models.CalendarPeriod, d time.Time) (time.Time, bool) {
+	d = dayOf(d)
+	for _, p := range periods {
+		if p.Kind == KindWorkday && p.TransferFrom.Equal(d) {
+			return p.StartDate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// DayNote возвращает подпись для дня в расписании: «Праздник: День народного единства»
+// или «Рабочий день (перенос) за 03.11.2025». Для обычного учебного дня — пустая строка.
+func DayNote(periods []models.CalendarPeriod, d time.Time) string {
+	for _, p := range periods {
+		if p.Kind == KindWorkday && covers(p, d) {
+			return KindName(p.Kind) + " за " + p.TransferFrom.Format("02.01.2006")
+		}
+	}
+	if p := NonTeachingPeriod(periods, d); p != nil {
+		return KindName(p.Kind) + ": " + p.Name
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/models"
+)
+
+const (
+	ParityAny  = "any"
+	ParityOdd  = "odd"
+	ParityEven = "even"
+)
+
+// MaxWeekNumber — наибольший номер недели: в году не больше 53 недель.
+const MaxWeekNumber = 53
+
+// MondayOf возвращает понедельник недели, в которую попадает t (время отбрасывается).
+func MondayOf(t time.Time) time.Time {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// WeekNumber возвращает номер учебной недели (с единицы) для даты t относительно
+// начала семестра. Первая неделя — та, в которую попадает termStart; недели считаются
+// с понедельника. Для дат раньше начала семестра возвращает 0.
+func WeekNumber(termStart, t time.Time) int {
+	days := int(MondayOf(t).Sub(MondayOf(termStart)).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days/7 + 1
+}
+
+// IsOddWeek сообщает, является ли неделя с номером n «числителем».
+func IsOddWeek(n int) bool {
+	return n%2 == 1
+}
+
+// ParityName возвращает название чётности недели: числитель или знаменатель.
+func ParityName(n int) string {
+	if IsOddWeek(n) {
+		return "числитель"
+	}
+	return "знаменатель"
+}
+
+// MatchesWeek проверяет, попадает ли неделя n под ограничения серии:
+// чётность (any/odd/even) и, если задан, явный список номеров недель.
+func MatchesWeek(n int, parity string, weekNumbers []int) bool {
+	switch parity {
+	case ParityOdd:
+		if !IsOddWeek(n) {
+			return false
+		}
+	case ParityEven:
+		if IsOddWeek(n) {
+			return false
+		}
+	}
+	if len(weekNumbers) == 0 {
+		return true
+	}
+	for _, w := range weekNumbers {
+		if w == n {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTerm возвращает семестр, в который попадает дата t, или nil.
+func FindTerm(terms []models.AcademicTerm, t time.Time) *models.AcademicTerm {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for i := range terms {
+		if !d.Before(terms[i].StartDate) && !d.After(terms[i].EndDate) {
+			return &terms[i]
+		}
+	}
+	return nil
+}
+
+// WeekLabel формирует подпись вида «3-я неделя, числитель» для даты t
+// или пустую строку, если дата не попадает ни в один семестр.
+func WeekLabel(terms []models.AcademicTerm, t time.Time) string {
+	term := FindTerm(terms, t)
+	if term == nil {
+		return ""
+	}
+	n := WeekNumber(term.StartDate, t)
+	return fmt.Sprintf("%d-я неделя, %s", n, ParityName(n))
+}
+
+// ParseWeekNumbers разбирает список номеров недель вида «1,3,5-9»; номера — от 1
+// до MaxWeekNumber.
+func ParseWeekNumbers(s string) ([]int, error) {
+	var result []int
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		from, to := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			from, to = part[:i], part[i+1:]
+		}
+		a, err1 := strconv.Atoi(from)
+		b, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || a < 1 || b < a {
+			return nil, fmt.Errorf("неверный номер недели: %s", part)
+		}
+		if b > MaxWeekNumber {
+			return nil, fmt.Errorf("неверный номер недели: %s (не больше %d)", part, MaxWeekNumber)
+		}
+		for n := a; n <= b; n++ {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
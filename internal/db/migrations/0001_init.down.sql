@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS requests;
+DROP TABLE IF EXISTS schedule_groups;
+DROP TABLE IF EXISTS schedule;
+DROP TABLE IF EXISTS classrooms;
+DROP TABLE IF EXISTS subjects;
+DROP TABLE IF EXISTS students;
+DROP TABLE IF EXISTS teachers;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    schedule_id INT NOT NULL REFERENCES schedule(id) ON DELETE CASCADE,
+    teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    comment_text TEXT NOT NULL,
+    file_path TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_schedule_series_id;
+ALTER TABLE schedule DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS lesson_series_exceptions;
+DROP TABLE IF EXISTS lesson_series_groups;
+DROP TABLE IF EXISTS lesson_series;
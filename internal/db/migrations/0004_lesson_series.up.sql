@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS lesson_series (
+    id SERIAL PRIMARY KEY,
+    subject_id INT NOT NULL REFERENCES subjects(id) ON DELETE CASCADE,
+    teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    classroom_id INT NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    start_clock TIME NOT NULL,
+    duration_minutes INT NOT NULL DEFAULT 90 CHECK (duration_minutes > 0),
+    interval_weeks INT NOT NULL DEFAULT 1 CHECK (interval_weeks IN (1, 2)),
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE TABLE IF NOT EXISTS lesson_series_groups (
+    series_id INT NOT NULL REFERENCES lesson_series(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (series_id, group_id)
+);
+
+CREATE TABLE IF NOT EXISTS lesson_series_exceptions (
+    series_id INT NOT NULL REFERENCES lesson_series(id) ON DELETE CASCADE,
+    exception_date DATE NOT NULL,
+    PRIMARY KEY (series_id, exception_date)
+);
+
+ALTER TABLE schedule ADD COLUMN IF NOT EXISTS series_id INT REFERENCES lesson_series(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_schedule_series_id ON schedule(series_id);
@@ -0,0 +1,5 @@
+ALTER TABLE lesson_series
+    DROP COLUMN IF EXISTS week_numbers,
+    DROP COLUMN IF EXISTS week_parity;
+
+DROP TABLE IF EXISTS academic_terms;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS academic_terms (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    CHECK (end_date >= start_date)
+);
+
+ALTER TABLE lesson_series
+    ADD COLUMN IF NOT EXISTS week_parity VARCHAR(10) NOT NULL DEFAULT 'any' CHECK (week_parity IN ('any', 'odd', 'even')),
+    ADD COLUMN IF NOT EXISTS week_numbers INT[];
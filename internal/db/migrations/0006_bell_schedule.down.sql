@@ -0,0 +1,3 @@
+ALTER TABLE lesson_series DROP COLUMN IF EXISTS pair_number;
+ALTER TABLE schedule DROP COLUMN IF EXISTS pair_number;
+DROP TABLE IF EXISTS bell_schedule;
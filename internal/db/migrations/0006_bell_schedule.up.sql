@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS bell_schedule (
+    id SERIAL PRIMARY KEY,
+    pair_number INT NOT NULL CHECK (pair_number > 0),
+    building VARCHAR(255) NOT NULL DEFAULT '',
+    start_clock TIME NOT NULL,
+    end_clock TIME NOT NULL,
+    CHECK (end_clock > start_clock),
+    UNIQUE (building, pair_number)
+);
+
+ALTER TABLE schedule ADD COLUMN IF NOT EXISTS pair_number INT;
+ALTER TABLE lesson_series ADD COLUMN IF NOT EXISTS pair_number INT;
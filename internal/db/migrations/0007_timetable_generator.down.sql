@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS timetable_draft_lessons;
+DROP TABLE IF EXISTS timetable_drafts;
+DROP TABLE IF EXISTS teacher_slot_preferences;
+DROP TABLE IF EXISTS curriculum;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS curriculum (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    subject_id INT NOT NULL REFERENCES subjects(id) ON DELETE CASCADE,
+    teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    hours_per_week INT NOT NULL CHECK (hours_per_week > 0),
+    UNIQUE (group_id, subject_id, teacher_id)
+);
+
+CREATE TABLE IF NOT EXISTS teacher_slot_preferences (
+    id SERIAL PRIMARY KEY,
+    teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    weekday INT NOT NULL CHECK (weekday BETWEEN 1 AND 6),
+    pair_number INT NOT NULL CHECK (pair_number > 0),
+    penalty INT NOT NULL DEFAULT 1 CHECK (penalty > 0),
+    UNIQUE (teacher_id, weekday, pair_number)
+);
+
+CREATE TABLE IF NOT EXISTS timetable_drafts (
+    id SERIAL PRIMARY KEY,
+    week_start DATE NOT NULL,
+    placed INT NOT NULL DEFAULT 0,
+    unplaced INT NOT NULL DEFAULT 0,
+    group_gaps INT NOT NULL DEFAULT 0,
+    teacher_preference_penalty INT NOT NULL DEFAULT 0,
+    building_changes INT NOT NULL DEFAULT 0,
+    score INT NOT NULL DEFAULT 0,
+    unplaced_details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    applied_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS timetable_draft_lessons (
+    id SERIAL PRIMARY KEY,
+    draft_id INT NOT NULL REFERENCES timetable_drafts(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    subject_id INT NOT NULL REFERENCES subjects(id) ON DELETE CASCADE,
+    teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    classroom_id INT NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    weekday INT NOT NULL CHECK (weekday BETWEEN 1 AND 6),
+    pair_number INT NOT NULL CHECK (pair_number > 0)
+);
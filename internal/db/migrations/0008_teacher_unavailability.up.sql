@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS teacher_unavailability (
+    id SERIAL PRIMARY KEY,
+    teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    -- Еженедельное окно: день недели (1 — понедельник) и интервал времени.
+    weekday INT CHECK (weekday BETWEEN 1 AND 7),
+    start_clock TIME,
+    end_clock TIME,
+    -- Разовый период: с даты и времени по дату и время.
+    start_at TIMESTAMP,
+    end_at TIMESTAMP,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (
+        (weekday IS NOT NULL AND start_clock IS NOT NULL AND end_clock > start_clock
+            AND start_at IS NULL AND end_at IS NULL)
+        OR
+        (weekday IS NULL AND start_clock IS NULL AND end_clock IS NULL
+            AND start_at IS NOT NULL AND end_at > start_at)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_teacher_unavailability_teacher ON teacher_unavailability(teacher_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS academic_calendar;
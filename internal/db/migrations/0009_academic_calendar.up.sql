@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS academic_calendar (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('holiday', 'vacation', 'exams', 'workday')),
+    name VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL CHECK (end_date >= start_date),
+    -- Для перенесённого рабочего дня: дата, занятия которой проводятся в этот день.
+    transfer_from DATE,
+    CHECK (kind <> 'workday' OR (start_date = end_date AND transfer_from IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_academic_calendar_dates ON academic_calendar(start_date, end_date);
@@ -0,0 +1,11 @@
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_schedule_id_fkey;
+ALTER TABLE requests
+    ADD CONSTRAINT requests_schedule_id_fkey
+        FOREIGN KEY (schedule_id) REFERENCES schedule(id) ON DELETE CASCADE;
+
+ALTER TABLE requests
+    DROP COLUMN IF EXISTS swap_schedule_id,
+    DROP COLUMN IF EXISTS proposed_teacher_id,
+    DROP COLUMN IF EXISTS proposed_classroom_id,
+    DROP COLUMN IF EXISTS proposed_start,
+    DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,20 @@
+-- Структурированные предложения в запросах на изменение расписания.
+-- kind = 'text'      — только текстовое описание (как раньше);
+--        'time'      — перенос на proposed_start (длительность занятия сохраняется);
+--        'classroom' — смена аудитории на proposed_classroom_id;
+--        'teacher'   — замена преподавателя на proposed_teacher_id;
+--        'swap'      — обмен временем и аудиторией с занятием swap_schedule_id;
+--        'cancel'    — отмена занятия.
+ALTER TABLE requests
+    ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'text'
+        CHECK (kind IN ('text', 'time', 'classroom', 'teacher', 'swap', 'cancel')),
+    ADD COLUMN IF NOT EXISTS proposed_start TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS proposed_classroom_id INT REFERENCES classrooms(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS proposed_teacher_id INT REFERENCES teachers(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS swap_schedule_id INT REFERENCES schedule(id) ON DELETE SET NULL;
+
+-- Отменённое по запросу занятие удаляется, а сам запрос должен остаться в истории.
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_schedule_id_fkey;
+ALTER TABLE requests
+    ADD CONSTRAINT requests_schedule_id_fkey
+        FOREIGN KEY (schedule_id) REFERENCES schedule(id) ON DELETE SET NULL;
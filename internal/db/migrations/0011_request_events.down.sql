@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_request_events_request;
+DROP TABLE IF EXISTS request_events;
+ALTER TABLE requests DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,19 @@
+-- Журнал жизненного цикла запросов и обсуждение между автором и администратором.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS request_events (
+    id SERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    event VARCHAR(20) NOT NULL
+        CHECK (event IN ('created', 'commented', 'approved', 'rejected', 'applied', 'withdrawn')),
+    -- Текст комментария, причина отклонения или описание применённого изменения.
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_events_request ON request_events(request_id, created_at);
+
+-- У уже существующих запросов известен только автор.
+INSERT INTO request_events (request_id, actor_id, event)
+SELECT id, user_id, 'created' FROM requests;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_requests_desired_change_fts;
+DROP INDEX IF EXISTS idx_requests_status_created;
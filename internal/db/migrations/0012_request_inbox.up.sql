@@ -0,0 +1,4 @@
+-- Индексы для фильтров и полнотекстового поиска во входящих запросах администратора.
+CREATE INDEX IF NOT EXISTS idx_requests_status_created ON requests(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_requests_desired_change_fts
+    ON requests USING GIN (to_tsvector('russian', COALESCE(desired_change, '')));
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS schedule_cancellation ON schedule;
+DROP FUNCTION IF EXISTS schedule_record_cancellation();
+DROP TRIGGER IF EXISTS schedule_revision ON schedule;
+DROP FUNCTION IF EXISTS schedule_bump_revision();
+DROP TABLE IF EXISTS cancelled_lessons;
+ALTER TABLE schedule DROP COLUMN IF EXISTS revision;
+ALTER TABLE users DROP COLUMN IF EXISTS calendar_token;
@@ -0,0 +1,63 @@
+-- Подписки на расписание в формате iCalendar.
+-- Секретный токен личной ленты; NULL — ссылка ещё не выдавалась.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token VARCHAR(64) UNIQUE;
+
+-- Номер редакции занятия: календари обновляют событие, только если SEQUENCE вырос.
+ALTER TABLE schedule ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 0;
+
+-- Удалённые занятия остаются в лентах как отменённые события (STATUS:CANCELLED),
+-- иначе уже загруженная в телефон копия так и не исчезнет.
+CREATE TABLE IF NOT EXISTS cancelled_lessons (
+    schedule_id INT PRIMARY KEY,
+    subject_name VARCHAR(255) NOT NULL DEFAULT '',
+    teacher_id INT,
+    teacher_name VARCHAR(255) NOT NULL DEFAULT '',
+    classroom_id INT,
+    room_number VARCHAR(50) NOT NULL DEFAULT '',
+    building VARCHAR(255) NOT NULL DEFAULT '',
+    group_ids INT[] NOT NULL DEFAULT '{}',
+    group_names TEXT NOT NULL DEFAULT '',
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    revision INT NOT NULL DEFAULT 0,
+    cancelled_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cancelled_lessons_start ON cancelled_lessons(start_time);
+
+CREATE OR REPLACE FUNCTION schedule_bump_revision() RETURNS trigger AS $$
+BEGIN
+    NEW.revision := OLD.revision + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS schedule_revision ON schedule;
+CREATE TRIGGER schedule_revision BEFORE UPDATE ON schedule
+    FOR EACH ROW EXECUTE FUNCTION schedule_bump_revision();
+
+-- Триггер ловит любое удаление: вручную, серией, по одобренному запросу или каскадом.
+-- Справочники читаются через LEFT JOIN: при каскадном удалении их строки уже удалены.
+CREATE OR REPLACE FUNCTION schedule_record_cancellation() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO cancelled_lessons (schedule_id, subject_name, teacher_id, teacher_name, classroom_id,
+                                   room_number, building, group_ids, group_names, start_time, end_time, revision)
+    SELECT OLD.id,
+           COALESCE((SELECT name FROM subjects WHERE id = OLD.subject_id), ''),
+           OLD.teacher_id,
+           COALESCE((SELECT name FROM teachers WHERE id = OLD.teacher_id), ''),
+           OLD.classroom_id,
+           COALESCE((SELECT room_number FROM classrooms WHERE id = OLD.classroom_id), ''),
+           COALESCE((SELECT building FROM classrooms WHERE id = OLD.classroom_id), ''),
+           COALESCE((SELECT array_agg(group_id ORDER BY group_id) FROM schedule_groups WHERE schedule_id = OLD.id), '{}'),
+           COALESCE((SELECT string_agg(g.name, ', ' ORDER BY g.name) FROM schedule_groups sg
+                     JOIN groups g ON g.id = sg.group_id WHERE sg.schedule_id = OLD.id), ''),
+           OLD.start_time, OLD.end_time, OLD.revision + 1
+    ON CONFLICT (schedule_id) DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS schedule_cancellation ON schedule;
+CREATE TRIGGER schedule_cancellation BEFORE DELETE ON schedule
+    FOR EACH ROW EXECUTE FUNCTION schedule_record_cancellation();
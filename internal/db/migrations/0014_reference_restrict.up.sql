@@ -0,0 +1,21 @@
+-- Удаление предмета, аудитории или группы больше не уносит занятия каскадом:
+-- справочник можно удалить, только когда на него не ссылается расписание.
+ALTER TABLE schedule
+    DROP CONSTRAINT IF EXISTS schedule_subject_id_fkey,
+    ADD CONSTRAINT schedule_subject_id_fkey FOREIGN KEY (subject_id) REFERENCES subjects(id) ON DELETE RESTRICT,
+    DROP CONSTRAINT IF EXISTS schedule_classroom_id_fkey,
+    ADD CONSTRAINT schedule_classroom_id_fkey FOREIGN KEY (classroom_id) REFERENCES classrooms(id) ON DELETE RESTRICT;
+
+ALTER TABLE schedule_groups
+    DROP CONSTRAINT IF EXISTS schedule_groups_group_id_fkey,
+    ADD CONSTRAINT schedule_groups_group_id_fkey FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT;
+
+ALTER TABLE lesson_series
+    DROP CONSTRAINT IF EXISTS lesson_series_subject_id_fkey,
+    ADD CONSTRAINT lesson_series_subject_id_fkey FOREIGN KEY (subject_id) REFERENCES subjects(id) ON DELETE RESTRICT,
+    DROP CONSTRAINT IF EXISTS lesson_series_classroom_id_fkey,
+    ADD CONSTRAINT lesson_series_classroom_id_fkey FOREIGN KEY (classroom_id) REFERENCES classrooms(id) ON DELETE RESTRICT;
+
+ALTER TABLE lesson_series_groups
+    DROP CONSTRAINT IF EXISTS lesson_series_groups_group_id_fkey,
+    ADD CONSTRAINT lesson_series_groups_group_id_fkey FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT;
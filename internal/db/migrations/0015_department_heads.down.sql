@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_teachers_department_id;
+ALTER TABLE departments DROP COLUMN IF EXISTS head_teacher_id;
@@ -0,0 +1,12 @@
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS head_teacher_id INT REFERENCES teachers(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_teachers_department_id ON teachers(department_id);
+
+-- Кафедры, записанные текстом в teachers.department, переносятся в справочник.
+INSERT INTO departments (name)
+SELECT DISTINCT btrim(department) FROM teachers
+WHERE btrim(COALESCE(department, '')) <> ''
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE teachers t SET department_id = d.id
+FROM departments d
+WHERE t.department_id IS NULL AND btrim(t.department) = d.name;
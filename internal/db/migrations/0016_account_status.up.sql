@@ -0,0 +1,4 @@
+-- Самостоятельно зарегистрированные преподаватели и администраторы ждут подтверждения.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'pending', 'rejected'));
+CREATE INDEX IF NOT EXISTS idx_users_pending ON users(created_at) WHERE status = 'pending';
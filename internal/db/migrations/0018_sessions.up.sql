@@ -0,0 +1,18 @@
+-- Сеанс — вход с одного устройства. JWT несёт id сеанса, refresh-токены привязаны к нему;
+-- отзыв сеанса сразу делает недействительными все его токены.
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id) WHERE revoked_at IS NULL;
+
+-- Refresh-токены без сеанса отозвать нельзя, поэтому они удаляются: пользователям
+-- достаточно войти заново.
+DELETE FROM refresh_tokens;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id INT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+-- Неудачные попытки входа по имени пользователя (scope = 'account') и по IP-адресу
+-- (scope = 'ip'). locked_until — до какого момента вход с этим ключом отклоняется.
+CREATE TABLE IF NOT EXISTS login_throttle (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('account', 'ip')),
+    key VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP,
+    UNIQUE (scope, key)
+);
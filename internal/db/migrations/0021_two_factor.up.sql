@@ -0,0 +1,34 @@
+-- Двухфакторная аутентификация по TOTP (RFC 6238). totp_secret без totp_enabled_at —
+-- начатая, но ещё не подтверждённая кодом настройка. totp_last_step — номер последнего
+-- принятого 30-секундного интервала, чтобы один и тот же код нельзя было ввести дважды.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+-- Одноразовые коды восстановления на случай потери телефона; хранится только SHA-256.
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Незавершённые входы: пароль уже проверен, ожидается код из приложения.
+-- Хранится только SHA-256 токена, который получает браузер или клиент API.
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL DEFAULT 0
+);
+
+-- Роли, для которых администратор сделал двухфакторную аутентификацию обязательной.
+CREATE TABLE IF NOT EXISTS two_factor_policy (
+    role VARCHAR(50) PRIMARY KEY CHECK (role IN ('admin', 'teacher')),
+    required BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO two_factor_policy (role) VALUES ('admin'), ('teacher') ON CONFLICT (role) DO NOTHING;
@@ -0,0 +1,21 @@
+CREATE OR REPLACE FUNCTION schedule_record_cancellation() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO cancelled_lessons (schedule_id, subject_name, teacher_id, teacher_name, classroom_id,
+                                   room_number, building, group_ids, group_names, start_time, end_time, revision)
+    SELECT OLD.id,
+           COALESCE((SELECT name FROM subjects WHERE id = OLD.subject_id), ''),
+           OLD.teacher_id,
+           COALESCE((SELECT name FROM teachers WHERE id = OLD.teacher_id), ''),
+           OLD.classroom_id,
+           COALESCE((SELECT room_number FROM classrooms WHERE id = OLD.classroom_id), ''),
+           COALESCE((SELECT building FROM classrooms WHERE id = OLD.classroom_id), ''),
+           COALESCE((SELECT array_agg(group_id ORDER BY group_id) FROM schedule_groups WHERE schedule_id = OLD.id), '{}'),
+           COALESCE((SELECT string_agg(g.name, ', ' ORDER BY g.name) FROM schedule_groups sg
+                     JOIN groups g ON g.id = sg.group_id WHERE sg.schedule_id = OLD.id), ''),
+           OLD.start_time, OLD.end_time, OLD.revision + 1
+    ON CONFLICT (schedule_id) DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS dropped_lesson_groups;
@@ -0,0 +1,36 @@
+-- Группы, снятые с занятия, которое само осталось в расписании. В лентах этих групп
+-- занятие показывается отменённым, иначе уже загруженная копия не исчезнет.
+CREATE TABLE IF NOT EXISTS dropped_lesson_groups (
+    schedule_id INT NOT NULL REFERENCES schedule(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (schedule_id, group_id)
+);
+
+-- Отмена занятия попадает и в ленты групп, снятых с него раньше; их названия тоже
+-- перечисляются в отмене.
+CREATE OR REPLACE FUNCTION schedule_record_cancellation() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO cancelled_lessons (schedule_id, subject_name, teacher_id, teacher_name, classroom_id,
+                                   room_number, building, group_ids, group_names, start_time, end_time, revision)
+    SELECT OLD.id,
+           COALESCE((SELECT name FROM subjects WHERE id = OLD.subject_id), ''),
+           OLD.teacher_id,
+           COALESCE((SELECT name FROM teachers WHERE id = OLD.teacher_id), ''),
+           OLD.classroom_id,
+           COALESCE((SELECT room_number FROM classrooms WHERE id = OLD.classroom_id), ''),
+           COALESCE((SELECT building FROM classrooms WHERE id = OLD.classroom_id), ''),
+           COALESCE((SELECT array_agg(group_id ORDER BY group_id) FROM (
+                         SELECT group_id FROM schedule_groups WHERE schedule_id = OLD.id
+                         UNION
+                         SELECT group_id FROM dropped_lesson_groups WHERE schedule_id = OLD.id
+                     ) ids), '{}'),
+           COALESCE((SELECT string_agg(g.name, ', ' ORDER BY g.name) FROM groups g WHERE g.id IN (
+                         SELECT group_id FROM schedule_groups WHERE schedule_id = OLD.id
+                         UNION
+                         SELECT group_id FROM dropped_lesson_groups WHERE schedule_id = OLD.id
+                     )), ''),
+           OLD.start_time, OLD.end_time, OLD.revision + 1
+    ON CONFLICT (schedule_id) DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
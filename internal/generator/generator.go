@@ -0,0 +1,370 @@
+// Package generator строит черновик недельного расписания по учебному плану.
+//
+// Жёсткие ограничения те же, что проверяет handlers.CheckScheduleCollision: у преподавателя,
+// аудитории и группы не может быть двух занятий в одном слоте; дополнительно аудитория должна
+// вмещать группу. Мягкие ограничения (окна у групп, пожелания преподавателей, переходы между
+// корпусами) сводятся в числовую оценку, которую генератор минимизирует локальным поиском.
+package generator
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Slot — пара pair (с единицы) в день day (0 — понедельник).
+type Slot struct {
+	Day  int
+	Pair int
+}
+
+// Lesson — одно занятие в неделю, которое нужно поставить в расписание.
+type Lesson struct {
+	CurriculumID int
+	GroupID      int
+	SubjectID    int
+	TeacherID    int
+	GroupSize    int
+}
+
+type Room struct {
+	ID       int
+	Capacity int
+	Building string
+}
+
+type Weights struct {
+	GroupGap          int
+	TeacherPreference int
+	BuildingChange    int
+}
+
+var DefaultWeights = Weights{GroupGap: 3, TeacherPreference: 2, BuildingChange: 1}
+
+type Problem struct {
+	Lessons []Lesson
+	Rooms   []Room
+	Days    int
+	// Номера пар, доступные для постановки занятий (по расписанию звонков).
+	Pairs []int
+
+	// Слоты, уже занятые существующими занятиями.
+	BusyTeachers map[int]map[Slot]bool
+	BusyRooms    map[int]map[Slot]bool
+	BusyGroups   map[int]map[Slot]bool
+
+	// Штраф за постановку занятия преподавателя в нежелательный слот.
+	TeacherPenalties map[int]map[Slot]int
+
+	Weights Weights
+}
+
+type Placement struct {
+	Lesson Lesson
+	Slot   Slot
+	RoomID int
+}
+
+type Report struct {
+	Placed                   int `json:"placed"`
+	Unplaced                 int `json:"unplaced"`
+	GroupGaps                int `json:"group_gaps"`
+	TeacherPreferencePenalty int `json:"teacher_preference_penalty"`
+	BuildingChanges          int `json:"building_changes"`
+	Score                    int `json:"score"`
+}
+
+type Result struct {
+	Placements []Placement
+	Unplaced   []Lesson
+	Report     Report
+}
+
+// groupDay — день одной группы: окна и переходы между корпусами считаются в его пределах.
+type groupDay struct{ group, day int }
+
+// state хранит текущую расстановку и индексы занятости для быстрых проверок.
+type state struct {
+	p          *Problem
+	w          Weights
+	rooms      map[int]Room
+	placements []Placement
+	teacherAt  map[int]map[Slot]int
+	roomAt     map[int]map[Slot]int
+	groupAt    map[int]map[Slot]int
+	// Аудитории занятий группы в течение дня по номерам пар — для пересчёта оценки
+	// только затронутого дня.
+	days map[groupDay]map[int]int
+}
+
+func newState(p *Problem) *state {
+	s := &state{
+		p:         p,
+		w:         p.weights(),
+		rooms:     make(map[int]Room, len(p.Rooms)),
+		teacherAt: make(map[int]map[Slot]int),
+		roomAt:    make(map[int]map[Slot]int),
+		groupAt:   make(map[int]map[Slot]int),
+		days:      make(map[groupDay]map[int]int),
+	}
+	for _, r := range p.Rooms {
+		s.rooms[r.ID] = r
+	}
+	return s
+}
+
+func inc(m map[int]map[Slot]int, id int, slot Slot, delta int) {
+	if m[id] == nil {
+		m[id] = make(map[Slot]int)
+	}
+	m[id][slot] += delta
+	if m[id][slot] == 0 {
+		delete(m[id], slot)
+	}
+}
+
+func (s *state) add(pl Placement) {
+	s.placements = append(s.placements, pl)
+	s.index(pl, 1)
+}
+
+func (s *state) index(pl Placement, delta int) {
+	inc(s.teacherAt, pl.Lesson.TeacherID, pl.Slot, delta)
+	inc(s.roomAt, pl.RoomID, pl.Slot, delta)
+	inc(s.groupAt, pl.Lesson.GroupID, pl.Slot, delta)
+
+	key := groupDay{pl.Lesson.GroupID, pl.Slot.Day}
+	if delta > 0 {
+		if s.days[key] == nil {
+			s.days[key] = make(map[int]int)
+		}
+		s.days[key][pl.Slot.Pair] = pl.RoomID
+	} else {
+		delete(s.days[key], pl.Slot.Pair)
+		if len(s.days[key]) == 0 {
+			delete(s.days, key)
+		}
+	}
+}
+
+func (s *state) move(i int, slot Slot, roomID int) {
+	s.index(s.placements[i], -1)
+	s.placements[i].Slot = slot
+	s.placements[i].RoomID = roomID
+	s.index(s.placements[i], 1)
+}
+
+// fits проверяет жёсткие ограничения для занятия l в слоте slot и аудитории room.
+func (s *state) fits(l Lesson, slot Slot, room Room) bool {
+	if room.Capacity > 0 && l.GroupSize > room.Capacity {
+		return false
+	}
+	if s.p.BusyTeachers[l.TeacherID][slot] || s.p.BusyRooms[room.ID][slot] || s.p.BusyGroups[l.GroupID][slot] {
+		return false
+	}
+	return s.teacherAt[l.TeacherID][slot] == 0 && s.roomAt[room.ID][slot] == 0 && s.groupAt[l.GroupID][slot] == 0
+}
+
+func (s *state) slots() []Slot {
+	result := make([]Slot, 0, s.p.Days*len(s.p.Pairs))
+	for d := 0; d < s.p.Days; d++ {
+		for _, pair := range s.p.Pairs {
+			result = append(result, Slot{Day: d, Pair: pair})
+		}
+	}
+	return result
+}
+
+// candidates возвращает все допустимые пары «слот + аудитория» для занятия.
+func (s *state) candidates(l Lesson) []Placement {
+	var result []Placement
+	for _, slot := range s.slots() {
+		for _, room := range s.p.Rooms {
+			if s.fits(l, slot, room) {
+				result = append(result, Placement{Lesson: l, Slot: slot, RoomID: room.ID})
+			}
+		}
+	}
+	return result
+}
+
+// weights возвращает веса оценки; нулевые веса означают DefaultWeights.
+func (p *Problem) weights() Weights {
+	if p.Weights == (Weights{}) {
+		return DefaultWeights
+	}
+	return p.Weights
+}
+
+// Evaluate считает оценку мягких ограничений для готовой расстановки.
+func Evaluate(p Problem, placements []Placement) Report {
+	rooms := make(map[int]Room, len(p.Rooms))
+	for _, r := range p.Rooms {
+		rooms[r.ID] = r
+	}
+
+	var rep Report
+	type groupDay struct{ group, day int }
+	byGroupDay := make(map[groupDay][]Placement)
+	for _, pl := range placements {
+		rep.TeacherPreferencePenalty += p.TeacherPenalties[pl.Lesson.TeacherID][pl.Slot]
+		key := groupDay{pl.Lesson.GroupID, pl.Slot.Day}
+		byGroupDay[key] = append(byGroupDay[key], pl)
+	}
+
+	for _, day := range byGroupDay {
+		sort.Slice(day, func(i, j int) bool { return day[i].Slot.Pair < day[j].Slot.Pair })
+		first, last := day[0].Slot.Pair, day[len(day)-1].Slot.Pair
+		rep.GroupGaps += (last - first + 1) - len(day)
+		for i := 1; i < len(day); i++ {
+			if rooms[day[i].RoomID].Building != rooms[day[i-1].RoomID].Building {
+				rep.BuildingChanges++
+			}
+		}
+	}
+
+	w := p.weights()
+	rep.Placed = len(placements)
+	rep.Score = w.GroupGap*rep.GroupGaps + w.TeacherPreference*rep.TeacherPreferencePenalty + w.BuildingChange*rep.BuildingChanges
+	return rep
+}
+
+// dayCost — взвешенные окна и переходы между корпусами в одном дне группы; совпадает
+// с вкладом этого дня в Evaluate.
+func (s *state) dayCost(key groupDay) int {
+	rooms := s.days[key]
+	if len(rooms) == 0 {
+		return 0
+	}
+	pairs := make([]int, 0, len(rooms))
+	for pair := range rooms {
+		pairs = append(pairs, pair)
+	}
+	sort.Ints(pairs)
+	gaps := (pairs[len(pairs)-1] - pairs[0] + 1) - len(pairs)
+	changes := 0
+	for i := 1; i < len(pairs); i++ {
+		if s.rooms[rooms[pairs[i]]].Building != s.rooms[rooms[pairs[i-1]]].Building {
+			changes++
+		}
+	}
+	return s.w.GroupGap*gaps + s.w.BuildingChange*changes
+}
+
+// addCost — на сколько изменится оценка, если добавить pl к текущей расстановке.
+// Затрагиваются только штраф преподавателя и день группы, поэтому полный Evaluate
+// для каждого кандидата не нужен.
+func (s *state) addCost(pl Placement) int {
+	key := groupDay{pl.Lesson.GroupID, pl.Slot.Day}
+	before := s.dayCost(key)
+	s.index(pl, 1)
+	after := s.dayCost(key)
+	s.index(pl, -1)
+	return s.w.TeacherPreference*s.p.TeacherPenalties[pl.Lesson.TeacherID][pl.Slot] + after - before
+}
+
+// Generate строит расстановку: сначала жадно размещает самые ограниченные занятия,
+// при неудаче пытается освободить слот переносом одного мешающего занятия, затем
+// улучшает мягкие ограничения локальным поиском из iterations шагов.
+func Generate(p Problem, seed int64, iterations int) Result {
+	s := newState(&p)
+	rnd := rand.New(rand.NewSource(seed))
+
+	order := make([]Lesson, len(p.Lessons))
+	copy(order, p.Lessons)
+	optionCount := make(map[int]int, len(order))
+	for i, l := range order {
+		optionCount[i] = len(s.candidates(l))
+	}
+	idx := make([]int, len(order))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return optionCount[idx[a]] < optionCount[idx[b]] })
+
+	var unplaced []Lesson
+	for _, i := range idx {
+		l := order[i]
+		if best, ok := s.bestCandidate(l); ok {
+			s.add(best)
+			continue
+		}
+		if s.placeWithKick(l) {
+			continue
+		}
+		unplaced = append(unplaced, l)
+	}
+
+	s.improve(rnd, iterations)
+
+	rep := Evaluate(p, s.placements)
+	rep.Unplaced = len(unplaced)
+	return Result{Placements: s.placements, Unplaced: unplaced, Report: rep}
+}
+
+// bestCandidate выбирает допустимое размещение с наименьшей итоговой оценкой;
+// при равенстве — самую маленькую подходящую аудиторию.
+func (s *state) bestCandidate(l Lesson) (Placement, bool) {
+	var best Placement
+	found := false
+	bestCost, bestCap := 0, 0
+	for _, c := range s.candidates(l) {
+		cost := s.addCost(c)
+		capacity := s.rooms[c.RoomID].Capacity
+		if !found || cost < bestCost || (cost == bestCost && capacity < bestCap) {
+			best, bestCost, bestCap, found = c, cost, capacity, true
+		}
+	}
+	return best, found
+}
+
+func (s *state) remove(i int) Placement {
+	pl := s.placements[i]
+	s.index(pl, -1)
+	s.placements = append(s.placements[:i], s.placements[i+1:]...)
+	return pl
+}
+
+// placeWithKick пытается поставить занятие, перенеся в другое место одно уже
+// размещённое занятие, которое мешает ему по преподавателю, группе или аудитории.
+func (s *state) placeWithKick(l Lesson) bool {
+	for i := range s.placements {
+		blocker := s.remove(i)
+		if c, ok := s.bestCandidate(l); ok && c.Slot == blocker.Slot {
+			s.add(c)
+			if alt, ok := s.bestCandidate(blocker.Lesson); ok {
+				s.add(alt)
+				return true
+			}
+			s.remove(len(s.placements) - 1)
+		}
+		s.placements = append(s.placements, Placement{})
+		copy(s.placements[i+1:], s.placements[i:])
+		s.placements[i] = blocker
+		s.index(blocker, 1)
+	}
+	return false
+}
+
+// improve — локальный поиск: случайное занятие переносится в случайное допустимое
+// место, изменение сохраняется, если оценка не ухудшилась.
+func (s *state) improve(rnd *rand.Rand, iterations int) {
+	if len(s.placements) == 0 {
+		return
+	}
+	for it := 0; it < iterations; it++ {
+		i := rnd.Intn(len(s.placements))
+		old := s.placements[i]
+		s.index(old, -1)
+		cands := s.candidates(old.Lesson)
+		if len(cands) == 0 {
+			s.index(old, 1)
+			continue
+		}
+		c := cands[rnd.Intn(len(cands))]
+		delta := s.addCost(c) - s.addCost(old)
+		s.index(old, 1)
+		if delta <= 0 {
+			s.move(i, c.Slot, c.RoomID)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/mailer"
+	"scheduleApp/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// devMailBaseURL — адрес сайта для ссылок в письмах, которые при разработке только
+// сохраняются в каталог или журнал.
+const devMailBaseURL = "http://localhost:8080"
+
+var (
+	mail        mailer.Mailer = mailer.File{}
+	mailBaseURL               = devMailBaseURL
+)
+
+// ConfigureMail задаёт способ отправки писем и адрес сайта для ссылок в них. Ссылки
+// с токенами никогда не строятся по заголовкам запроса: подменив Host, можно было бы
+// получить письмо со сбросом чужого пароля, ведущее на свой сайт. Поэтому для
+// настоящей отправки адрес сайта обязателен.
+func ConfigureMail(m mailer.Mailer, baseURL string) error {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		if _, dev := m.(mailer.File); !dev {
+			return errors.New("APP_BASE_URL не задан: без него нельзя строить ссылки в письмах")
+		}
+		log.Printf("ВНИМАНИЕ: APP_BASE_URL не задан, ссылки в письмах ведут на %s", devMailBaseURL)
+		baseURL = devMailBaseURL
+	}
+	mail = m
+	mailBaseURL = baseURL
+	return nil
+}
+
+func mailLink(path string) string {
+	return mailBaseURL + path
+}
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+)
+
+var (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+	// mailResendInterval — не чаще этого письмо одного назначения уходит одному пользователю.
+	mailResendInterval = time.Minute
+)
+
+var (
+	errInvalidAccountToken = errors.New("Ссылка недействительна или устарела")
+	errEmptyPassword       = errors.New("Введите новый пароль")
+	errMailTooSoon         = errors.New("письмо уже отправлено недавно")
+)
+
+func hashAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueAccountToken выдаёт одноразовый токен для ссылки из письма; прежние
+// неиспользованные токены того же назначения перестают действовать.
+func issueAccountToken(db *sql.DB, userID int, purpose string, ttl time.Duration) (string, error) {
+	var recent bool
+	err := db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM account_tokens
+            WHERE user_id = $1 AND purpose = $2 AND created_at > NOW() - make_interval(secs => $3)
+        )
+    `, userID, purpose, mailResendInterval.Seconds()).Scan(&recent)
+	if err != nil {
+		return "", err
+	}
+	if recent {
+		return "", errMailTooSoon
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE account_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, purpose); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO account_tokens (user_id, purpose, token_hash, expires_at)
+        VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+    `, userID, purpose, hashAccountToken(token), ttl.Seconds()); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// checkAccountToken возвращает пользователя действующего токена, не расходуя его.
+func checkAccountToken(db *sql.DB, token, purpose string) (int, error) {
+	var userID int
+	err := db.QueryRow(`
+        SELECT user_id FROM account_tokens
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+    `, hashAccountToken(token), purpose).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidAccountToken
+	}
+	return userID, err
+}
+
+// consumeAccountToken погашает токен и возвращает его пользователя. Повторно
+// предъявленный токен недействителен.
+func consumeAccountToken(tx DBQuerier, token, purpose string) (int, error) {
+	var userID int
+	err := tx.QueryRow(`
+        UPDATE account_tokens SET used_at = NOW()
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+        RETURNING user_id
+    `, hashAccountToken(token), purpose).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidAccountToken
+	}
+	return userID, err
+}
+
+// sendVerificationEmail отправляет ссылку для подтверждения адреса. Если письмо
+// уходило меньше mailResendInterval назад, новое не отправляется.
+func sendVerificationEmail(db *sql.DB, userID int, username, email string) error {
+	token, err := issueAccountToken(db, userID, purposeVerifyEmail, verifyEmailTTL)
+	if err == errMailTooSoon {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return mail.Send(mailer.Message{
+		To:      email,
+		Subject: "Подтверждение адреса электронной почты",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить адрес и завершить регистрацию в системе расписания, перейдите по ссылке:\n%s\n\nСсылка действует %d ч. Если вы не регистрировались, просто проигнорируйте это письмо.\n",
+			username, mailLink("/verify-email?token="+url.QueryEscape(token)), int(verifyEmailTTL.Hours())),
+	})
+}
+
+// emailVerificationMessage возвращает пустую строку для подтверждённого адреса, иначе —
+// объяснение для страницы входа; при этом ссылка отправляется повторно.
+func emailVerificationMessage(db *sql.DB, userID int, username, email string, verified bool) string {
+	if verified {
+		return ""
+	}
+	if err := sendVerificationEmail(db, userID, username, email); err != nil {
+		log.Printf("Ошибка отправки письма подтверждения пользователю %d: %v", userID, err)
+	}
+	return "Адрес электронной почты не подтверждён. Перейдите по ссылке из письма, отправленного на " + email
+}
+
+// VerifyEmailHandler подтверждает адрес по ссылке из письма.
+func VerifyEmailHandler(c *gin.Context, db *sql.DB) {
+	tx, err := db.Begin()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login", gin.H{"Title": "Авторизация", "Error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeAccountToken(tx, c.Query("token"), purposeVerifyEmail)
+	if err == errInvalidAccountToken {
+		c.HTML(http.StatusBadRequest, "login", gin.H{"Title": "Авторизация", "Error": err.Error()})
+		return
+	}
+	if err == nil {
+		_, err = tx.Exec(`UPDATE users SET email_verified_at = NOW() WHERE id = $1`, userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login", gin.H{"Title": "Авторизация", "Error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/login?alarm="+url.QueryEscape("Адрес электронной почты подтверждён, теперь можно войти"))
+}
+
+// passwordResetSentMessage показывается при любом запросе сброса, чтобы по ответу
+// нельзя было узнать, существует ли учётная запись.
+const passwordResetSentMessage = "Если учётная запись с таким именем или адресом существует, на её почту отправлена ссылка для сброса пароля"
+
+// requestPasswordReset отправляет ссылку для сброса пароля пользователю с таким
+// именем или подтверждённым адресом. Неизвестное имя ошибкой не считается.
+func requestPasswordReset(db *sql.DB, login string) error {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return nil
+	}
+	var userID int
+	var username, email string
+	err := db.QueryRow(`
+        SELECT id, username, email FROM users
+        WHERE (username = $1 OR LOWER(email) = LOWER($1)) AND email_verified_at IS NOT NULL AND email <> ''
+        ORDER BY username = $1 DESC
+        LIMIT 1
+    `, login).Scan(&userID, &username, &email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := issueAccountToken(db, userID, purposeResetPassword, resetPasswordTTL)
+	if err == errMailTooSoon {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return mail.Send(mailer.Message{
+		To:      email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\nСсылка действует %d мин и только один раз. Если вы не запрашивали сброс пароля, проигнорируйте это письмо — пароль останется прежним.\n",
+			username, mailLink("/reset-password?token="+url.QueryEscape(token)), int(resetPasswordTTL.Minutes())),
+	})
+}
+
+// resetPassword задаёт новый пароль по токену из письма, завершает все сеансы
+// пользователя и снимает блокировку входа по его имени.
+func resetPassword(db *sql.DB, token, password string) error {
+	if password == "" {
+		return errEmptyPassword
+	}
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	userID, err := consumeAccountToken(tx, token, purposeResetPassword)
+	if err != nil {
+		return err
+	}
+	var username string
+	if err := tx.QueryRow(`UPDATE users SET password = $1 WHERE id = $2 RETURNING username`, string(hashedPwd), userID).Scan(&username); err != nil {
+		return err
+	}
+	if err := clearLoginFailures(tx, username); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	_, err = middleware.RevokeUserSessions(db, userID)
+	return err
+}
+
+func RenderForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "forgot_password", gin.H{"Title": "Восстановление пароля"})
+}
+
+// ForgotPasswordHandler принимает имя пользователя или адрес и отправляет ссылку
+// для сброса пароля.
+func ForgotPasswordHandler(c *gin.Context, db *sql.DB) {
+	if err := requestPasswordReset(db, c.PostForm("login")); err != nil {
+		log.Printf("Ошибка отправки ссылки для сброса пароля: %v", err)
+	}
+	c.HTML(http.StatusOK, "forgot_password", gin.H{
+		"Title": "Восстановление пароля",
+		"Alarm": passwordResetSentMessage,
+	})
+}
+
+// RenderResetPasswordPage показывает форму нового пароля для действующей ссылки.
+func RenderResetPasswordPage(c *gin.Context, db *sql.DB) {
+	token := c.Query("token")
+	if _, err := checkAccountToken(db, token, purposeResetPassword); err != nil {
+		status := http.StatusInternalServerError
+		if err == errInvalidAccountToken {
+			status = http.StatusBadRequest
+		}
+		c.HTML(status, "reset_password", gin.H{"Title": "Новый пароль", "Error": err.Error()})
+		return
+	}
+	c.HTML(http.StatusOK, "reset_password", gin.H{"Title": "Новый пароль", "Token": token})
+}
+
+// ResetPasswordHandler сохраняет новый пароль и отправляет пользователя на вход.
+func ResetPasswordHandler(c *gin.Context, db *sql.DB) {
+	token := c.PostForm("token")
+	password := c.PostForm("password")
+	if password != c.PostForm("password_confirm") {
+		c.HTML(http.StatusBadRequest, "reset_password", gin.H{
+			"Title": "Новый пароль",
+			"Token": token,
+			"Error": "Пароли не совпадают",
+		})
+		return
+	}
+	if err := resetPassword(db, token, password); err != nil {
+		data := gin.H{"Title": "Новый пароль", "Error": err.Error()}
+		status := http.StatusBadRequest
+		switch err {
+		case errInvalidAccountToken:
+		case errEmptyPassword:
+			data["Token"] = token
+		default:
+			status = http.StatusInternalServerError
+			data["Token"] = token
+		}
+		c.HTML(status, "reset_password", data)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/login?alarm="+url.QueryEscape("Пароль изменён, войдите с новым паролем"))
+}
+
+// RequestPasswordResetAPIHandler отправляет ссылку для сброса пароля; ответ не
+// зависит от того, найден ли пользователь.
+func RequestPasswordResetAPIHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		Login string `json:"login" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if err := requestPasswordReset(db, body.Login); err != nil {
+		log.Printf("Ошибка отправки ссылки для сброса пароля: %v", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": passwordResetSentMessage})
+}
+
+// ResetPasswordAPIHandler задаёт новый пароль по токену из письма.
+func ResetPasswordAPIHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if err := resetPassword(db, body.Token, body.Password); err == errInvalidAccountToken || err == errEmptyPassword {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
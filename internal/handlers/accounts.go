@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loadAccountApplications возвращает заявки на регистрацию: ожидающие и отклонённые.
+// Отклонённую заявку администратор может позже одобрить.
+func loadAccountApplications(db *sql.DB, status string) ([]models.PendingAccount, error) {
+	rows, err := db.Query(`
+        SELECT u.id, u.username, COALESCE(u.email, ''), u.role, COALESCE(t.name, ''),
+               COALESCE(d.name, ''), u.created_at
+        FROM users u
+        LEFT JOIN teachers t ON t.user_id = u.id
+        LEFT JOIN departments d ON d.id = t.department_id
+        WHERE u.status = $1
+        ORDER BY u.created_at, u.id
+    `, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.PendingAccount
+	for rows.Next() {
+		var a models.PendingAccount
+		if err := rows.Scan(&a.ID, &a.Username, &a.Email, &a.Role, &a.Name, &a.DepartmentName, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// ReviewAccountHandler одобряет (action=approve) или отклоняет (action=reject) заявку
+// на регистрацию. Одобренная учётная запись получает запрошенную роль и может войти.
+func ReviewAccountHandler(c *gin.Context, db *sql.DB, action string) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Неверный ID пользователя",
+		})
+		return
+	}
+	var status string
+	switch action {
+	case "approve":
+		status = "active"
+	case "reject":
+		status = "rejected"
+	default:
+		c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Неверное действие",
+		})
+		return
+	}
+
+	res, err := db.Exec(`UPDATE users SET status = $1 WHERE id = $2 AND status <> 'active'`, status, userID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка обработки заявки: " + err.Error(),
+		})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.HTML(http.StatusNotFound, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Заявка не найдена или уже одобрена",
+		})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/users")
+}
+
+// NewAccount описывает учётную запись, создаваемую из командной строки. Группа студента
+// и кафедра преподавателя указываются названием.
+type NewAccount struct {
+	Username   string
+	Password   string
+	Email      string
+	Role       string
+	Name       string
+	Group      string
+	Department string
+}
+
+// CreateAccount создаёт активную учётную запись вместе с записью студента или
+// преподавателя и возвращает id пользователя.
+func CreateAccount(db *sql.DB, a NewAccount) (int, error) {
+	a.Username = strings.TrimSpace(a.Username)
+	if a.Username == "" {
+		return 0, fmt.Errorf("не указано имя пользователя")
+	}
+	if a.Password == "" {
+		return 0, fmt.Errorf("не указан пароль")
+	}
+	switch a.Role {
+	case "student", "teacher", "admin":
+	default:
+		return 0, fmt.Errorf("неизвестная роль %q: допустимы student, teacher, admin", a.Role)
+	}
+	if strings.TrimSpace(a.Name) == "" {
+		a.Name = a.Username
+	}
+	a.Email = strings.TrimSpace(a.Email)
+	if a.Email != "" {
+		email, err := parseEmail(a.Email)
+		if err != nil {
+			return 0, err
+		}
+		a.Email = email
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(a.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(`
+        INSERT INTO users (username, password, email, role, status, email_verified_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4, 'active', NOW()) RETURNING id
+    `, a.Username, string(hashedPwd), a.Email, a.Role).Scan(&userID)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать пользователя %s: %v", a.Username, err)
+	}
+
+	switch a.Role {
+	case "student":
+		groupID, err := lookupIDByName(tx, "groups", "группа", a.Group)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO students (user_id, name, group_id) VALUES ($1, $2, $3)`,
+			userID, a.Name, nullableInt(groupID))
+		if err != nil {
+			return 0, err
+		}
+	case "teacher":
+		departmentID, err := lookupIDByName(tx, "departments", "кафедра", a.Department)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO teachers (user_id, name, department_id) VALUES ($1, $2, $3)`,
+			userID, a.Name, nullableInt(departmentID))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return userID, tx.Commit()
+}
+
+// lookupIDByName ищет запись справочника по названию; пустое название означает
+// «не указано» и даёт 0, ненайденное — ошибку.
+func lookupIDByName(db DBQuerier, table, what, name string) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, nil
+	}
+	id, err := findIDByName(db, table, name)
+	if err == nil && id == 0 {
+		err = fmt.Errorf("%s «%s» не найдена", what, name)
+	}
+	return id, err
+}
+
+// findIDByName возвращает id записи с названием name без учёта регистра или 0.
+func findIDByName(db DBQuerier, table, name string) (int, error) {
+	var id int
+	err := db.QueryRow(`SELECT id FROM `+table+` WHERE lower(name) = lower($1) ORDER BY id LIMIT 1`,
+		strings.TrimSpace(name)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// SetAccountPassword задаёт новый пароль пользователю username.
+func SetAccountPassword(db *sql.DB, username, password string) error {
+	if password == "" {
+		return fmt.Errorf("не указан пароль")
+	}
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`UPDATE users SET password = $1 WHERE username = $2`, string(hashedPwd), username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("пользователь %s не найден", username)
+	}
+	return nil
+}
+
+// PromoteToAdmin назначает пользователю роль администратора и активирует учётную запись.
+func PromoteToAdmin(db *sql.DB, username string) error {
+	res, err := db.Exec(`UPDATE users SET role = 'admin', status = 'active' WHERE username = $1`, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("пользователь %s не найден", username)
+	}
+	return nil
+}
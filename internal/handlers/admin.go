@@ -13,6 +13,48 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// adminScheduleFilter — фильтры страницы расписания администратора; те же фильтры
+// используются при выгрузке в XLSX и PDF.
+type adminScheduleFilter struct {
+	Group      string
+	Teacher    string
+	Classroom  string
+	Department string
+}
+
+func parseAdminScheduleFilter(c *gin.Context) adminScheduleFilter {
+	return adminScheduleFilter{
+		Group:      c.Query("group"),
+		Teacher:    c.Query("teacher"),
+		Classroom:  c.Query("classroom"),
+		Department: c.Query("department"),
+	}
+}
+
+// where строит условия отбора занятий. Группа проверяется через EXISTS, чтобы
+// в строке занятия остались все его группы.
+func (f adminScheduleFilter) where() ([]string, []interface{}) {
+	whereClauses := []string{}
+	args := []interface{}{}
+	if f.Group != "" {
+		args = append(args, f.Group)
+		whereClauses = append(whereClauses, fmt.Sprintf("EXISTS (SELECT 1 FROM schedule_groups fg WHERE fg.schedule_id = s.id AND fg.group_id = $%d)", len(args)))
+	}
+	if f.Teacher != "" {
+		args = append(args, f.Teacher)
+		whereClauses = append(whereClauses, fmt.Sprintf("s.teacher_id = $%d", len(args)))
+	}
+	if f.Classroom != "" {
+		args = append(args, f.Classroom)
+		whereClauses = append(whereClauses, fmt.Sprintf("s.classroom_id = $%d", len(args)))
+	}
+	if f.Department != "" {
+		args = append(args, f.Department)
+		whereClauses = append(whereClauses, fmt.Sprintf("s.teacher_id IN (SELECT id FROM teachers WHERE department_id = $%d)", len(args)))
+	}
+	return whereClauses, args
+}
+
 func RenderAdminSchedulesPageWithFilters(c *gin.Context, db *sql.DB) {
 	if gin.Mode() == gin.TestMode {
 		c.String(http.StatusOK, "Mock admin_schedules page in test mode")
@@ -52,29 +94,54 @@ func RenderAdminSchedulesPageWithFilters(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	groupFilter := c.Query("group")
-	teacherFilter := c.Query("teacher")
-	classroomFilter := c.Query("classroom")
+	allDepartments, err := loadAllDepartments(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_admin", gin.H{
+			"Title": "Управление расписанием (Admin)",
+			"Alarm": "Ошибка загрузки кафедр: " + err.Error(),
+		})
+		return
+	}
 
-	whereClauses := []string{}
-	args := []interface{}{}
-	argIndex := 1
-	if groupFilter != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("sg.group_id = $%d", argIndex))
-		args = append(args, groupFilter)
-		argIndex++
+	allSeries, err := loadAllLessonSeries(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_admin", gin.H{
+			"Title": "Управление расписанием (Admin)",
+			"Alarm": "Ошибка загрузки серий занятий: " + err.Error(),
+		})
+		return
 	}
-	if teacherFilter != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("s.teacher_id = $%d", argIndex))
-		args = append(args, teacherFilter)
-		argIndex++
+
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_admin", gin.H{
+			"Title": "Управление расписанием (Admin)",
+			"Alarm": "Ошибка загрузки семестров: " + err.Error(),
+		})
+		return
 	}
-	if classroomFilter != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("s.classroom_id = $%d", argIndex))
-		args = append(args, classroomFilter)
-		argIndex++
+
+	periods, err := loadCalendarPeriods(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_admin", gin.H{
+			"Title": "Управление расписанием (Admin)",
+			"Alarm": "Ошибка загрузки учебного календаря: " + err.Error(),
+		})
+		return
 	}
 
+	bells, err := loadBellSchedule(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_admin", gin.H{
+			"Title": "Управление расписанием (Admin)",
+			"Alarm": "Ошибка загрузки расписания звонков: " + err.Error(),
+		})
+		return
+	}
+
+	filter := parseAdminScheduleFilter(c)
+	whereClauses, args := filter.where()
+
 	query := `
 		SELECT
 			s.id,
@@ -88,7 +155,13 @@ func RenderAdminSchedulesPageWithFilters(c *gin.Context, db *sql.DB) {
 			s.end_time,
 			s.created_at,
 			COALESCE(string_agg(g.name, ', '), '') AS group_names,
-			MIN(g.id) as group_id
+			MIN(g.id) as group_id,
+			COALESCE(s.series_id, 0) AS series_id,
+			COALESCE(s.pair_number, 0) AS pair_number,
+			(SELECT COUNT(*) FROM students st
+				JOIN schedule_groups sg2 ON sg2.group_id = st.group_id
+				WHERE sg2.schedule_id = s.id) AS student_count,
+			COALESCE(c.capacity, 0) AS capacity
 		FROM schedule s
 		JOIN subjects sub ON s.subject_id = sub.id
 		JOIN teachers t ON s.teacher_id = t.id
@@ -100,7 +173,7 @@ func RenderAdminSchedulesPageWithFilters(c *gin.Context, db *sql.DB) {
 		query += " WHERE " + joinClauses(whereClauses, " AND ")
 	}
 	query += `
-		GROUP BY s.id, sub.name, s.subject_id, t.name, s.teacher_id, c.room_number, s.classroom_id, s.start_time, s.end_time, s.created_at
+		GROUP BY s.id, sub.name, s.subject_id, t.name, s.teacher_id, c.room_number, s.classroom_id, s.start_time, s.end_time, s.created_at, s.series_id, s.pair_number, c.capacity
 		ORDER BY s.start_time ASC;
 	`
 
@@ -118,7 +191,8 @@ func RenderAdminSchedulesPageWithFilters(c *gin.Context, db *sql.DB) {
 	for rows.Next() {
 		var sch models.ScheduleDisplay
 		if err := rows.Scan(&sch.ID, &sch.SubjectName, &sch.SubjectID, &sch.TeacherName, &sch.TeacherID,
-			&sch.RoomNumber, &sch.ClassroomID, &sch.StartTime, &sch.EndTime, &sch.CreatedAt, &sch.GroupNames, &sch.GroupID); err != nil {
+			&sch.RoomNumber, &sch.ClassroomID, &sch.StartTime, &sch.EndTime, &sch.CreatedAt, &sch.GroupNames, &sch.GroupID, &sch.SeriesID, &sch.PairNumber,
+			&sch.StudentCount, &sch.Capacity); err != nil {
 			c.HTML(http.StatusInternalServerError, "schedules_admin", gin.H{
 				"Title": "Управление расписанием (Admin)",
 				"Alarm": "Ошибка сканирования строки: " + err.Error(),
@@ -131,23 +205,33 @@ func RenderAdminSchedulesPageWithFilters(c *gin.Context, db *sql.DB) {
 
 	alarm, _ := c.Get("Alarm")
 	c.HTML(http.StatusOK, "schedules_admin", gin.H{
-		"Title":           "Управление расписанием (Admin)",
-		"Schedules":       groupedSchedules,
-		"AllGroups":       allGroups,
-		"AllTeachers":     allTeachers,
-		"AllClassrooms":   allClassrooms,
-		"AllSubjects":     allSubjects,
-		"GroupFilter":     groupFilter,
-		"TeacherFilter":   teacherFilter,
-		"ClassroomFilter": classroomFilter,
-		"Alarm":           alarm,
+		"Title":            "Управление расписанием (Admin)",
+		"Schedules":        groupedSchedules,
+		"AllGroups":        allGroups,
+		"AllTeachers":      allTeachers,
+		"AllClassrooms":    allClassrooms,
+		"AllSubjects":      allSubjects,
+		"AllSeries":        allSeries,
+		"Terms":            terms,
+		"Calendar":         periods,
+		"PairOptions":      pairOptions(bells),
+		"AllDepartments":   allDepartments,
+		"GroupFilter":      filter.Group,
+		"TeacherFilter":    filter.Teacher,
+		"ClassroomFilter":  filter.Classroom,
+		"DepartmentFilter": filter.Department,
+		"Alarm":            alarm,
 	})
 }
 
 func DeleteScheduleHandler(c *gin.Context, db *sql.DB) {
-	scheduleID := c.Param("id")
-	_, err := db.Exec("DELETE FROM schedule WHERE id=$1", scheduleID)
+	scheduleID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
+		c.Set("Alarm", "Неверный ID занятия")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if _, err := deleteLesson(db, scheduleID); err != nil {
 		c.Set("Alarm", "Ошибка удаления записи: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
@@ -163,17 +247,19 @@ func RenderManageUserRolesPage(c *gin.Context, db *sql.DB) {
 	var nonStudentArgs []interface{}
 	if userIdSearch == "" {
 		nonStudentQuery = `
-			SELECT id, username, email, role 
-			FROM users 
-			WHERE role <> 'student'
-			ORDER BY id
+			SELECT u.id, u.username, COALESCE(u.email, ''), u.role, COALESCE(t.department_id, 0), u.totp_enabled_at IS NOT NULL
+			FROM users u
+			LEFT JOIN teachers t ON u.id = t.user_id
+			WHERE u.role <> 'student' AND u.status = 'active'
+			ORDER BY u.id
 		`
 	} else {
 		nonStudentQuery = `
-			SELECT id, username, email, role 
-			FROM users 
-			WHERE role <> 'student' AND id = $1
-			ORDER BY id
+			SELECT u.id, u.username, COALESCE(u.email, ''), u.role, COALESCE(t.department_id, 0), u.totp_enabled_at IS NOT NULL
+			FROM users u
+			LEFT JOIN teachers t ON u.id = t.user_id
+			WHERE u.role <> 'student' AND u.status = 'active' AND u.id = $1
+			ORDER BY u.id
 		`
 		nonStudentArgs = append(nonStudentArgs, userIdSearch)
 	}
@@ -191,7 +277,7 @@ func RenderManageUserRolesPage(c *gin.Context, db *sql.DB) {
 	var teachers []models.User
 	for nonStudentRows.Next() {
 		var u models.User
-		if err := nonStudentRows.Scan(&u.ID, &u.Username, &u.Email, &u.Role); err != nil {
+		if err := nonStudentRows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.DepartmentID, &u.TwoFactorEnabled); err != nil {
 			c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
 				"Title": "Управление пользователями",
 				"Error": err.Error(),
@@ -209,7 +295,7 @@ func RenderManageUserRolesPage(c *gin.Context, db *sql.DB) {
 	var studentArgs []interface{}
 	if userIdSearch == "" {
 		studentQuery = `
-			SELECT u.id, u.username, u.email, u.role, COALESCE(s.group_id, 0) as group_id 
+			SELECT u.id, u.username, COALESCE(u.email, ''), u.role, COALESCE(s.group_id, 0) as group_id 
 			FROM users u
 			LEFT JOIN students s ON u.id = s.user_id
 			WHERE u.role = 'student'
@@ -217,7 +303,7 @@ func RenderManageUserRolesPage(c *gin.Context, db *sql.DB) {
 		`
 	} else {
 		studentQuery = `
-			SELECT u.id, u.username, u.email, u.role, COALESCE(s.group_id, 0) as group_id 
+			SELECT u.id, u.username, COALESCE(u.email, ''), u.role, COALESCE(s.group_id, 0) as group_id 
 			FROM users u
 			LEFT JOIN students s ON u.id = s.user_id
 			WHERE u.role = 'student' AND u.id = $1
@@ -257,13 +343,71 @@ func RenderManageUserRolesPage(c *gin.Context, db *sql.DB) {
 		return
 	}
 
+	allDepartments, err := loadAllDepartments(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка загрузки кафедр: " + err.Error(),
+		})
+		return
+	}
+
+	pending, err := loadAccountApplications(db, "pending")
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка загрузки заявок на регистрацию: " + err.Error(),
+		})
+		return
+	}
+	rejected, err := loadAccountApplications(db, "rejected")
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка загрузки заявок на регистрацию: " + err.Error(),
+		})
+		return
+	}
+
+	lockouts, err := loadLoginLockouts(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка загрузки блокировок входа: " + err.Error(),
+		})
+		return
+	}
+	activeSessions, err := loadActiveSessionCounts(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка загрузки сеансов: " + err.Error(),
+		})
+		return
+	}
+	twoFactorPolicy, err := loadTwoFactorPolicy(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка загрузки настроек входа: " + err.Error(),
+		})
+		return
+	}
+
 	c.HTML(http.StatusOK, "manage_users", gin.H{
-		"Title":        "Управление пользователями",
-		"Admins":       admins,
-		"Teachers":     teachers,
-		"Students":     students,
-		"AllGroups":    allGroups,
-		"UserIDSearch": userIdSearch,
+		"Title":           "Управление пользователями",
+		"Alarm":           c.Query("alarm"),
+		"ActiveSessions":  activeSessions,
+		"TwoFactorPolicy": twoFactorPolicy,
+		"LoginLockouts":   lockouts,
+		"Pending":         pending,
+		"Rejected":        rejected,
+		"Admins":          admins,
+		"Teachers":        teachers,
+		"Students":        students,
+		"AllGroups":       allGroups,
+		"AllDepartments":  allDepartments,
+		"UserIDSearch":    userIdSearch,
 	})
 }
 
@@ -290,8 +434,11 @@ func UpdateUserRoleHandler(c *gin.Context, db *sql.DB) {
 	c.Redirect(http.StatusSeeOther, "/admin/users")
 }
 
-func CheckScheduleCollision(db *sql.DB, teacherID, classroomID, groupID int, startTime, endTime time.Time, excludeID int) (bool, error) {
+// CheckScheduleCollision сообщает, пересекается ли занятие с другими занятиями того же
+// преподавателя, аудитории или группы либо попадает на время, когда преподаватель недоступен.
+func CheckScheduleCollision(db DBQuerier, teacherID, classroomID, groupID int, startTime, endTime time.Time, excludeID int) (bool, error) {
 	var query string
+	var count int
 	if excludeID > 0 {
 		query = `
          SELECT COUNT(DISTINCT s.id)
@@ -307,14 +454,11 @@ func CheckScheduleCollision(db *sql.DB, teacherID, classroomID, groupID int, sta
            AND EXTRACT(EPOCH FROM s.end_time) > $6
          `
 		log.Printf("DEBUG: Collision query with excludeID: %s", query)
-		var count int
 		err := db.QueryRow(query, excludeID, teacherID, classroomID, groupID, endTime.Unix(), startTime.Unix()).Scan(&count)
 		if err != nil {
 			log.Printf("DEBUG: Error executing collision query: %v", err)
 			return false, err
 		}
-		log.Printf("DEBUG: Collision check result: count=%d", count)
-		return count > 0, nil
 	} else {
 		query = `
          SELECT COUNT(DISTINCT s.id)
@@ -329,15 +473,33 @@ func CheckScheduleCollision(db *sql.DB, teacherID, classroomID, groupID int, sta
            AND EXTRACT(EPOCH FROM s.end_time) > $5
          `
 		log.Printf("DEBUG: Collision query without excludeID: %s", query)
-		var count int
 		err := db.QueryRow(query, teacherID, classroomID, groupID, endTime.Unix(), startTime.Unix()).Scan(&count)
 		if err != nil {
 			log.Printf("DEBUG: Error executing collision query: %v", err)
 			return false, err
 		}
-		log.Printf("DEBUG: Collision check result: count=%d", count)
-		return count > 0, nil
 	}
+	log.Printf("DEBUG: Collision check result: count=%d", count)
+	if count > 0 {
+		return true, nil
+	}
+	return CheckTeacherUnavailability(db, teacherID, startTime, endTime)
+}
+
+// CheckScheduleCollisionForGroups выполняет CheckScheduleCollision для каждой группы занятия,
+// чтобы потоковая лекция не пересекалась с занятиями ни одной из своих групп.
+// Без групп проверяются только преподаватель и аудитория.
+func CheckScheduleCollisionForGroups(db DBQuerier, teacherID, classroomID int, groupIDs []int, startTime, endTime time.Time, excludeID int) (bool, error) {
+	if len(groupIDs) == 0 {
+		return CheckScheduleCollision(db, teacherID, classroomID, 0, startTime, endTime, excludeID)
+	}
+	for _, groupID := range groupIDs {
+		collision, err := CheckScheduleCollision(db, teacherID, classroomID, groupID, startTime, endTime, excludeID)
+		if err != nil || collision {
+			return collision, err
+		}
+	}
+	return false, nil
 }
 
 func UpdateStudentGroupHandler(c *gin.Context, db *sql.DB) {
@@ -365,7 +527,7 @@ func UpdateStudentGroupHandler(c *gin.Context, db *sql.DB) {
 		}
 	}
 
-	_, err = db.Exec(`UPDATE students SET group_id = $1 WHERE user_id = $2`, groupID, userID)
+	_, err = db.Exec(`UPDATE students SET group_id = $1 WHERE user_id = $2`, nullableInt(groupID), userID)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
 			"Title": "Управление пользователями",
@@ -0,0 +1,594 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Ответы /api/v1: ошибки — {"error": "..."}, списки — {"data": [...], "page", "per_page", "total"}.
+
+const (
+	apiDefaultPerPage = 50
+	apiMaxPerPage     = 200
+)
+
+func apiError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// APINotFoundHandler отвечает на неизвестные пути внутри /api/ JSON-ошибкой.
+func APINotFoundHandler(c *gin.Context) {
+	apiError(c, http.StatusNotFound, "not found")
+}
+
+// apiPagination читает page и per_page; per_page ограничен apiMaxPerPage.
+func apiPagination(c *gin.Context) (page, perPage int, err error) {
+	page, perPage = 1, apiDefaultPerPage
+	if v := c.Query("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page должен быть положительным числом")
+		}
+	}
+	if v := c.Query("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > apiMaxPerPage {
+			return 0, 0, fmt.Errorf("per_page должен быть от 1 до %d", apiMaxPerPage)
+		}
+	}
+	return page, perPage, nil
+}
+
+func apiList(c *gin.Context, data interface{}, page, perPage, total int) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":     data,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	})
+}
+
+// apiPage отдаёт страницу уже загруженного справочника.
+func apiPage[T any](c *gin.Context, items []T, err error) {
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	page, perPage, perr := apiPagination(c)
+	if perr != nil {
+		apiError(c, http.StatusBadRequest, perr.Error())
+		return
+	}
+	lo := (page - 1) * perPage
+	if lo > len(items) {
+		lo = len(items)
+	}
+	hi := lo + perPage
+	if hi > len(items) {
+		hi = len(items)
+	}
+	data := items[lo:hi]
+	if data == nil {
+		data = []T{}
+	}
+	apiList(c, data, page, perPage, len(items))
+}
+
+func ListGroupsAPIHandler(c *gin.Context, db *sql.DB) {
+	groups, err := loadAllGroups(db)
+	apiPage(c, groups, err)
+}
+
+func ListTeachersAPIHandler(c *gin.Context, db *sql.DB) {
+	teachers, err := loadAllTeachers(db)
+	apiPage(c, teachers, err)
+}
+
+func ListClassroomsAPIHandler(c *gin.Context, db *sql.DB) {
+	classrooms, err := loadAllClassrooms(db)
+	apiPage(c, classrooms, err)
+}
+
+func ListSubjectsAPIHandler(c *gin.Context, db *sql.DB) {
+	subjects, err := loadAllSubjects(db)
+	apiPage(c, subjects, err)
+}
+
+// apiLesson — занятие в ответах API.
+type apiLesson struct {
+	ID          int       `json:"id"`
+	SubjectID   int       `json:"subject_id"`
+	SubjectName string    `json:"subject_name"`
+	TeacherID   int       `json:"teacher_id"`
+	TeacherName string    `json:"teacher_name"`
+	ClassroomID int       `json:"classroom_id"`
+	RoomNumber  string    `json:"room_number"`
+	GroupIDs    []int64   `json:"group_ids"`
+	GroupNames  string    `json:"group_names"`
+	PairNumber  int       `json:"pair_number,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+}
+
+// ListScheduleAPIHandler возвращает занятия по времени начала с фильтрами group, teacher,
+// classroom и интервалом from/to (даты в формате 2006-01-02, to включительно).
+func ListScheduleAPIHandler(c *gin.Context, db *sql.DB) {
+	page, perPage, err := apiPagination(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	clauses := []string{}
+	args := []interface{}{}
+	for param, clause := range map[string]string{
+		"group":     "EXISTS (SELECT 1 FROM schedule_groups fg WHERE fg.schedule_id = s.id AND fg.group_id = $%d)",
+		"teacher":   "s.teacher_id = $%d",
+		"classroom": "s.classroom_id = $%d",
+	} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "Неверный параметр "+param)
+			return
+		}
+		args = append(args, id)
+		clauses = append(clauses, fmt.Sprintf(clause, len(args)))
+	}
+	for param, clause := range map[string]string{"from": "s.start_time >= $%d", "to": "s.start_time < $%d"} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "Неверная дата "+param)
+			return
+		}
+		if param == "to" {
+			d = d.AddDate(0, 0, 1)
+		}
+		args = append(args, d)
+		clauses = append(clauses, fmt.Sprintf(clause, len(args)))
+	}
+	where := ""
+	if len(clauses) > 0 {
+		where = " WHERE " + joinClauses(clauses, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schedule s`+where, args...).Scan(&total); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rows, err := db.Query(`
+        SELECT s.id, s.subject_id, sub.name, s.teacher_id, t.name, s.classroom_id, c.room_number,
+               COALESCE(array_agg(g.id ORDER BY g.id) FILTER (WHERE g.id IS NOT NULL), '{}'),
+               COALESCE(string_agg(g.name, ', ' ORDER BY g.name), ''),
+               COALESCE(s.pair_number, 0), s.start_time, s.end_time
+        FROM schedule s
+        JOIN subjects sub ON sub.id = s.subject_id
+        JOIN teachers t ON t.id = s.teacher_id
+        JOIN classrooms c ON c.id = s.classroom_id
+        LEFT JOIN schedule_groups sg ON sg.schedule_id = s.id
+        LEFT JOIN groups g ON g.id = sg.group_id`+where+`
+        GROUP BY s.id, sub.name, t.name, c.room_number
+        ORDER BY s.start_time, s.id
+        LIMIT `+strconv.Itoa(perPage)+` OFFSET `+strconv.Itoa((page-1)*perPage), args...)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	lessons := []apiLesson{}
+	for rows.Next() {
+		var l apiLesson
+		if err := rows.Scan(&l.ID, &l.SubjectID, &l.SubjectName, &l.TeacherID, &l.TeacherName,
+			&l.ClassroomID, &l.RoomNumber, pq.Array(&l.GroupIDs), &l.GroupNames,
+			&l.PairNumber, &l.StartTime, &l.EndTime); err != nil {
+			apiError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		lessons = append(lessons, l)
+	}
+	apiList(c, lessons, page, perPage, total)
+}
+
+// DeleteScheduleAPIHandler удаляет занятие; дата занятия серии становится её исключением.
+func DeleteScheduleAPIHandler(c *gin.Context, db *sql.DB) {
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid schedule ID")
+		return
+	}
+	deleted, err := deleteLesson(db, scheduleID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		apiError(c, http.StatusNotFound, "not found")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func apiActor(c *gin.Context) (int, string) {
+	userIDVal, _ := c.Get("user_id")
+	roleVal, _ := c.Get("role")
+	userID, _ := userIDVal.(int)
+	role, _ := roleVal.(string)
+	return userID, role
+}
+
+// ListRequestsAPIHandler возвращает запросы: администратору — все (с фильтром status),
+// остальным — только собственные.
+func ListRequestsAPIHandler(c *gin.Context, db *sql.DB) {
+	page, perPage, err := apiPagination(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, role := apiActor(c)
+
+	clauses := []string{}
+	args := []interface{}{}
+	if role != "admin" {
+		args = append(args, userID)
+		clauses = append(clauses, fmt.Sprintf("r.user_id = $%d", len(args)))
+	}
+	if status := c.Query("status"); status != "" {
+		args = append(args, status)
+		clauses = append(clauses, fmt.Sprintf("r.status = $%d", len(args)))
+	}
+	where := joinClauses(clauses, " AND ")
+
+	countQuery := `SELECT COUNT(*) FROM requests r`
+	if where != "" {
+		countQuery += " WHERE " + where
+	}
+	var total int
+	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	requests, err := loadRequests(db, where,
+		fmt.Sprintf("r.created_at DESC, r.id DESC LIMIT %d OFFSET %d", perPage, (page-1)*perPage), args...)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if requests == nil {
+		requests = []models.Request{}
+	}
+	apiList(c, requests, page, perPage, total)
+}
+
+// apiRequestForActor загружает запрос, доступный текущему пользователю.
+func apiRequestForActor(c *gin.Context, db *sql.DB) (models.Request, bool) {
+	reqID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid request ID")
+		return models.Request{}, false
+	}
+	userID, role := apiActor(c)
+	requests, err := loadRequests(db, "r.id = $1", "r.id", reqID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return models.Request{}, false
+	}
+	if len(requests) == 0 || (role != "admin" && requests[0].UserID != userID) {
+		apiError(c, http.StatusNotFound, "not found")
+		return models.Request{}, false
+	}
+	return requests[0], true
+}
+
+func GetRequestAPIHandler(c *gin.Context, db *sql.DB) {
+	r, ok := apiRequestForActor(c, db)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// ProcessRequestAPIHandler одобряет (action=approve) или отклоняет (action=reject) запрос.
+// Тело: {"reason": "..."}; для отклонения причина обязательна.
+func ProcessRequestAPIHandler(c *gin.Context, db *sql.DB, action string) {
+	reqID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid request ID")
+		return
+	}
+	status, _ := requestActionStatus(action)
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+			return
+		}
+	}
+	adminID, _ := apiActor(c)
+
+	err = processRequest(db, reqID, adminID, status, strings.TrimSpace(body.Reason))
+	if isProposalError(err) {
+		apiError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: strconv.Itoa(reqID)})
+	GetRequestAPIHandler(c, db)
+}
+
+// CreateRequestCommentAPIHandler добавляет комментарий в обсуждение запроса.
+func CreateRequestCommentAPIHandler(c *gin.Context, db *sql.DB) {
+	r, ok := apiRequestForActor(c, db)
+	if !ok {
+		return
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.Message) == "" {
+		apiError(c, http.StatusBadRequest, "Комментарий не может быть пустым")
+		return
+	}
+	userID, _ := apiActor(c)
+	if err := addRequestEvent(db, r.ID, userID, RequestEventCommented, strings.TrimSpace(body.Message)); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Comment created"})
+}
+
+// ListCommentsAPIHandler возвращает комментарии преподавателей к занятиям;
+// schedule_id ограничивает выборку одним занятием.
+func ListCommentsAPIHandler(c *gin.Context, db *sql.DB) {
+	page, perPage, err := apiPagination(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	where := ""
+	args := []interface{}{}
+	if v := c.Query("schedule_id"); v != "" {
+		scheduleID, err := strconv.Atoi(v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "Неверный параметр schedule_id")
+			return
+		}
+		where = " WHERE schedule_id = $1"
+		args = append(args, scheduleID)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM comments`+where, args...).Scan(&total); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rows, err := db.Query(`
+        SELECT id, schedule_id, teacher_id, comment_text, COALESCE(file_path, ''), created_at
+        FROM comments`+where+`
+        ORDER BY created_at DESC, id DESC
+        LIMIT `+strconv.Itoa(perPage)+` OFFSET `+strconv.Itoa((page-1)*perPage), args...)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var cm models.Comment
+		if err := rows.Scan(&cm.ID, &cm.ScheduleID, &cm.TeacherID, &cm.CommentText, &cm.FilePath, &cm.CreatedAt); err != nil {
+			apiError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		comments = append(comments, cm)
+	}
+	apiList(c, comments, page, perPage, total)
+}
+
+// CreateCommentAPIHandler добавляет комментарий текущего преподавателя к занятию.
+// Вложения через API не поддерживаются — только текст.
+func CreateCommentAPIHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		ScheduleID  int    `json:"schedule_id"`
+		CommentText string `json:"comment_text"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(body.CommentText) == "" {
+		apiError(c, http.StatusBadRequest, "Комментарий не может быть пустым")
+		return
+	}
+	teacherID, err := currentTeacherID(c, db)
+	if err != nil {
+		apiError(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var cm models.Comment
+	err = db.QueryRow(`
+        INSERT INTO comments (schedule_id, teacher_id, comment_text, created_at)
+        SELECT id, $2, $3, NOW() FROM schedule WHERE id = $1
+        RETURNING id, schedule_id, teacher_id, comment_text, created_at
+    `, body.ScheduleID, teacherID, strings.TrimSpace(body.CommentText)).
+		Scan(&cm.ID, &cm.ScheduleID, &cm.TeacherID, &cm.CommentText, &cm.CreatedAt)
+	if err == sql.ErrNoRows {
+		apiError(c, http.StatusNotFound, "Занятие не найдено")
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, cm)
+}
+
+// CreateAPITokenHandler выдаёт bearer-токен по логину и паролю.
+func CreateAPITokenHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	user, err := verifyCredentials(db, body.Username, body.Password, c.ClientIP())
+	if err != nil {
+		apiLoginFailure(c, err)
+		return
+	}
+	if msg := emailVerificationMessage(db, user.ID, user.Username, user.Email, user.EmailVerified); msg != "" {
+		apiError(c, http.StatusForbidden, msg)
+		return
+	}
+	if msg := accountStatusMessage(user.Status); msg != "" {
+		apiError(c, http.StatusForbidden, msg)
+		return
+	}
+	if user.TwoFactorEnabled {
+		challenge, err := createLoginChallenge(db, user.ID)
+		if err != nil {
+			apiError(c, http.StatusInternalServerError, "Ошибка входа, попробуйте позже")
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":               "Введите код из приложения-аутентификатора",
+			"two_factor_required": true,
+			"challenge":           challenge,
+			"expires_in":          int(loginChallengeTTL.Seconds()),
+		})
+		return
+	}
+	if user.TwoFactorRequired {
+		apiError(c, http.StatusForbidden, errTwoFactorSetupRequired.Error())
+		return
+	}
+
+	token, refresh, err := middleware.IssueTokens(db, user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, "Ошибка генерации токена")
+		return
+	}
+	apiTokenResponse(c, token, refresh, user.Role)
+}
+
+// CreateAPITokenTOTPHandler — второй шаг входа через API: обменивает challenge из
+// ответа /auth/token и код из приложения (или код восстановления) на токены.
+func CreateAPITokenTOTPHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		Challenge string `json:"challenge" binding:"required"`
+		Code      string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	ch, _, err := completeLoginChallenge(db, body.Challenge, body.Code, c.ClientIP(), false)
+	if err != nil {
+		apiLoginFailure(c, err)
+		return
+	}
+	if msg := accountStatusMessage(ch.user.Status); msg != "" {
+		apiError(c, http.StatusForbidden, msg)
+		return
+	}
+
+	token, refresh, err := middleware.IssueTokens(db, ch.user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, "Ошибка генерации токена")
+		return
+	}
+	apiTokenResponse(c, token, refresh, ch.user.Role)
+}
+
+// apiLoginFailure отвечает на ошибку проверки пароля или кода; при блокировке входа
+// добавляет заголовок Retry-After.
+func apiLoginFailure(c *gin.Context, err error) {
+	var locked *loginLockedError
+	if errors.As(err, &locked) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.wait.Seconds()))))
+	}
+	status, msg := loginFailure(err)
+	apiError(c, status, msg)
+}
+
+func apiTokenResponse(c *gin.Context, token, refresh, role string) {
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"token_type":    "Bearer",
+		"expires_in":    int(middleware.AccessTokenTTL.Seconds()),
+		"refresh_token": refresh,
+		"role":          role,
+	})
+}
+
+// RefreshAPITokenHandler обменивает refresh-токен на новую пару токенов.
+func RefreshAPITokenHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	user, token, refresh, err := middleware.RefreshTokens(db, body.RefreshToken)
+	if err == middleware.ErrInvalidRefreshToken {
+		apiError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if refresh == "" {
+		// Токен только что обменян другим запросом клиента. Новый токен хранится только
+		// в виде хеша, а старый уже отозван, поэтому клиенту нечего вернуть.
+		apiError(c, http.StatusConflict, "Refresh-токен уже обменян параллельным запросом: используйте выданный им токен")
+		return
+	}
+	apiTokenResponse(c, token, refresh, user.Role)
+}
+
+// RevokeAPITokenHandler завершает сеанс refresh-токена (выход клиента API).
+func RevokeAPITokenHandler(c *gin.Context, db *sql.DB) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apiError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if err := middleware.RevokeRefreshToken(db, body.RefreshToken); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
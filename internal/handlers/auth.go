@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
 	"net/http"
+	netmail "net/mail"
 	"scheduleApp/internal/middleware"
 	"scheduleApp/internal/models"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -15,29 +19,35 @@ func LoginFormHandler(c *gin.Context, db *sql.DB) {
 	username := c.PostForm("username")
 	password := c.PostForm("password")
 
-	var user models.User
-	err := db.QueryRow(`
-        SELECT id, username, password, email, role
-        FROM users
-        WHERE username=$1
-    `, username).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.Role)
+	user, err := verifyCredentials(db, username, password, c.ClientIP())
 	if err != nil {
-		c.HTML(http.StatusUnauthorized, "login", gin.H{
+		status, msg := loginFailure(err)
+		c.HTML(status, "login", gin.H{
 			"Title": "Авторизация",
-			"Error": "Пользователь не найден",
+			"Error": msg,
 		})
 		return
 	}
-
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
-		c.HTML(http.StatusUnauthorized, "login", gin.H{
+	if msg := emailVerificationMessage(db, user.ID, user.Username, user.Email, user.EmailVerified); msg != "" {
+		c.HTML(http.StatusForbidden, "login", gin.H{
+			"Title": "Авторизация",
+			"Error": msg,
+		})
+		return
+	}
+	if msg := accountStatusMessage(user.Status); msg != "" {
+		c.HTML(http.StatusForbidden, "login", gin.H{
 			"Title": "Авторизация",
-			"Error": "Неверный пароль",
+			"Error": msg,
 		})
 		return
 	}
+	if user.TwoFactorEnabled || user.TwoFactorRequired {
+		startTwoFactorLogin(c, db, user)
+		return
+	}
 
-	token, err := middleware.GenerateJWT(user)
+	token, refresh, err := middleware.IssueTokens(db, user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "login", gin.H{
 			"Title": "Авторизация",
@@ -46,8 +56,12 @@ func LoginFormHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	c.SetCookie("token", token, 3600, "/", "", false, true)
+	middleware.SetAuthCookies(c, token, refresh)
+	renderLoginHome(c, user, token)
+}
 
+// renderLoginHome показывает главную страницу роли сразу после входа.
+func renderLoginHome(c *gin.Context, user models.User, token string) {
 	switch user.Role {
 	case "admin":
 		c.HTML(http.StatusOK, "index_admin", gin.H{
@@ -88,7 +102,7 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "register", gin.H{
 			"Title":     "Регистрация",
-			"Error":     "Ошибка загрузки отделов: " + err.Error(),
+			"Error":     "Ошибка загрузки кафедр: " + err.Error(),
 			"AllGroups": groups,
 		})
 		return
@@ -96,12 +110,40 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 
 	username := c.PostForm("username")
 	password := c.PostForm("password")
-	email := c.PostForm("email")
+	email := strings.TrimSpace(c.PostForm("email"))
 	name := c.PostForm("name")
 	role := c.PostForm("role")
 	if role == "" {
 		role = "student"
 	}
+	// Студент начинает работу сразу, преподаватель — после подтверждения администратором.
+	// Роль администратора самостоятельно получить нельзя.
+	status := "active"
+	switch role {
+	case "student":
+	case "teacher":
+		status = "pending"
+	default:
+		c.HTML(http.StatusBadRequest, "register", gin.H{
+			"Title":          "Регистрация",
+			"Error":          "Зарегистрироваться можно только как студент или преподаватель",
+			"AllGroups":      groups,
+			"AllDepartments": departments,
+		})
+		return
+	}
+
+	// На адрес приходит ссылка подтверждения, без неё войти нельзя.
+	email, err = parseEmail(email)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "register", gin.H{
+			"Title":          "Регистрация",
+			"Error":          "Укажите адрес электронной почты: на него придёт ссылка для подтверждения",
+			"AllGroups":      groups,
+			"AllDepartments": departments,
+		})
+		return
+	}
 
 	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -116,9 +158,9 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 
 	var userID int
 	err = db.QueryRow(`
-        INSERT INTO users (username, password, email, role)
-        VALUES ($1, $2, $3, $4) RETURNING id
-    `, username, string(hashedPwd), email, role).Scan(&userID)
+        INSERT INTO users (username, password, email, role, status)
+        VALUES ($1, $2, $3, $4, $5) RETURNING id
+    `, username, string(hashedPwd), email, role, status).Scan(&userID)
 	if err != nil {
 		c.HTML(http.StatusConflict, "register", gin.H{
 			"Title":          "Регистрация",
@@ -148,7 +190,7 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 		_, err = db.Exec(`
           INSERT INTO students (user_id, name, group_id)
           VALUES ($1, $2, $3)
-        `, userID, name, groupID)
+        `, userID, name, nullableInt(groupID))
 		if err != nil {
 			c.HTML(http.StatusInternalServerError, "register", gin.H{
 				"Title":          "Регистрация",
@@ -166,7 +208,7 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 			if err != nil {
 				c.HTML(http.StatusBadRequest, "register", gin.H{
 					"Title":          "Регистрация",
-					"Error":          "Неверный ID отдела",
+					"Error":          "Неверный ID кафедры",
 					"AllGroups":      groups,
 					"AllDepartments": departments,
 				})
@@ -176,7 +218,7 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 		_, err = db.Exec(`
           INSERT INTO teachers (user_id, name, department_id)
           VALUES ($1, $2, $3)
-        `, userID, name, departmentID)
+        `, userID, name, nullableInt(departmentID))
 		if err != nil {
 			c.HTML(http.StatusInternalServerError, "register", gin.H{
 				"Title":          "Регистрация",
@@ -188,12 +230,32 @@ func RegisterFormHandler(c *gin.Context, db *sql.DB) {
 		}
 	}
 
+	if err := sendVerificationEmail(db, userID, username, email); err != nil {
+		log.Printf("Ошибка отправки письма подтверждения пользователю %d: %v", userID, err)
+	}
+	alarm := "Регистрация почти завершена: подтвердите адрес по ссылке из письма, отправленного на " + email + "."
+	if status == "pending" {
+		alarm = "Заявка на регистрацию отправлена. Подтвердите адрес по ссылке из письма, отправленного на " + email +
+			"; войти можно будет после подтверждения заявки администратором."
+	}
 	c.HTML(http.StatusOK, "login", gin.H{
 		"Title": "Авторизация",
-		"Alarm": "Регистрация успешно завершена! Теперь войдите в систему.",
+		"Alarm": alarm,
 	})
 }
 
+// accountStatusMessage объясняет, почему учётной записи нельзя выдать токен;
+// для активной учётной записи возвращает пустую строку.
+func accountStatusMessage(status string) string {
+	switch status {
+	case "pending":
+		return "Учётная запись ожидает подтверждения администратором"
+	case "rejected":
+		return "Заявка на регистрацию отклонена администратором"
+	}
+	return ""
+}
+
 func RenderRegisterPage(c *gin.Context, db *sql.DB) {
 	groups, err := loadAllGroups(db)
 	if err != nil {
@@ -207,7 +269,7 @@ func RenderRegisterPage(c *gin.Context, db *sql.DB) {
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "register", gin.H{
 			"Title": "Регистрация",
-			"Error": "Ошибка загрузки отделов: " + err.Error(),
+			"Error": "Ошибка загрузки кафедр: " + err.Error(),
 		})
 		return
 	}
@@ -218,7 +280,26 @@ func RenderRegisterPage(c *gin.Context, db *sql.DB) {
 	})
 }
 
-func LogoutHandler(c *gin.Context) {
-	c.SetCookie("token", "", -1, "/", "", false, true)
+// LogoutHandler удаляет cookie с токенами и завершает сеанс refresh-токена, чтобы
+// выданные в нём токены перестали приниматься.
+func LogoutHandler(c *gin.Context, db *sql.DB) {
+	if refresh := middleware.ClearAuthCookies(c); refresh != "" {
+		if err := middleware.RevokeRefreshToken(db, refresh); err != nil {
+			log.Printf("Ошибка отзыва refresh-токена: %v", err)
+		}
+	}
 	c.Redirect(http.StatusSeeOther, "/")
 }
+
+// parseEmail проверяет, что строка — один адрес вида user@example.com без имени и
+// переводов строки: адрес попадает в заголовок To: письма как есть.
+func parseEmail(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return "", fmt.Errorf("адрес электронной почты содержит перевод строки")
+	}
+	addr, err := netmail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return "", fmt.Errorf("неверный адрес электронной почты: %s", s)
+	}
+	return addr.Address, nil
+}
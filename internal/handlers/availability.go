@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isoWeekday возвращает номер дня недели t: 1 — понедельник, 7 — воскресенье.
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// CheckTeacherUnavailability сообщает, попадает ли интервал [startTime, endTime) на окно
+// недоступности преподавателя: разовый период или еженедельное окно в тот же день недели.
+func CheckTeacherUnavailability(db DBQuerier, teacherID int, startTime, endTime time.Time) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+        SELECT COUNT(*)
+        FROM teacher_unavailability
+        WHERE teacher_id = $1
+          AND (
+                (start_at IS NOT NULL
+                    AND EXTRACT(EPOCH FROM start_at) < $2
+                    AND EXTRACT(EPOCH FROM end_at) > $3)
+             OR (weekday IS NOT NULL
+                    AND weekday = $4
+                    AND start_clock < $5::time
+                    AND end_clock > $6::time)
+          )
+    `, teacherID, endTime.Unix(), startTime.Unix(), isoWeekday(startTime),
+		endTime.Format("15:04:05"), startTime.Format("15:04:05")).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UnavailabilityOverlaps проверяет то же, что CheckTeacherUnavailability, для уже
+// загруженного окна: используется генератором, чтобы не ставить занятия в такие слоты.
+func UnavailabilityOverlaps(u models.TeacherUnavailability, startTime, endTime time.Time) bool {
+	if u.Weekday > 0 {
+		if isoWeekday(startTime) != u.Weekday {
+			return false
+		}
+		return atClock(startTime, u.StartClock).Before(endTime) && atClock(startTime, u.EndClock).After(startTime)
+	}
+	return u.StartAt.Before(endTime) && u.EndAt.After(startTime)
+}
+
+func loadTeacherUnavailability(db DBQuerier, teacherID int) ([]models.TeacherUnavailability, error) {
+	query := `
+		SELECT id, teacher_id, COALESCE(weekday, 0),
+		       COALESCE(to_char(start_clock, 'HH24:MI'), ''), COALESCE(to_char(end_clock, 'HH24:MI'), ''),
+		       COALESCE(start_at, 'epoch'), COALESCE(end_at, 'epoch'), reason
+		FROM teacher_unavailability`
+	var args []interface{}
+	if teacherID > 0 {
+		query += ` WHERE teacher_id = $1`
+		args = append(args, teacherID)
+	}
+	query += ` ORDER BY weekday NULLS LAST, start_clock, start_at;`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TeacherUnavailability
+	for rows.Next() {
+		var u models.TeacherUnavailability
+		if err := rows.Scan(&u.ID, &u.TeacherID, &u.Weekday, &u.StartClock, &u.EndClock, &u.StartAt, &u.EndAt, &u.Reason); err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+// currentTeacherID находит запись преподавателя для авторизованного пользователя.
+func currentTeacherID(c *gin.Context, db *sql.DB) (int, error) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return 0, fmt.Errorf("Пользователь не авторизован")
+	}
+	var teacherID int
+	if err := db.QueryRow(`SELECT id FROM teachers WHERE user_id = $1`, userID).Scan(&teacherID); err != nil {
+		return 0, fmt.Errorf("Учитель не найден: %v", err)
+	}
+	return teacherID, nil
+}
+
+func renderTeacherAvailabilityPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
+	teacherID, err := currentTeacherID(c, db)
+	if err != nil {
+		c.HTML(http.StatusUnauthorized, "teacher_availability", gin.H{
+			"Title": "Моя доступность",
+			"Error": err.Error(),
+		})
+		return
+	}
+	windows, err := loadTeacherUnavailability(db, teacherID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "teacher_availability", gin.H{
+			"Title": "Моя доступность",
+			"Error": "Ошибка загрузки окон недоступности: " + err.Error(),
+		})
+		return
+	}
+
+	var weekly, oneOff []models.TeacherUnavailability
+	for _, u := range windows {
+		if u.Weekday > 0 {
+			weekly = append(weekly, u)
+		} else {
+			oneOff = append(oneOff, u)
+		}
+	}
+	c.HTML(status, "teacher_availability", gin.H{
+		"Title":  "Моя доступность",
+		"Weekly": weekly,
+		"OneOff": oneOff,
+		"Error":  errMsg,
+		"Alarm":  c.Query("alarm"),
+	})
+}
+
+func RenderTeacherAvailabilityPage(c *gin.Context, db *sql.DB) {
+	renderTeacherAvailabilityPage(c, db, http.StatusOK, "")
+}
+
+// CreateTeacherUnavailabilityHandler добавляет окно недоступности текущему преподавателю.
+// kind=weekly — еженедельное окно (weekday, start_clock, end_clock),
+// kind=range — разовый период (start_at, end_at в формате даты и времени).
+func CreateTeacherUnavailabilityHandler(c *gin.Context, db *sql.DB) {
+	teacherID, err := currentTeacherID(c, db)
+	if err != nil {
+		renderTeacherAvailabilityPage(c, db, http.StatusUnauthorized, err.Error())
+		return
+	}
+	reason := strings.TrimSpace(c.PostForm("reason"))
+
+	switch c.PostForm("kind") {
+	case "weekly":
+		weekday, err := strconv.Atoi(c.PostForm("weekday"))
+		if err != nil || weekday < 1 || weekday > 7 {
+			renderTeacherAvailabilityPage(c, db, http.StatusBadRequest, "Неверный день недели")
+			return
+		}
+		startClock, endClock := c.PostForm("start_clock"), c.PostForm("end_clock")
+		start, err1 := time.Parse("15:04", startClock)
+		end, err2 := time.Parse("15:04", endClock)
+		if err1 != nil || err2 != nil || !end.After(start) {
+			renderTeacherAvailabilityPage(c, db, http.StatusBadRequest, "Неверный интервал времени")
+			return
+		}
+		_, err = db.Exec(`
+            INSERT INTO teacher_unavailability (teacher_id, weekday, start_clock, end_clock, reason)
+            VALUES ($1, $2, $3, $4, $5)
+        `, teacherID, weekday, startClock, endClock, reason)
+		if err != nil {
+			renderTeacherAvailabilityPage(c, db, http.StatusInternalServerError, "Ошибка сохранения: "+err.Error())
+			return
+		}
+	case "range":
+		start, err1 := time.Parse("2006-01-02T15:04", c.PostForm("start_at"))
+		end, err2 := time.Parse("2006-01-02T15:04", c.PostForm("end_at"))
+		if err1 != nil || err2 != nil || !end.After(start) {
+			renderTeacherAvailabilityPage(c, db, http.StatusBadRequest, "Неверный период")
+			return
+		}
+		_, err = db.Exec(`
+            INSERT INTO teacher_unavailability (teacher_id, start_at, end_at, reason)
+            VALUES ($1, $2, $3, $4)
+        `, teacherID, start, end, reason)
+		if err != nil {
+			renderTeacherAvailabilityPage(c, db, http.StatusInternalServerError, "Ошибка сохранения: "+err.Error())
+			return
+		}
+	default:
+		renderTeacherAvailabilityPage(c, db, http.StatusBadRequest, "Неизвестный тип окна")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/teacher/availability?alarm=Окно+недоступности+добавлено")
+}
+
+func DeleteTeacherUnavailabilityHandler(c *gin.Context, db *sql.DB) {
+	teacherID, err := currentTeacherID(c, db)
+	if err != nil {
+		renderTeacherAvailabilityPage(c, db, http.StatusUnauthorized, err.Error())
+		return
+	}
+	windowID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderTeacherAvailabilityPage(c, db, http.StatusBadRequest, "Неверный ID окна")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM teacher_unavailability WHERE id = $1 AND teacher_id = $2`, windowID, teacherID); err != nil {
+		renderTeacherAvailabilityPage(c, db, http.StatusInternalServerError, "Ошибка удаления: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/teacher/availability")
+}
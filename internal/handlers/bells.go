@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLessonDuration — длительность занятия, если не выбрана пара и не указана своя длительность.
+const defaultLessonDuration = 90 * time.Minute
+
+const maxLessonDurationMinutes = 600
+
+// lookupBellPeriod возвращает время начала и окончания пары pairNumber для аудитории
+// classroomID. Звонки, заданные для корпуса аудитории, имеют приоритет над общими
+// (с пустым корпусом).
+func lookupBellPeriod(db DBQuerier, classroomID, pairNumber int) (string, string, error) {
+	var startClock, endClock string
+	err := db.QueryRow(`
+        SELECT to_char(b.start_clock, 'HH24:MI'), to_char(b.end_clock, 'HH24:MI')
+        FROM bell_schedule b
+        WHERE b.pair_number = $1
+          AND (b.building = '' OR b.building = (SELECT COALESCE(building, '') FROM classrooms WHERE id = $2))
+        ORDER BY b.building DESC
+        LIMIT 1
+    `, pairNumber, classroomID).Scan(&startClock, &endClock)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("пара №%d не найдена в расписании звонков", pairNumber)
+	}
+	return startClock, endClock, err
+}
+
+// ResolveLessonTime вычисляет время начала и окончания занятия. Если указан номер пары,
+// время берётся из расписания звонков на дату day; иначе используется start.
+// Ненулевая durationMinutes переопределяет окончание (сдвоенные лабораторные, занятия по 45 минут).
+func ResolveLessonTime(db DBQuerier, classroomID int, day time.Time, pairNumber int, start time.Time, durationMinutes int) (time.Time, time.Time, error) {
+	if durationMinutes < 0 || durationMinutes > maxLessonDurationMinutes {
+		return time.Time{}, time.Time{}, fmt.Errorf("длительность занятия должна быть от 1 до %d минут", maxLessonDurationMinutes)
+	}
+
+	if pairNumber > 0 {
+		startClock, endClock, err := lookupBellPeriod(db, classroomID, pairNumber)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = atClock(day, startClock)
+		end := atClock(day, endClock)
+		if durationMinutes > 0 {
+			end = start.Add(time.Duration(durationMinutes) * time.Minute)
+		}
+		return start, end, nil
+	}
+
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, fmt.Errorf("не указаны ни пара, ни время начала")
+	}
+	if durationMinutes > 0 {
+		return start, start.Add(time.Duration(durationMinutes) * time.Minute), nil
+	}
+	return start, start.Add(defaultLessonDuration), nil
+}
+
+// pairOptions оставляет по одной записи на каждый номер пары для выпадающих списков,
+// предпочитая общее для всех корпусов время.
+func pairOptions(bells []models.BellPeriod) []models.BellPeriod {
+	byPair := make(map[int]int)
+	var result []models.BellPeriod
+	for _, b := range bells {
+		if i, ok := byPair[b.PairNumber]; ok {
+			if b.Building == "" {
+				result[i] = b
+			}
+			continue
+		}
+		byPair[b.PairNumber] = len(result)
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PairNumber < result[j].PairNumber })
+	return result
+}
+
+// atClock возвращает момент на дату day во время clock (формат HH:MM).
+func atClock(day time.Time, clock string) time.Time {
+	t, _ := time.Parse("15:04", clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// parseLessonTimeForm читает из формы дату и номер пары (или время начала вручную)
+// и необязательную длительность, возвращая итоговое время занятия и номер пары.
+func parseLessonTimeForm(c *gin.Context, db DBQuerier, classroomID int) (time.Time, time.Time, int, error) {
+	durationMinutes := 0
+	if v := c.PostForm("duration_minutes"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d <= 0 {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("Неверная длительность занятия")
+		}
+		durationMinutes = d
+	}
+
+	pairNumber := 0
+	var day, start time.Time
+	if v := c.PostForm("pair_number"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("Неверный номер пары")
+		}
+		pairNumber = n
+		day, err = time.Parse(dateLayout, c.PostForm("date"))
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("Для выбора пары укажите дату занятия")
+		}
+	} else {
+		startTimeStr := c.PostForm("start_time")
+		if startTimeStr == "" {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("Укажите пару или время начала занятия")
+		}
+		var err error
+		start, err = time.Parse("2006-01-02T15:04", startTimeStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("Неверный формат времени начала: %v", err)
+		}
+	}
+
+	start, end, err := ResolveLessonTime(db, classroomID, day, pairNumber, start, durationMinutes)
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("Ошибка определения времени занятия: %v", err)
+	}
+	return start, end, pairNumber, nil
+}
+
+func CreateBellPeriodHandler(c *gin.Context, db *sql.DB) {
+	pairNumber, err := strconv.Atoi(c.PostForm("pair_number"))
+	if err != nil || pairNumber <= 0 {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверный номер пары")
+		return
+	}
+	building := strings.TrimSpace(c.PostForm("building"))
+	startClock := c.PostForm("start_clock")
+	endClock := c.PostForm("end_clock")
+	start, err1 := time.Parse("15:04", startClock)
+	end, err2 := time.Parse("15:04", endClock)
+	if err1 != nil || err2 != nil {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверное время начала или окончания пары")
+		return
+	}
+	if !end.After(start) {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Окончание пары должно быть позже начала")
+		return
+	}
+
+	_, err = db.Exec(`
+        INSERT INTO bell_schedule (pair_number, building, start_clock, end_clock)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (building, pair_number) DO UPDATE
+        SET start_clock = EXCLUDED.start_clock, end_clock = EXCLUDED.end_clock
+    `, pairNumber, building, startClock, endClock)
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, "Ошибка сохранения пары: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/calendar?alarm=Расписание+звонков+обновлено")
+}
+
+func DeleteBellPeriodHandler(c *gin.Context, db *sql.DB) {
+	bellID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверный ID пары")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM bell_schedule WHERE id = $1`, bellID); err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, "Ошибка удаления пары: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/calendar")
+}
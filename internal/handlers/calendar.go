@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func renderAdminCalendarPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "calendar_admin", gin.H{
+			"Title": "Учебный календарь",
+			"Error": "Ошибка загрузки семестров: " + err.Error(),
+		})
+		return
+	}
+	bells, err := loadBellSchedule(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "calendar_admin", gin.H{
+			"Title": "Учебный календарь",
+			"Error": "Ошибка загрузки расписания звонков: " + err.Error(),
+		})
+		return
+	}
+	periods, err := loadCalendarPeriods(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "calendar_admin", gin.H{
+			"Title": "Учебный календарь",
+			"Error": "Ошибка загрузки праздников и каникул: " + err.Error(),
+		})
+		return
+	}
+	c.HTML(status, "calendar_admin", gin.H{
+		"Title":   "Учебный календарь",
+		"Terms":   terms,
+		"Bells":   bells,
+		"Periods": periods,
+		"Error":   errMsg,
+		"Alarm":   c.Query("alarm"),
+	})
+}
+
+func RenderAdminCalendarPage(c *gin.Context, db *sql.DB) {
+	renderAdminCalendarPage(c, db, http.StatusOK, "")
+}
+
+func CreateAcademicTermHandler(c *gin.Context, db *sql.DB) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	startDate, err1 := time.Parse(dateLayout, c.PostForm("start_date"))
+	endDate, err2 := time.Parse(dateLayout, c.PostForm("end_date"))
+	if name == "" || err1 != nil || err2 != nil {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверные данные формы")
+		return
+	}
+	if endDate.Before(startDate) {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Дата окончания семестра раньше даты начала")
+		return
+	}
+
+	var overlapping int
+	err := db.QueryRow(`
+        SELECT COUNT(*) FROM academic_terms
+        WHERE start_date <= $2 AND end_date >= $1
+    `, startDate, endDate).Scan(&overlapping)
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if overlapping > 0 {
+		renderAdminCalendarPage(c, db, http.StatusConflict, "Семестр пересекается с уже существующим")
+		return
+	}
+
+	_, err = db.Exec(`INSERT INTO academic_terms (name, start_date, end_date) VALUES ($1, $2, $3)`, name, startDate, endDate)
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, "Ошибка создания семестра: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/calendar?alarm=Семестр+добавлен")
+}
+
+func DeleteAcademicTermHandler(c *gin.Context, db *sql.DB) {
+	termID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверный ID семестра")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM academic_terms WHERE id = $1`, termID); err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, "Ошибка удаления семестра: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/calendar")
+}
+
+// CheckNonTeachingDay возвращает запись учебного календаря, из-за которой на дату t
+// нельзя ставить занятия (праздник, каникулы, сессия), либо nil.
+func CheckNonTeachingDay(db DBQuerier, t time.Time) (*models.CalendarPeriod, error) {
+	periods, err := loadCalendarPeriods(db)
+	if err != nil {
+		return nil, err
+	}
+	return calendar.NonTeachingPeriod(periods, t), nil
+}
+
+// nonTeachingDayMessage формирует сообщение об отказе поставить занятие на неучебный день.
+func nonTeachingDayMessage(p *models.CalendarPeriod, t time.Time) string {
+	return t.Format("02.01.2006") + " — неучебный день (" + calendar.KindName(p.Kind) + ": " + p.Name + ")"
+}
+
+// upcomingCalendarPeriods отбирает записи учебного календаря, которые ещё не закончились
+// к моменту now, — для списка ближайших праздников и переносов на страницах расписания.
+func upcomingCalendarPeriods(periods []models.CalendarPeriod, now time.Time) []models.CalendarPeriod {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var result []models.CalendarPeriod
+	for _, p := range periods {
+		if !p.EndDate.Before(today) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// CreateCalendarPeriodHandler добавляет в учебный календарь праздник, каникулы, сессию
+// или перенесённый рабочий день. Для переноса start_date — рабочий день (обычно суббота),
+// transfer_from — день, занятия которого на него переносятся.
+func CreateCalendarPeriodHandler(c *gin.Context, db *sql.DB) {
+	kind := c.PostForm("kind")
+	name := strings.TrimSpace(c.PostForm("name"))
+	startDate, err := time.Parse(dateLayout, c.PostForm("start_date"))
+	if !calendar.ValidKind(kind) || err != nil {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверные данные формы")
+		return
+	}
+
+	endDate := startDate
+	var transferFrom interface{}
+	if kind == calendar.KindWorkday {
+		from, err := time.Parse(dateLayout, c.PostForm("transfer_from"))
+		if err != nil {
+			renderAdminCalendarPage(c, db, http.StatusBadRequest, "Укажите день, с которого переносятся занятия")
+			return
+		}
+		if from.Equal(startDate) {
+			renderAdminCalendarPage(c, db, http.StatusBadRequest, "День переноса совпадает с рабочим днём")
+			return
+		}
+		transferFrom = from
+		if name == "" {
+			name = "Перенос с " + from.Format("02.01.2006")
+		}
+	} else {
+		if name == "" {
+			renderAdminCalendarPage(c, db, http.StatusBadRequest, "Укажите название")
+			return
+		}
+		if v := c.PostForm("end_date"); v != "" {
+			endDate, err = time.Parse(dateLayout, v)
+			if err != nil {
+				renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверная дата окончания")
+				return
+			}
+		}
+		if endDate.Before(startDate) {
+			renderAdminCalendarPage(c, db, http.StatusBadRequest, "Дата окончания раньше даты начала")
+			return
+		}
+	}
+
+	_, err = db.Exec(`
+        INSERT INTO academic_calendar (kind, name, start_date, end_date, transfer_from)
+        VALUES ($1, $2, $3, $4, $5)
+    `, kind, name, startDate, endDate, transferFrom)
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, "Ошибка сохранения: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/calendar?alarm=Запись+календаря+добавлена")
+}
+
+func DeleteCalendarPeriodHandler(c *gin.Context, db *sql.DB) {
+	periodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminCalendarPage(c, db, http.StatusBadRequest, "Неверный ID записи")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM academic_calendar WHERE id = $1`, periodID); err != nil {
+		renderAdminCalendarPage(c, db, http.StatusInternalServerError, "Ошибка удаления: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/calendar")
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// CheckClassroomCapacity возвращает суммарное число студентов в группах groupIDs и вместимость
+// аудитории classroomID. Нулевая вместимость означает, что она не указана и ничего не ограничивает.
+func CheckClassroomCapacity(db DBQuerier, classroomID int, groupIDs []int) (int, int, error) {
+	var students, capacity int
+	err := db.QueryRow(`
+        SELECT
+            (SELECT COUNT(*) FROM students WHERE group_id = ANY($2)),
+            COALESCE((SELECT capacity FROM classrooms WHERE id = $1), 0)
+    `, classroomID, pq.Array(groupIDs)).Scan(&students, &capacity)
+	return students, capacity, err
+}
+
+// capacityError — группы занятия не помещаются в аудиторию, а превышение вместимости
+// не разрешено. Показывается пользователю как есть; hint подсказывает, как всё же
+// сохранить занятие.
+type capacityError struct {
+	students, capacity int
+	hint               string
+}
+
+func (e *capacityError) Error() string {
+	return fmt.Sprintf("Аудитория рассчитана на %d мест, а в группах занятия %d студентов.%s", e.capacity, e.students, e.hint)
+}
+
+func isCapacityError(err error) bool {
+	var ce *capacityError
+	return errors.As(err, &ce)
+}
+
+// checkCapacity проверяет вместимость аудитории для занятия. Если группы не помещаются,
+// возвращается capacityError, пока превышение не разрешено явно (override); тогда —
+// предупреждение, которое стоит показать вместе с результатом сохранения.
+func checkCapacity(db DBQuerier, classroomID int, groupIDs []int, override bool) (string, error) {
+	students, capacity, err := CheckClassroomCapacity(db, classroomID, groupIDs)
+	if err != nil {
+		return "", fmt.Errorf("Ошибка проверки вместимости аудитории: %v", err)
+	}
+	if capacity == 0 || students <= capacity {
+		return "", nil
+	}
+	if !override {
+		return "", &capacityError{students: students, capacity: capacity}
+	}
+	return fmt.Sprintf(" Внимание: превышена вместимость аудитории (%d студентов на %d мест).", students, capacity), nil
+}
+
+// checkCapacityForm проверяет вместимость аудитории для занятия из формы: превышение
+// разрешает отмеченный флажок capacity_override.
+func checkCapacityForm(c *gin.Context, db DBQuerier, classroomID int, groupIDs []int) (string, error) {
+	warning, err := checkCapacity(db, classroomID, groupIDs, c.PostForm("capacity_override") != "")
+	var ce *capacityError
+	if errors.As(err, &ce) {
+		ce.hint = " Отметьте «Разрешить превышение вместимости», чтобы всё равно сохранить занятие."
+	}
+	return warning, err
+}
@@ -0,0 +1,533 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var departmentUsages = []referenceUsage{
+	{"преподавателей", `SELECT COUNT(*) FROM teachers WHERE department_id = $1`},
+}
+
+func loadDepartments(db *sql.DB) ([]models.Department, error) {
+	rows, err := db.Query(`
+        SELECT d.id, d.name, COALESCE(d.head_teacher_id, 0), COALESCE(h.name, ''),
+               (SELECT COUNT(*) FROM teachers t WHERE t.department_id = d.id)
+        FROM departments d
+        LEFT JOIN teachers h ON h.id = d.head_teacher_id
+        ORDER BY d.name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Department
+	for rows.Next() {
+		var d models.Department
+		if err := rows.Scan(&d.ID, &d.Name, &d.HeadTeacherID, &d.HeadName, &d.TeacherCount); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// saveDepartment сохраняет кафедру. Назначенный заведующий переводится на эту кафедру;
+// заведовать можно только одной кафедрой.
+func saveDepartment(db *sql.DB, d *models.Department) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if err := checkLength(d.Name, "название кафедры", 255, true); err != nil {
+		return err
+	}
+
+	var duplicates int
+	err := db.QueryRow(`SELECT COUNT(*) FROM departments WHERE lower(name) = lower($1) AND id <> $2`, d.Name, d.ID).Scan(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return referenceErrorf(http.StatusConflict, "Кафедра «%s» уже есть", d.Name)
+	}
+
+	if d.HeadTeacherID != 0 {
+		var headName, headsOther string
+		err := db.QueryRow(`
+            SELECT t.name, COALESCE((SELECT name FROM departments WHERE head_teacher_id = t.id AND id <> $2 LIMIT 1), '')
+            FROM teachers t WHERE t.id = $1
+        `, d.HeadTeacherID, d.ID).Scan(&headName, &headsOther)
+		if err == sql.ErrNoRows {
+			return referenceErrorf(http.StatusBadRequest, "Преподаватель не найден")
+		}
+		if err != nil {
+			return err
+		}
+		if headsOther != "" {
+			return referenceErrorf(http.StatusConflict, "%s уже заведует кафедрой «%s»", headName, headsOther)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if d.ID == 0 {
+		err = tx.QueryRow(`INSERT INTO departments (name, head_teacher_id) VALUES ($1, $2) RETURNING id`,
+			d.Name, nullableInt(d.HeadTeacherID)).Scan(&d.ID)
+		if err != nil {
+			return err
+		}
+	} else {
+		res, err := tx.Exec(`UPDATE departments SET name = $2, head_teacher_id = $3 WHERE id = $1`,
+			d.ID, d.Name, nullableInt(d.HeadTeacherID))
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return referenceErrorf(http.StatusNotFound, "Запись не найдена")
+		}
+	}
+	if d.HeadTeacherID != 0 {
+		if _, err := tx.Exec(`UPDATE teachers SET department_id = $1 WHERE id = $2`, d.ID, d.HeadTeacherID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// departmentFromForm читает кафедру из формы; пустой заведующий — «не назначен».
+func departmentFromForm(c *gin.Context) (models.Department, error) {
+	d := models.Department{Name: c.PostForm("name")}
+	if v := c.PostForm("head_teacher_id"); v != "" {
+		headID, err := strconv.Atoi(v)
+		if err != nil {
+			return d, referenceErrorf(http.StatusBadRequest, "Неверный ID заведующего")
+		}
+		d.HeadTeacherID = headID
+	}
+	return d, nil
+}
+
+func SaveDepartmentFormHandler(c *gin.Context, db *sql.DB) {
+	d, err := departmentFromForm(c)
+	if err == nil && c.Param("id") != "" {
+		d.ID, err = referenceFormID(c)
+	}
+	if err == nil {
+		err = saveDepartment(db, &d)
+	}
+	finishReferenceForm(c, db, err, "Кафедра «"+d.Name+"» сохранена")
+}
+
+func DeleteDepartmentFormHandler(c *gin.Context, db *sql.DB) {
+	id, err := referenceFormID(c)
+	if err == nil {
+		err = deleteReference(db, "departments", "кафедру", id, departmentUsages)
+	}
+	finishReferenceForm(c, db, err, "Кафедра удалена")
+}
+
+func ListDepartmentsAPIHandler(c *gin.Context, db *sql.DB) {
+	departments, err := loadDepartments(db)
+	apiPage(c, departments, err)
+}
+
+func SaveDepartmentAPIHandler(c *gin.Context, db *sql.DB) {
+	var d models.Department
+	saveReferenceAPI(c, &d, func(id int) { d.ID = id }, func() error { return saveDepartment(db, &d) })
+}
+
+func DeleteDepartmentAPIHandler(c *gin.Context, db *sql.DB) {
+	deleteReferenceAPI(c, db, "departments", "кафедру", departmentUsages)
+}
+
+// UpdateTeacherDepartmentHandler переводит преподавателя на другую кафедру. Если он
+// заведовал прежней кафедрой, она остаётся без заведующего.
+func UpdateTeacherDepartmentHandler(c *gin.Context, db *sql.DB) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Неверный ID пользователя",
+		})
+		return
+	}
+	var departmentID int
+	if v := c.PostForm("department_id"); v != "" {
+		if departmentID, err = strconv.Atoi(v); err != nil {
+			c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+				"Title": "Управление пользователями",
+				"Error": "Неверный ID кафедры",
+			})
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err == nil {
+		defer tx.Rollback()
+		_, err = tx.Exec(`
+            UPDATE departments SET head_teacher_id = NULL
+            WHERE head_teacher_id = (SELECT id FROM teachers WHERE user_id = $1) AND id IS DISTINCT FROM $2
+        `, userID, nullableInt(departmentID))
+	}
+	if err == nil {
+		_, err = tx.Exec(`UPDATE teachers SET department_id = $1 WHERE user_id = $2`, nullableInt(departmentID), userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка обновления кафедры: " + err.Error(),
+		})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/users")
+}
+
+// errNotDepartmentHead — преподаватель не заведует кафедрой.
+var errNotDepartmentHead = fmt.Errorf("Раздел доступен только заведующему кафедрой")
+
+// headDepartment возвращает кафедру, которой заведует текущий преподаватель.
+func headDepartment(c *gin.Context, db *sql.DB) (models.Department, error) {
+	var d models.Department
+	userID, _ := c.Get("user_id")
+	err := db.QueryRow(`
+        SELECT d.id, d.name, t.id, t.name
+        FROM departments d
+        JOIN teachers t ON t.id = d.head_teacher_id
+        WHERE t.user_id = $1
+    `, userID).Scan(&d.ID, &d.Name, &d.HeadTeacherID, &d.HeadName)
+	if err == sql.ErrNoRows {
+		return d, errNotDepartmentHead
+	}
+	return d, err
+}
+
+// departmentRequestsWhere отбирает запросы, поданные преподавателями кафедры или
+// касающиеся их занятий ($1 — id кафедры).
+const departmentRequestsWhere = `(
+        EXISTS (SELECT 1 FROM teachers dt WHERE dt.user_id = r.user_id AND dt.department_id = $1)
+        OR EXISTS (SELECT 1 FROM schedule ds JOIN teachers dt ON dt.id = ds.teacher_id
+                   WHERE ds.id = r.schedule_id AND dt.department_id = $1)
+    )`
+
+// departmentApprovalWhere ограничивает одобрение заведующим: предложение должно
+// касаться только занятий и преподавателей его кафедры ($1 — id кафедры). Текстовые
+// запросы расписание не меняют и одобряются в пределах departmentRequestsWhere.
+const departmentApprovalWhere = `(
+        r.kind = 'text'
+        OR (EXISTS (SELECT 1 FROM schedule ds JOIN teachers dt ON dt.id = ds.teacher_id
+                    WHERE ds.id = r.schedule_id AND dt.department_id = $1)
+            AND (r.swap_schedule_id IS NULL
+                 OR EXISTS (SELECT 1 FROM schedule ds JOIN teachers dt ON dt.id = ds.teacher_id
+                            WHERE ds.id = r.swap_schedule_id AND dt.department_id = $1))
+            AND (r.proposed_teacher_id IS NULL
+                 OR EXISTS (SELECT 1 FROM teachers dt WHERE dt.id = r.proposed_teacher_id AND dt.department_id = $1)))
+    )`
+
+func loadDepartmentTeachers(db *sql.DB, departmentID int) ([]models.TeacherDisplay, error) {
+	rows, err := db.Query(`SELECT id, name FROM teachers WHERE department_id = $1 ORDER BY name`, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TeacherDisplay
+	for rows.Next() {
+		var t models.TeacherDisplay
+		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+func renderDepartmentPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
+	dept, err := headDepartment(c, db)
+	if err == errNotDepartmentHead {
+		c.HTML(http.StatusForbidden, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": "Ошибка загрузки кафедры: " + err.Error(),
+		})
+		return
+	}
+
+	teachers, err := loadDepartmentTeachers(db, dept.ID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": "Ошибка загрузки преподавателей: " + err.Error(),
+		})
+		return
+	}
+	classrooms, err := loadAllClassrooms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": "Ошибка загрузки аудиторий: " + err.Error(),
+		})
+		return
+	}
+	bells, err := loadBellSchedule(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": "Ошибка загрузки расписания звонков: " + err.Error(),
+		})
+		return
+	}
+
+	// Показываются предстоящие занятия; фильтр — по преподавателю кафедры.
+	teacherFilter := c.Query("teacher")
+	where := "WHERE t.department_id = $1 AND s.start_time >= $2"
+	args := []interface{}{dept.ID, dateOnly(time.Now())}
+	if teacherFilter != "" {
+		where += " AND s.teacher_id = $3"
+		args = append(args, teacherFilter)
+	}
+	lessons, err := loadScheduleView(db, where, args...)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": "Ошибка загрузки расписания: " + err.Error(),
+		})
+		return
+	}
+
+	requests, err := loadRequests(db, "r.status = 'pending' AND "+departmentRequestsWhere, "r.created_at DESC, r.id DESC", dept.ID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "department_teacher", gin.H{
+			"Title": "Кафедра",
+			"Error": "Ошибка загрузки запросов: " + err.Error(),
+		})
+		return
+	}
+
+	c.HTML(status, "department_teacher", gin.H{
+		"Title":         "Кафедра «" + dept.Name + "»",
+		"Department":    dept,
+		"Teachers":      teachers,
+		"Classrooms":    classrooms,
+		"PairOptions":   pairOptions(bells),
+		"Lessons":       lessons,
+		"Requests":      requests,
+		"KindNames":     requestKindNames,
+		"TeacherFilter": teacherFilter,
+		"Error":         errMsg,
+		"Alarm":         c.Query("alarm"),
+	})
+}
+
+func RenderDepartmentPage(c *gin.Context, db *sql.DB) {
+	renderDepartmentPage(c, db, http.StatusOK, "")
+}
+
+// departmentLesson проверяет, что занятие ведёт преподаватель кафедры заведующего,
+// и возвращает кафедру и id занятия.
+func departmentLesson(c *gin.Context, db *sql.DB) (models.Department, int, int, error) {
+	dept, err := headDepartment(c, db)
+	if err != nil {
+		return dept, 0, http.StatusForbidden, err
+	}
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return dept, 0, http.StatusBadRequest, fmt.Errorf("Неверный ID занятия")
+	}
+	var inDepartment bool
+	err = db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM schedule s JOIN teachers t ON t.id = s.teacher_id
+                       WHERE s.id = $1 AND t.department_id = $2)
+    `, scheduleID, dept.ID).Scan(&inDepartment)
+	if err != nil {
+		return dept, 0, http.StatusInternalServerError, err
+	}
+	if !inDepartment {
+		return dept, 0, http.StatusNotFound, fmt.Errorf("Занятие не найдено среди занятий кафедры")
+	}
+	return dept, scheduleID, http.StatusOK, nil
+}
+
+// UpdateDepartmentLessonHandler переносит занятие кафедры: другой день и пара (или время),
+// аудитория и преподаватель кафедры. Предмет и группы не меняются; превышение вместимости
+// аудитории, как и у администратора, нужно разрешить флажком capacity_override.
+func UpdateDepartmentLessonHandler(c *gin.Context, db *sql.DB) {
+	dept, scheduleID, status, err := departmentLesson(c, db)
+	if err != nil {
+		renderDepartmentPage(c, db, status, err.Error())
+		return
+	}
+
+	teacherID, _ := strconv.Atoi(c.PostForm("teacher_id"))
+	classroomID, _ := strconv.Atoi(c.PostForm("classroom_id"))
+	var teacherInDepartment bool
+	err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM teachers WHERE id = $1 AND department_id = $2)`, teacherID, dept.ID).
+		Scan(&teacherInDepartment)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !teacherInDepartment {
+		renderDepartmentPage(c, db, http.StatusBadRequest, "Занятие можно передать только преподавателю кафедры")
+		return
+	}
+
+	startTime, endTime, pairNumber, err := parseLessonTimeForm(c, db, classroomID)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusBadRequest, err.Error())
+		return
+	}
+	dayOff, err := CheckNonTeachingDay(db, startTime)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, "Ошибка проверки учебного календаря: "+err.Error())
+		return
+	}
+	if dayOff != nil {
+		renderDepartmentPage(c, db, http.StatusConflict, "Нельзя перенести занятие: "+nonTeachingDayMessage(dayOff, startTime)+".")
+		return
+	}
+
+	lesson, err := loadRequestLesson(db, scheduleID)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	collision, err := CheckScheduleCollisionForGroups(db, teacherID, classroomID, lesson.GroupIDs, startTime, endTime, scheduleID)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, "Ошибка проверки коллизий: "+err.Error())
+		return
+	}
+	if collision {
+		renderDepartmentPage(c, db, http.StatusConflict, "Коллизия обнаружена: у преподавателя, в аудитории или у одной из групп уже существует пересекающееся занятие, либо преподаватель недоступен в это время.")
+		return
+	}
+	capacityWarning, err := checkCapacityForm(c, db, classroomID, lesson.GroupIDs)
+	if isCapacityError(err) {
+		renderDepartmentPage(c, db, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	if err := detachFromSeries(tx, scheduleID); err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, "Ошибка отвязки занятия от серии: "+err.Error())
+		return
+	}
+	_, err = tx.Exec(`
+        UPDATE schedule SET teacher_id = $1, classroom_id = $2, start_time = $3, end_time = $4, pair_number = $5
+        WHERE id = $6
+    `, teacherID, classroomID, startTime, endTime, nullableInt(pairNumber), scheduleID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, "Ошибка обновления расписания: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/teacher/department?alarm="+url.QueryEscape("Занятие перенесено."+capacityWarning))
+}
+
+// DeleteDepartmentLessonHandler отменяет занятие преподавателя кафедры.
+func DeleteDepartmentLessonHandler(c *gin.Context, db *sql.DB) {
+	_, scheduleID, status, err := departmentLesson(c, db)
+	if err != nil {
+		renderDepartmentPage(c, db, status, err.Error())
+		return
+	}
+	if _, err := deleteLesson(db, scheduleID); err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, "Ошибка удаления занятия: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/teacher/department?alarm="+url.QueryEscape("Занятие отменено"))
+}
+
+// ProcessDepartmentRequestHandler одобряет или отклоняет запрос, относящийся к кафедре;
+// в истории запроса решение записывается от имени заведующего.
+func ProcessDepartmentRequestHandler(c *gin.Context, db *sql.DB, action string) {
+	dept, err := headDepartment(c, db)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusForbidden, err.Error())
+		return
+	}
+	reqID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusBadRequest, "Неверный ID запроса")
+		return
+	}
+	status, ok := requestActionStatus(action)
+	if !ok {
+		renderDepartmentPage(c, db, http.StatusBadRequest, "Неверное действие")
+		return
+	}
+
+	var inDepartment bool
+	err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM requests r WHERE r.id = $2 AND `+departmentRequestsWhere+`)`, dept.ID, reqID).
+		Scan(&inDepartment)
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !inDepartment {
+		renderDepartmentPage(c, db, http.StatusNotFound, "Запрос не относится к вашей кафедре")
+		return
+	}
+	if status == "approved" {
+		var approvable bool
+		err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM requests r WHERE r.id = $2 AND `+departmentApprovalWhere+`)`, dept.ID, reqID).
+			Scan(&approvable)
+		if err != nil {
+			renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !approvable {
+			renderDepartmentPage(c, db, http.StatusForbidden, "Запрос затрагивает занятия или преподавателей другой кафедры: его может одобрить только администратор")
+			return
+		}
+	}
+
+	userIDVal, _ := c.Get("user_id")
+	userID, _ := userIDVal.(int)
+	err = processRequest(db, reqID, userID, status, strings.TrimSpace(c.PostForm("reason")))
+	if isProposalError(err) {
+		renderDepartmentPage(c, db, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		renderDepartmentPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	alarm := "Запрос отклонён"
+	if status == "approved" {
+		alarm = "Запрос одобрен"
+	}
+	c.Redirect(http.StatusSeeOther, "/teacher/department?alarm="+url.QueryEscape(alarm))
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/models"
+	"scheduleApp/internal/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-pdf/fpdf"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// scheduleExport — выборка для печати и выгрузки: занятия по фильтрам страницы
+// расписания администратора и подпись к ней. Поля, по которым задан фильтр,
+// в ячейках сетки не повторяются: на двери аудитории номер аудитории не нужен.
+type scheduleExport struct {
+	title         string
+	byGroup       bool
+	byTeacher     bool
+	byClassroom   bool
+	lessons       []models.ScheduleDisplay
+	bells         []models.BellPeriod
+	nonTeaching   []models.CalendarPeriod
+	weekStart     time.Time
+	weekEnd       time.Time
+	weekSpecified bool
+}
+
+// scheduleExportTitle подписывает выгрузку названиями выбранных группы, преподавателя,
+// аудитории и кафедры.
+func scheduleExportTitle(db *sql.DB, filter adminScheduleFilter) (string, error) {
+	var parts []string
+	for _, f := range []struct {
+		value, label, query string
+	}{
+		{filter.Group, "Группа", `SELECT name FROM groups WHERE id = $1`},
+		{filter.Teacher, "Преподаватель", `SELECT name FROM teachers WHERE id = $1`},
+		{filter.Classroom, "Аудитория", `SELECT room_number FROM classrooms WHERE id = $1`},
+		{filter.Department, "Кафедра", `SELECT name FROM departments WHERE id = $1`},
+	} {
+		if f.value == "" {
+			continue
+		}
+		var name string
+		if err := db.QueryRow(f.query, f.value).Scan(&name); err != nil {
+			return "", err
+		}
+		parts = append(parts, f.label+" "+name)
+	}
+	if len(parts) == 0 {
+		return "Расписание занятий", nil
+	}
+	return "Расписание: " + strings.Join(parts, ", "), nil
+}
+
+// loadScheduleExport читает фильтры из запроса так же, как RenderAdminSchedulesPageWithFilters.
+// Для PDF выборка ограничивается неделей (параметр week, по умолчанию текущая).
+func loadScheduleExport(c *gin.Context, db *sql.DB, weekly bool) (*scheduleExport, int, error) {
+	filter := parseAdminScheduleFilter(c)
+	exp := &scheduleExport{
+		byGroup:     filter.Group != "",
+		byTeacher:   filter.Teacher != "",
+		byClassroom: filter.Classroom != "",
+	}
+
+	day := dateOnly(time.Now())
+	if week := c.Query("week"); week != "" {
+		d, err := time.Parse(dateLayout, week)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("неверная дата недели")
+		}
+		day = d
+		exp.weekSpecified = true
+	}
+	exp.weekStart = calendar.MondayOf(day)
+	exp.weekEnd = exp.weekStart.AddDate(0, 0, 7)
+
+	title, err := scheduleExportTitle(db, filter)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, fmt.Errorf("группа, преподаватель, аудитория или кафедра не найдены")
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	exp.title = title
+
+	clauses, args := filter.where()
+	if weekly || exp.weekSpecified {
+		args = append(args, exp.weekStart, exp.weekEnd)
+		clauses = append(clauses, fmt.Sprintf("s.start_time >= $%d AND s.start_time < $%d", len(args)-1, len(args)))
+	}
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + joinClauses(clauses, " AND ")
+	}
+	if exp.lessons, err = loadScheduleView(db, where, args...); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if exp.bells, err = loadBellSchedule(db); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if weekly {
+		if exp.nonTeaching, err = loadCalendarPeriods(db); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+	return exp, http.StatusOK, nil
+}
+
+// lessonPair определяет номер пары по времени начала занятия: сначала по звонкам
+// корпуса аудитории, затем по общим. Если время не совпадает ни с одной парой — 0.
+func lessonPair(bells []models.BellPeriod, l models.ScheduleDisplay) int {
+	clock := l.StartTime.Format("15:04")
+	pair := 0
+	for _, b := range bells {
+		if b.StartClock != clock {
+			continue
+		}
+		if b.Building == l.Building && l.Building != "" {
+			return b.PairNumber
+		}
+		if b.Building == "" {
+			pair = b.PairNumber
+		}
+	}
+	return pair
+}
+
+// ExportSchedulesHandler выгружает отфильтрованное расписание: format=xlsx — таблица
+// занятий (её можно загрузить обратно через импорт), format=pdf — сетка на неделю
+// «день × пара» для печати.
+func ExportSchedulesHandler(c *gin.Context, db *sql.DB) {
+	format := c.DefaultQuery("format", "xlsx")
+	if format != "xlsx" && format != "pdf" {
+		c.String(http.StatusBadRequest, "Неизвестный формат выгрузки")
+		return
+	}
+
+	exp, status, err := loadScheduleExport(c, db, format == "pdf")
+	if err != nil {
+		c.String(status, "Ошибка выгрузки расписания: %v", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "xlsx":
+		err = writeScheduleXLSX(&buf, exp)
+	case "pdf":
+		err = writeSchedulePDF(&buf, exp)
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Ошибка выгрузки расписания: %v", err)
+		return
+	}
+
+	filename := "schedule"
+	if format == "pdf" || exp.weekSpecified {
+		filename += "-" + exp.weekStart.Format(dateLayout)
+	}
+	contentType := "application/pdf"
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+"."+format+`"`)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// Заголовки XLSX совпадают с названиями столбцов импорта.
+var scheduleXLSXHeader = []interface{}{
+	"Дата", "День недели", "Пара", "Начало", "Окончание",
+	"Предмет", "Преподаватель", "Аудитория", "Корпус", "Группы",
+}
+
+func writeScheduleXLSX(w io.Writer, exp *scheduleExport) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Расписание"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+	if err := f.SetDocProps(&excelize.DocProperties{Title: exp.title, Language: "ru-RU"}); err != nil {
+		return err
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	dateFormat := "dd.mm.yyyy"
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+
+	if err := f.SetSheetRow(sheet, "A1", &scheduleXLSXHeader); err != nil {
+		return err
+	}
+	for i, l := range exp.lessons {
+		var pair interface{}
+		if n := lessonPair(exp.bells, l); n > 0 {
+			pair = n
+		}
+		row := []interface{}{
+			dateOnly(l.StartTime), web.WeekdayName(l.StartTime.Weekday()), pair,
+			l.StartTime.Format("15:04"), l.EndTime.Format("15:04"),
+			l.SubjectName, l.TeacherName, l.RoomNumber, l.Building, l.GroupNames,
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	last := len(exp.lessons) + 1
+	if err := f.SetCellStyle(sheet, "A1", "J1", headerStyle); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "A2", fmt.Sprintf("A%d", last), dateStyle); err != nil {
+		return err
+	}
+	for col, width := range map[string]float64{"A": 12, "B": 14, "C": 6, "D": 8, "E": 10, "F": 30, "G": 24, "H": 10, "I": 14, "J": 24} {
+		if err := f.SetColWidth(sheet, col, col, width); err != nil {
+			return err
+		}
+	}
+	if err := f.AutoFilter(sheet, fmt.Sprintf("A1:J%d", last), nil); err != nil {
+		return err
+	}
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// Размеры сетки PDF в миллиметрах (A4, альбомная ориентация).
+const (
+	pdfMargin     = 10.0
+	pdfPairColumn = 24.0
+	pdfLineHeight = 4.0
+	pdfFontSize   = 8.0
+	pdfMinRow     = 14.0
+)
+
+// scheduleGridRow — строка сетки: пара со временем звонков или занятия вне сетки (pair = 0).
+type scheduleGridRow struct {
+	pair  int
+	label string
+	cells map[time.Weekday][]models.ScheduleDisplay
+}
+
+// scheduleGrid раскладывает занятия недели по дням и парам.
+func scheduleGrid(exp *scheduleExport) ([]time.Time, []*scheduleGridRow) {
+	days := []time.Time{}
+	for d := 0; d < 6; d++ {
+		days = append(days, exp.weekStart.AddDate(0, 0, d))
+	}
+	byPair := map[int]*scheduleGridRow{}
+	var rows []*scheduleGridRow
+	for _, b := range pairOptions(exp.bells) {
+		row := &scheduleGridRow{
+			pair:  b.PairNumber,
+			label: fmt.Sprintf("%d пара\n%s–%s", b.PairNumber, b.StartClock, b.EndClock),
+			cells: map[time.Weekday][]models.ScheduleDisplay{},
+		}
+		byPair[b.PairNumber] = row
+		rows = append(rows, row)
+	}
+
+	sunday := false
+	for _, l := range exp.lessons {
+		if l.StartTime.Weekday() == time.Sunday {
+			sunday = true
+		}
+		pair := lessonPair(exp.bells, l)
+		row, ok := byPair[pair]
+		if !ok {
+			row = &scheduleGridRow{pair: pair, label: "Вне сетки\nзвонков", cells: map[time.Weekday][]models.ScheduleDisplay{}}
+			if pair > 0 {
+				row.label = fmt.Sprintf("%d пара", pair)
+			}
+			byPair[pair] = row
+			rows = append(rows, row)
+		}
+		row.cells[l.StartTime.Weekday()] = append(row.cells[l.StartTime.Weekday()], l)
+	}
+	if sunday {
+		days = append(days, exp.weekStart.AddDate(0, 0, 6))
+	}
+	// Занятия вне сетки звонков идут последней строкой.
+	sort.SliceStable(rows, func(i, j int) bool {
+		if (rows[i].pair == 0) != (rows[j].pair == 0) {
+			return rows[j].pair == 0
+		}
+		return rows[i].pair < rows[j].pair
+	})
+	return days, rows
+}
+
+// gridCellText описывает занятия ячейки, опуская то, что уже указано в заголовке.
+func gridCellText(exp *scheduleExport, lessons []models.ScheduleDisplay, withTime bool) string {
+	var blocks []string
+	for _, l := range lessons {
+		var lines []string
+		if withTime {
+			lines = append(lines, l.StartTime.Format("15:04")+"–"+l.EndTime.Format("15:04"))
+		}
+		lines = append(lines, l.SubjectName)
+		if !exp.byTeacher {
+			lines = append(lines, l.TeacherName)
+		}
+		if !exp.byClassroom {
+			lines = append(lines, strings.TrimSpace("ауд. "+l.RoomNumber+" "+l.Building))
+		}
+		if !exp.byGroup && l.GroupNames != "" {
+			lines = append(lines, l.GroupNames)
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func writeSchedulePDF(w io.Writer, exp *scheduleExport) error {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("Go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("Go", "B", gobold.TTF)
+	pdf.SetTitle(exp.title, true)
+	pdf.SetCreator("scheduleApp", true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+
+	days, rows := scheduleGrid(exp)
+	pageWidth, pageHeight := pdf.GetPageSize()
+	dayWidth := (pageWidth - 2*pdfMargin - pdfPairColumn) / float64(len(days))
+
+	// rowHeight считает высоту строки по самой длинной ячейке.
+	rowHeight := func(texts []string, widths []float64) float64 {
+		h := pdfMinRow
+		for i, t := range texts {
+			if lh := float64(len(pdf.SplitText(t, widths[i]-2)))*pdfLineHeight + 2; lh > h {
+				h = lh
+			}
+		}
+		return h
+	}
+	drawRow := func(texts []string, widths []float64, h float64, fill []bool) {
+		x, y := pdf.GetXY()
+		for i, t := range texts {
+			style := "D"
+			if fill[i] {
+				style = "FD"
+			}
+			pdf.Rect(x, y, widths[i], h, style)
+			pdf.SetXY(x+1, y+1)
+			pdf.MultiCell(widths[i]-2, pdfLineHeight, t, "", "L", false)
+			x += widths[i]
+		}
+		pdf.SetXY(pdfMargin, y+h)
+	}
+
+	widths := []float64{pdfPairColumn}
+	header := []string{"Пара"}
+	headerFill := []bool{true}
+	for _, d := range days {
+		widths = append(widths, dayWidth)
+		text := web.WeekdayName(d.Weekday()) + "\n" + d.Format("02.01.2006")
+		if note := calendar.DayNote(exp.nonTeaching, d); note != "" {
+			text += "\n" + note
+		}
+		header = append(header, text)
+		headerFill = append(headerFill, true)
+	}
+
+	newPage := func() {
+		pdf.AddPage()
+		pdf.SetFont("Go", "B", 12)
+		pdf.CellFormat(0, 7, exp.title, "", 1, "L", false, 0, "")
+		pdf.SetFont("Go", "", 9)
+		pdf.CellFormat(0, 5, "Неделя "+exp.weekStart.Format("02.01.2006")+" – "+
+			exp.weekEnd.AddDate(0, 0, -1).Format("02.01.2006"), "", 1, "L", false, 0, "")
+		pdf.Ln(2)
+		pdf.SetFont("Go", "B", pdfFontSize)
+		pdf.SetFillColor(230, 230, 230)
+		drawRow(header, widths, rowHeight(header, widths), headerFill)
+		pdf.SetFont("Go", "", pdfFontSize)
+	}
+	newPage()
+
+	for _, row := range rows {
+		texts := []string{row.label}
+		fill := []bool{true}
+		for _, d := range days {
+			texts = append(texts, gridCellText(exp, row.cells[d.Weekday()], row.pair == 0))
+			fill = append(fill, calendar.NonTeachingPeriod(exp.nonTeaching, d) != nil)
+		}
+		h := rowHeight(texts, widths)
+		if pdf.GetY()+h > pageHeight-pdfMargin {
+			newPage()
+		}
+		drawRow(texts, widths, h, fill)
+	}
+	if len(exp.lessons) == 0 {
+		pdf.Ln(4)
+		pdf.CellFormat(0, 6, "На этой неделе занятий нет", "", 1, "L", false, 0, "")
+	}
+	return pdf.Output(w)
+}
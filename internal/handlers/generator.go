@@ -0,0 +1,698 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/generator"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generatorIterations — число шагов локального поиска при улучшении черновика.
+const generatorIterations = 3000
+
+// draftGridRow — строка сетки черновика: одна пара и занятия в неё по дням недели.
+type draftGridRow struct {
+	PairNumber int
+	Clock      string
+	Cells      [][]models.DraftLessonDisplay
+}
+
+func loadCurriculum(db *sql.DB) ([]models.CurriculumDisplay, error) {
+	rows, err := db.Query(`
+		SELECT cu.id, g.name, sub.name, t.name, cu.hours_per_week
+		FROM curriculum cu
+		JOIN groups g ON cu.group_id = g.id
+		JOIN subjects sub ON cu.subject_id = sub.id
+		JOIN teachers t ON cu.teacher_id = t.id
+		ORDER BY g.name, sub.name;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.CurriculumDisplay
+	for rows.Next() {
+		var cu models.CurriculumDisplay
+		if err := rows.Scan(&cu.ID, &cu.GroupName, &cu.SubjectName, &cu.TeacherName, &cu.HoursPerWeek); err != nil {
+			return nil, err
+		}
+		result = append(result, cu)
+	}
+	return result, nil
+}
+
+func loadTeacherPreferences(db *sql.DB) ([]models.TeacherPreferenceDisplay, error) {
+	rows, err := db.Query(`
+		SELECT p.id, t.name, p.weekday, p.pair_number, p.penalty
+		FROM teacher_slot_preferences p
+		JOIN teachers t ON p.teacher_id = t.id
+		ORDER BY t.name, p.weekday, p.pair_number;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TeacherPreferenceDisplay
+	for rows.Next() {
+		var p models.TeacherPreferenceDisplay
+		if err := rows.Scan(&p.ID, &p.TeacherName, &p.Weekday, &p.PairNumber, &p.Penalty); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func loadTimetableDrafts(db *sql.DB) ([]models.TimetableDraft, error) {
+	rows, err := db.Query(`
+		SELECT id, week_start, placed, unplaced, group_gaps, teacher_preference_penalty,
+		       building_changes, score, unplaced_details, created_at, applied_at
+		FROM timetable_drafts
+		ORDER BY created_at DESC
+		LIMIT 10;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TimetableDraft
+	for rows.Next() {
+		var d models.TimetableDraft
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WeekStart, &d.Placed, &d.Unplaced, &d.GroupGaps, &d.TeacherPreferencePenalty,
+			&d.BuildingChanges, &d.Score, &d.UnplacedDetails, &d.CreatedAt, &appliedAt); err != nil {
+			return nil, err
+		}
+		if appliedAt.Valid {
+			d.AppliedAt = &appliedAt.Time
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func loadDraftLessons(db *sql.DB, draftID int) ([]models.DraftLessonDisplay, error) {
+	rows, err := db.Query(`
+		SELECT dl.weekday, dl.pair_number, g.name, sub.name, t.name, c.room_number
+		FROM timetable_draft_lessons dl
+		JOIN groups g ON dl.group_id = g.id
+		JOIN subjects sub ON dl.subject_id = sub.id
+		JOIN teachers t ON dl.teacher_id = t.id
+		JOIN classrooms c ON dl.classroom_id = c.id
+		WHERE dl.draft_id = $1
+		ORDER BY dl.weekday, dl.pair_number, g.name;
+	`, draftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.DraftLessonDisplay
+	for rows.Next() {
+		var l models.DraftLessonDisplay
+		if err := rows.Scan(&l.Weekday, &l.PairNumber, &l.GroupName, &l.SubjectName, &l.TeacherName, &l.RoomNumber); err != nil {
+			return nil, err
+		}
+		result = append(result, l)
+	}
+	return result, nil
+}
+
+// buildDraftGrid раскладывает занятия черновика по сетке «пара × день недели».
+func buildDraftGrid(lessons []models.DraftLessonDisplay, bells []models.BellPeriod, days int) []draftGridRow {
+	var grid []draftGridRow
+	rowByPair := make(map[int]int)
+	addRow := func(pair int, clock string) {
+		rowByPair[pair] = len(grid)
+		grid = append(grid, draftGridRow{PairNumber: pair, Clock: clock, Cells: make([][]models.DraftLessonDisplay, days)})
+	}
+	for _, b := range pairOptions(bells) {
+		addRow(b.PairNumber, b.StartClock+"–"+b.EndClock)
+	}
+	for _, l := range lessons {
+		if _, ok := rowByPair[l.PairNumber]; !ok {
+			addRow(l.PairNumber, "")
+		}
+		if l.Weekday >= 1 && l.Weekday <= days {
+			row := &grid[rowByPair[l.PairNumber]]
+			row.Cells[l.Weekday-1] = append(row.Cells[l.Weekday-1], l)
+		}
+	}
+	return grid
+}
+
+func renderAdminGeneratorPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
+	fail := func(msg string, err error) {
+		c.HTML(http.StatusInternalServerError, "generator_admin", gin.H{
+			"Title": "Генератор расписания",
+			"Error": msg + ": " + err.Error(),
+		})
+	}
+
+	curriculum, err := loadCurriculum(db)
+	if err != nil {
+		fail("Ошибка загрузки учебного плана", err)
+		return
+	}
+	preferences, err := loadTeacherPreferences(db)
+	if err != nil {
+		fail("Ошибка загрузки пожеланий преподавателей", err)
+		return
+	}
+	drafts, err := loadTimetableDrafts(db)
+	if err != nil {
+		fail("Ошибка загрузки черновиков", err)
+		return
+	}
+	bells, err := loadBellSchedule(db)
+	if err != nil {
+		fail("Ошибка загрузки расписания звонков", err)
+		return
+	}
+	groups, err := loadAllGroups(db)
+	if err != nil {
+		fail("Ошибка загрузки групп", err)
+		return
+	}
+	subjects, err := loadAllSubjects(db)
+	if err != nil {
+		fail("Ошибка загрузки предметов", err)
+		return
+	}
+	teachers, err := loadAllTeachers(db)
+	if err != nil {
+		fail("Ошибка загрузки преподавателей", err)
+		return
+	}
+
+	// Показываем выбранный черновик, а по умолчанию — последний созданный.
+	var selected *models.TimetableDraft
+	selectedID, _ := strconv.Atoi(c.Query("draft_id"))
+	for i := range drafts {
+		if drafts[i].ID == selectedID || (selectedID == 0 && i == 0) {
+			selected = &drafts[i]
+			break
+		}
+	}
+
+	var grid []draftGridRow
+	var days []time.Time
+	if selected != nil {
+		lessons, err := loadDraftLessons(db, selected.ID)
+		if err != nil {
+			fail("Ошибка загрузки занятий черновика", err)
+			return
+		}
+		dayCount := 5
+		for _, l := range lessons {
+			if l.Weekday > dayCount {
+				dayCount = l.Weekday
+			}
+		}
+		for i := 0; i < dayCount; i++ {
+			days = append(days, selected.WeekStart.AddDate(0, 0, i))
+		}
+		grid = buildDraftGrid(lessons, bells, dayCount)
+	}
+
+	c.HTML(status, "generator_admin", gin.H{
+		"Title":       "Генератор расписания",
+		"Curriculum":  curriculum,
+		"Preferences": preferences,
+		"Drafts":      drafts,
+		"Draft":       selected,
+		"Days":        days,
+		"Grid":        grid,
+		"PairOptions": pairOptions(bells),
+		"Groups":      groups,
+		"Subjects":    subjects,
+		"Teachers":    teachers,
+		"Error":       errMsg,
+		"Alarm":       c.Query("alarm"),
+	})
+}
+
+func RenderAdminGeneratorPage(c *gin.Context, db *sql.DB) {
+	renderAdminGeneratorPage(c, db, http.StatusOK, "")
+}
+
+func CreateCurriculumItemHandler(c *gin.Context, db *sql.DB) {
+	groupID, err1 := strconv.Atoi(c.PostForm("group_id"))
+	subjectID, err2 := strconv.Atoi(c.PostForm("subject_id"))
+	teacherID, err3 := strconv.Atoi(c.PostForm("teacher_id"))
+	hours, err4 := strconv.Atoi(c.PostForm("hours_per_week"))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || hours <= 0 {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверные данные формы")
+		return
+	}
+
+	_, err := db.Exec(`
+        INSERT INTO curriculum (group_id, subject_id, teacher_id, hours_per_week)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (group_id, subject_id, teacher_id) DO UPDATE SET hours_per_week = EXCLUDED.hours_per_week
+    `, groupID, subjectID, teacherID, hours)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка сохранения учебного плана: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/generator?alarm=Учебный+план+обновлён")
+}
+
+func DeleteCurriculumItemHandler(c *gin.Context, db *sql.DB) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверный ID строки учебного плана")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM curriculum WHERE id = $1`, itemID); err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка удаления строки учебного плана: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/generator")
+}
+
+func CreateTeacherPreferenceHandler(c *gin.Context, db *sql.DB) {
+	teacherID, err1 := strconv.Atoi(c.PostForm("teacher_id"))
+	weekday, err2 := strconv.Atoi(c.PostForm("weekday"))
+	pairNumber, err3 := strconv.Atoi(c.PostForm("pair_number"))
+	penalty, err4 := strconv.Atoi(c.DefaultPostForm("penalty", "1"))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || weekday < 1 || weekday > 6 || pairNumber <= 0 || penalty <= 0 {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверные данные формы")
+		return
+	}
+
+	_, err := db.Exec(`
+        INSERT INTO teacher_slot_preferences (teacher_id, weekday, pair_number, penalty)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (teacher_id, weekday, pair_number) DO UPDATE SET penalty = EXCLUDED.penalty
+    `, teacherID, weekday, pairNumber, penalty)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка сохранения пожелания: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/generator?alarm=Пожелание+преподавателя+сохранено")
+}
+
+func DeleteTeacherPreferenceHandler(c *gin.Context, db *sql.DB) {
+	prefID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверный ID пожелания")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM teacher_slot_preferences WHERE id = $1`, prefID); err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка удаления пожелания: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/generator")
+}
+
+// markSlot отмечает слот занятым для идентификатора id.
+func markSlot(m map[int]map[generator.Slot]bool, id int, slot generator.Slot) {
+	if m[id] == nil {
+		m[id] = make(map[generator.Slot]bool)
+	}
+	m[id][slot] = true
+}
+
+// buildTimetableProblem собирает задачу для генератора: занятия по учебному плану
+// (одна пара на каждые два часа в неделю), аудитории, пары из расписания звонков,
+// слоты, уже занятые существующими занятиями недели или недоступностью преподавателей,
+// и пожелания преподавателей.
+// Вторым значением возвращает подписи строк учебного плана для отчёта.
+func buildTimetableProblem(db *sql.DB, weekStart time.Time, days int) (generator.Problem, map[int]string, error) {
+	p := generator.Problem{
+		Days:             days,
+		BusyTeachers:     make(map[int]map[generator.Slot]bool),
+		BusyRooms:        make(map[int]map[generator.Slot]bool),
+		BusyGroups:       make(map[int]map[generator.Slot]bool),
+		TeacherPenalties: make(map[int]map[generator.Slot]int),
+		Weights:          generator.DefaultWeights,
+	}
+	labels := make(map[int]string)
+
+	bells, err := loadBellSchedule(db)
+	if err != nil {
+		return p, nil, err
+	}
+	periods := pairOptions(bells)
+	if len(periods) == 0 {
+		return p, nil, fmt.Errorf("расписание звонков не задано")
+	}
+	for _, b := range periods {
+		p.Pairs = append(p.Pairs, b.PairNumber)
+	}
+
+	rows, err := db.Query(`
+		SELECT cu.id, cu.group_id, cu.subject_id, cu.teacher_id, cu.hours_per_week,
+		       g.name, sub.name, t.name,
+		       (SELECT COUNT(*) FROM students st WHERE st.group_id = cu.group_id)
+		FROM curriculum cu
+		JOIN groups g ON cu.group_id = g.id
+		JOIN subjects sub ON cu.subject_id = sub.id
+		JOIN teachers t ON cu.teacher_id = t.id
+		ORDER BY cu.id;
+	`)
+	if err != nil {
+		return p, nil, err
+	}
+	for rows.Next() {
+		var l generator.Lesson
+		var hours int
+		var groupName, subjectName, teacherName string
+		if err := rows.Scan(&l.CurriculumID, &l.GroupID, &l.SubjectID, &l.TeacherID, &hours,
+			&groupName, &subjectName, &teacherName, &l.GroupSize); err != nil {
+			rows.Close()
+			return p, nil, err
+		}
+		labels[l.CurriculumID] = fmt.Sprintf("%s — %s (%s)", groupName, subjectName, teacherName)
+		for i := 0; i < (hours+1)/2; i++ {
+			p.Lessons = append(p.Lessons, l)
+		}
+	}
+	rows.Close()
+	if len(p.Lessons) == 0 {
+		return p, nil, fmt.Errorf("учебный план пуст")
+	}
+
+	rows, err = db.Query(`SELECT id, COALESCE(capacity, 0), COALESCE(building, '') FROM classrooms ORDER BY id;`)
+	if err != nil {
+		return p, nil, err
+	}
+	for rows.Next() {
+		var r generator.Room
+		if err := rows.Scan(&r.ID, &r.Capacity, &r.Building); err != nil {
+			rows.Close()
+			return p, nil, err
+		}
+		p.Rooms = append(p.Rooms, r)
+	}
+	rows.Close()
+	if len(p.Rooms) == 0 {
+		return p, nil, fmt.Errorf("нет ни одной аудитории")
+	}
+
+	weekEnd := weekStart.AddDate(0, 0, days)
+	rows, err = db.Query(`
+		SELECT s.teacher_id, s.classroom_id, COALESCE(sg.group_id, 0), s.start_time, s.end_time
+		FROM schedule s
+		LEFT JOIN schedule_groups sg ON sg.schedule_id = s.id
+		WHERE s.start_time < $2 AND s.end_time > $1;
+	`, weekStart, weekEnd)
+	if err != nil {
+		return p, nil, err
+	}
+	for rows.Next() {
+		var teacherID, classroomID, groupID int
+		var start, end time.Time
+		if err := rows.Scan(&teacherID, &classroomID, &groupID, &start, &end); err != nil {
+			rows.Close()
+			return p, nil, err
+		}
+		day := int(dateOnly(start).Sub(weekStart).Hours() / 24)
+		for _, b := range periods {
+			if !start.Before(atClock(start, b.EndClock)) || !end.After(atClock(start, b.StartClock)) {
+				continue
+			}
+			slot := generator.Slot{Day: day, Pair: b.PairNumber}
+			markSlot(p.BusyTeachers, teacherID, slot)
+			markSlot(p.BusyRooms, classroomID, slot)
+			if groupID > 0 {
+				markSlot(p.BusyGroups, groupID, slot)
+			}
+		}
+	}
+	rows.Close()
+
+	windows, err := loadTeacherUnavailability(db, 0)
+	if err != nil {
+		return p, nil, err
+	}
+	for _, u := range windows {
+		for d := 0; d < days; d++ {
+			day := weekStart.AddDate(0, 0, d)
+			for _, b := range periods {
+				if UnavailabilityOverlaps(u, atClock(day, b.StartClock), atClock(day, b.EndClock)) {
+					markSlot(p.BusyTeachers, u.TeacherID, generator.Slot{Day: d, Pair: b.PairNumber})
+				}
+			}
+		}
+	}
+
+	rows, err = db.Query(`SELECT teacher_id, weekday, pair_number, penalty FROM teacher_slot_preferences;`)
+	if err != nil {
+		return p, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var teacherID, weekday, pairNumber, penalty int
+		if err := rows.Scan(&teacherID, &weekday, &pairNumber, &penalty); err != nil {
+			return p, nil, err
+		}
+		if p.TeacherPenalties[teacherID] == nil {
+			p.TeacherPenalties[teacherID] = make(map[generator.Slot]int)
+		}
+		p.TeacherPenalties[teacherID][generator.Slot{Day: weekday - 1, Pair: pairNumber}] = penalty
+	}
+	return p, labels, nil
+}
+
+// GenerateTimetableHandler строит черновик расписания на неделю и сохраняет его
+// вместе с отчётом об оценке. В расписание черновик попадает только после применения.
+func GenerateTimetableHandler(c *gin.Context, db *sql.DB) {
+	weekStart, err := time.Parse(dateLayout, c.PostForm("week_start"))
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверная дата начала недели")
+		return
+	}
+	if weekStart.Weekday() != time.Monday {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неделя должна начинаться с понедельника")
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultPostForm("days", "5"))
+	if err != nil || days < 1 || days > 6 {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Число учебных дней должно быть от 1 до 6")
+		return
+	}
+
+	problem, labels, err := buildTimetableProblem(db, weekStart, days)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Невозможно построить расписание: "+err.Error())
+		return
+	}
+	result := generator.Generate(problem, weekStart.Unix(), generatorIterations)
+
+	unplacedCount := make(map[int]int)
+	var unplacedOrder []int
+	for _, l := range result.Unplaced {
+		if unplacedCount[l.CurriculumID] == 0 {
+			unplacedOrder = append(unplacedOrder, l.CurriculumID)
+		}
+		unplacedCount[l.CurriculumID]++
+	}
+	var details []string
+	for _, id := range unplacedOrder {
+		details = append(details, fmt.Sprintf("%s: %d пар(ы)", labels[id], unplacedCount[id]))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка начала транзакции: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	rep := result.Report
+	var draftID int
+	err = tx.QueryRow(`
+        INSERT INTO timetable_drafts (week_start, placed, unplaced, group_gaps, teacher_preference_penalty,
+                                      building_changes, score, unplaced_details)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
+    `, weekStart, rep.Placed, rep.Unplaced, rep.GroupGaps, rep.TeacherPreferencePenalty,
+		rep.BuildingChanges, rep.Score, strings.Join(details, "; ")).Scan(&draftID)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка сохранения черновика: "+err.Error())
+		return
+	}
+	for _, pl := range result.Placements {
+		_, err := tx.Exec(`
+            INSERT INTO timetable_draft_lessons (draft_id, group_id, subject_id, teacher_id, classroom_id, weekday, pair_number)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+        `, draftID, pl.Lesson.GroupID, pl.Lesson.SubjectID, pl.Lesson.TeacherID, pl.RoomID, pl.Slot.Day+1, pl.Slot.Pair)
+		if err != nil {
+			renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка сохранения занятий черновика: "+err.Error())
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка сохранения черновика: "+err.Error())
+		return
+	}
+
+	alarm := fmt.Sprintf("Черновик построен: размещено %d пар, не размещено %d, оценка %d.", rep.Placed, rep.Unplaced, rep.Score)
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/admin/generator?draft_id=%d&alarm=%s", draftID, url.QueryEscape(alarm)))
+}
+
+// ApplyTimetableDraftHandler переносит черновик в расписание: каждое занятие черновика
+// становится еженедельной серией с недели черновика до end_date (по умолчанию — до конца
+// семестра). Все занятия проверяются CheckScheduleCollision; при любой коллизии ничего не сохраняется.
+func ApplyTimetableDraftHandler(c *gin.Context, db *sql.DB) {
+	draftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверный ID черновика")
+		return
+	}
+
+	var weekStart time.Time
+	var appliedAt sql.NullTime
+	err = db.QueryRow(`SELECT week_start, applied_at FROM timetable_drafts WHERE id = $1`, draftID).Scan(&weekStart, &appliedAt)
+	if err == sql.ErrNoRows {
+		renderAdminGeneratorPage(c, db, http.StatusNotFound, "Черновик не найден")
+		return
+	} else if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if appliedAt.Valid {
+		renderAdminGeneratorPage(c, db, http.StatusConflict, "Черновик уже применён")
+		return
+	}
+
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка загрузки семестров: "+err.Error())
+		return
+	}
+	term := calendar.FindTerm(terms, weekStart)
+	var endDate time.Time
+	if v := c.PostForm("end_date"); v != "" {
+		endDate, err = time.Parse(dateLayout, v)
+		if err != nil || endDate.Before(weekStart) {
+			renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверная дата окончания")
+			return
+		}
+	} else if term != nil {
+		endDate = term.EndDate
+	} else {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Укажите дату окончания: неделя черновика не входит ни в один семестр")
+		return
+	}
+
+	rows, err := db.Query(`
+        SELECT dl.group_id, dl.subject_id, dl.teacher_id, dl.classroom_id, dl.weekday, dl.pair_number,
+               g.name, sub.name
+        FROM timetable_draft_lessons dl
+        JOIN groups g ON dl.group_id = g.id
+        JOIN subjects sub ON dl.subject_id = sub.id
+        WHERE dl.draft_id = $1
+        ORDER BY dl.weekday, dl.pair_number
+    `, draftID)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	type draftLesson struct {
+		series models.LessonSeries
+		label  string
+	}
+	var lessons []draftLesson
+	for rows.Next() {
+		var l draftLesson
+		var weekday, groupID int
+		var groupName, subjectName string
+		if err := rows.Scan(&groupID, &l.series.SubjectID, &l.series.TeacherID, &l.series.ClassroomID,
+			&weekday, &l.series.PairNumber, &groupName, &subjectName); err != nil {
+			rows.Close()
+			renderAdminGeneratorPage(c, db, http.StatusInternalServerError, err.Error())
+			return
+		}
+		l.series.GroupIDs = []int{groupID}
+		l.series.StartDate = weekStart.AddDate(0, 0, weekday-1)
+		l.series.EndDate = endDate
+		l.series.IntervalWeeks = 1
+		l.series.WeekParity = calendar.ParityAny
+		if term != nil {
+			l.series.TermStart = term.StartDate
+		}
+		l.label = groupName + " — " + subjectName
+		lessons = append(lessons, l)
+	}
+	rows.Close()
+
+	periods, err := loadCalendarPeriods(db)
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка загрузки учебного календаря: "+err.Error())
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка начала транзакции: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	created := 0
+	var conflicts []string
+	for _, l := range lessons {
+		l.series.Calendar = periods
+		if err := resolveSeriesTime(tx, &l.series); err != nil {
+			renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Ошибка определения времени занятий: "+err.Error())
+			return
+		}
+		occurrences := ExpandLessonSeries(l.series)
+		if len(occurrences) == 0 {
+			continue
+		}
+		dates, err := createLessonSeries(tx, l.series, occurrences)
+		if err != nil {
+			renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка при создании серии: "+err.Error())
+			return
+		}
+		if len(dates) > 0 {
+			conflicts = append(conflicts, l.label+": "+formatDates(dates))
+			continue
+		}
+		created += len(occurrences)
+	}
+	if len(conflicts) > 0 {
+		renderAdminGeneratorPage(c, db, http.StatusConflict, "Черновик не применён, найдены коллизии: "+strings.Join(conflicts, "; "))
+		return
+	}
+
+	if _, err := tx.Exec(`UPDATE timetable_drafts SET applied_at = NOW() WHERE id = $1`, draftID); err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка применения черновика: "+err.Error())
+		return
+	}
+
+	alarm := fmt.Sprintf("Черновик применён: создано %d занятий.", created)
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/admin/generator?draft_id=%d&alarm=%s", draftID, url.QueryEscape(alarm)))
+}
+
+func DeleteTimetableDraftHandler(c *gin.Context, db *sql.DB) {
+	draftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusBadRequest, "Неверный ID черновика")
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM timetable_drafts WHERE id = $1`, draftID); err != nil {
+		renderAdminGeneratorPage(c, db, http.StatusInternalServerError, "Ошибка удаления черновика: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/generator")
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/ical"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// В ленты попадают и недавно прошедшие занятия, чтобы они не пропадали из календаря
+// сразу после окончания.
+const calendarFeedHistoryDays = 30
+
+// generateCalendarToken возвращает случайный секрет для личной ленты.
+func generateCalendarToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ensureCalendarToken возвращает токен личной ленты пользователя, выдавая его при первом обращении.
+func ensureCalendarToken(db *sql.DB, userID int) (string, error) {
+	token, err := generateCalendarToken()
+	if err != nil {
+		return "", err
+	}
+	err = db.QueryRow(`
+        UPDATE users SET calendar_token = COALESCE(calendar_token, $2)
+        WHERE id = $1
+        RETURNING calendar_token
+    `, userID, token).Scan(&token)
+	return token, err
+}
+
+// absoluteURL строит полный адрес для вставки в приложение календаря.
+func absoluteURL(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + path
+}
+
+// calendarFeedLinks возвращает ссылки на личную ленту пользователя: https-адрес для
+// копирования и webcal-адрес, который сразу открывает подписку в календаре.
+func calendarFeedLinks(c *gin.Context, db *sql.DB, userID int) (gin.H, error) {
+	token, err := ensureCalendarToken(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	feedURL := absoluteURL(c, "/ical/user/"+token+".ics")
+	return gin.H{
+		"URL": feedURL,
+		// html/template вырезает неизвестные схемы ссылок, webcal помечен как доверенный.
+		"Webcal": template.URL("webcal://" + strings.SplitN(feedURL, "://", 2)[1]),
+	}, nil
+}
+
+// ResetCalendarTokenHandler выдаёт новую ссылку на личную ленту; старая перестаёт работать.
+func ResetCalendarTokenHandler(c *gin.Context, db *sql.DB) {
+	userIDVal, _ := c.Get("user_id")
+	roleVal, _ := c.Get("role")
+	userID, _ := userIDVal.(int)
+	role, _ := roleVal.(string)
+
+	back := "/student/schedules"
+	if role == "teacher" {
+		back = "/teacher/schedule"
+	}
+
+	alarm := "Ссылка на календарь обновлена, переподпишитесь по новой ссылке"
+	token, err := generateCalendarToken()
+	if err == nil {
+		_, err = db.Exec(`UPDATE users SET calendar_token = $1 WHERE id = $2`, token, userID)
+	}
+	if err != nil {
+		alarm = "Ошибка обновления ссылки на календарь: " + err.Error()
+	}
+	c.Redirect(http.StatusSeeOther, back+"?alarm="+url.QueryEscape(alarm))
+}
+
+// calendarFeed — выборка ленты: условие для действующих занятий (scheduleViewSelect)
+// и для отменённых (cancelled_lessons cl) с общими аргументами. droppedWhere, если
+// задано, выбирает занятия, с которых сняли группы ленты: для неё они тоже отменены.
+type calendarFeed struct {
+	name           string
+	lessonWhere    string
+	cancelledWhere string
+	droppedWhere   string
+	args           []interface{}
+}
+
+func lessonEventSummary(subject string) string {
+	if subject == "" {
+		return "Занятие"
+	}
+	return subject
+}
+
+func lessonEventLocation(room, building string) string {
+	if room == "" {
+		return building
+	}
+	if building == "" {
+		return "Ауд. " + room
+	}
+	return "Ауд. " + room + ", " + building
+}
+
+func lessonEventDescription(teacher, groups string) string {
+	var lines []string
+	if teacher != "" {
+		lines = append(lines, "Преподаватель: "+teacher)
+	}
+	if groups != "" {
+		lines = append(lines, "Группы: "+groups)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lessonEventUID(scheduleID int) string {
+	return fmt.Sprintf("schedule-%d@scheduleApp", scheduleID)
+}
+
+func scheduleEvent(l models.ScheduleDisplay, cancelled bool) ical.Event {
+	return ical.Event{
+		UID:         lessonEventUID(l.ID),
+		Sequence:    l.Revision,
+		Summary:     lessonEventSummary(l.SubjectName),
+		Location:    lessonEventLocation(l.RoomNumber, l.Building),
+		Description: lessonEventDescription(l.TeacherName, l.GroupNames),
+		Start:       l.StartTime,
+		End:         l.EndTime,
+		Cancelled:   cancelled,
+	}
+}
+
+// loadCalendarFeed собирает события ленты: действующие занятия и отмены за тот же период.
+func loadCalendarFeed(db *sql.DB, feed calendarFeed) (ical.Calendar, error) {
+	cal := ical.Calendar{Name: feed.name}
+	since := fmt.Sprintf("NOW() - INTERVAL '%d days'", calendarFeedHistoryDays)
+
+	lessons, err := loadScheduleView(db, feed.lessonWhere+" AND s.start_time >= "+since, feed.args...)
+	if err != nil {
+		return cal, err
+	}
+	for _, l := range lessons {
+		cal.Events = append(cal.Events, scheduleEvent(l, false))
+	}
+	if feed.droppedWhere != "" {
+		dropped, err := loadScheduleView(db, feed.droppedWhere+" AND s.start_time >= "+since, feed.args...)
+		if err != nil {
+			return cal, err
+		}
+		for _, l := range dropped {
+			cal.Events = append(cal.Events, scheduleEvent(l, true))
+		}
+	}
+
+	rows, err := db.Query(`
+        SELECT cl.schedule_id, cl.subject_name, cl.teacher_name, cl.room_number, cl.building,
+               cl.group_names, cl.start_time, cl.end_time, cl.revision
+        FROM cancelled_lessons cl
+        `+feed.cancelledWhere+` AND cl.start_time >= `+since+`
+        ORDER BY cl.start_time
+    `, feed.args...)
+	if err != nil {
+		return cal, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			scheduleID, revision                     int
+			subject, teacher, room, building, groups string
+			start, end                               time.Time
+		)
+		if err := rows.Scan(&scheduleID, &subject, &teacher, &room, &building, &groups, &start, &end, &revision); err != nil {
+			return cal, err
+		}
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         lessonEventUID(scheduleID),
+			Sequence:    revision,
+			Summary:     lessonEventSummary(subject),
+			Location:    lessonEventLocation(room, building),
+			Description: lessonEventDescription(teacher, groups),
+			Start:       start,
+			End:         end,
+			Cancelled:   true,
+		})
+	}
+	return cal, rows.Err()
+}
+
+func writeCalendarFeed(c *gin.Context, db *sql.DB, feed calendarFeed, filename string) {
+	cal, err := loadCalendarFeed(db, feed)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Ошибка формирования календаря: %v", err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		c.String(http.StatusInternalServerError, "Ошибка формирования календаря: %v", err)
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// feedID читает числовой идентификатор из параметра вида "12.ics".
+func feedID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(strings.TrimSuffix(c.Param("file"), ".ics"))
+	return id, err == nil && id > 0
+}
+
+// UserCalendarFeedHandler отдаёт личную ленту по секретному токену: студенту —
+// занятия его группы, преподавателю — его занятия.
+func UserCalendarFeedHandler(c *gin.Context, db *sql.DB) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+	if token == "" {
+		c.String(http.StatusNotFound, "Календарь не найден")
+		return
+	}
+	var user models.User
+	err := db.QueryRow(`SELECT id, username, role FROM users WHERE calendar_token = $1`, token).
+		Scan(&user.ID, &user.Username, &user.Role)
+	if err == sql.ErrNoRows {
+		c.String(http.StatusNotFound, "Календарь не найден")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Ошибка загрузки календаря: %v", err)
+		return
+	}
+
+	switch user.Role {
+	case "student":
+		writeCalendarFeed(c, db, calendarFeed{
+			name: "Расписание — " + user.Username,
+			lessonWhere: `WHERE EXISTS (
+			SELECT 1
+			FROM students st
+			JOIN schedule_groups sg ON sg.group_id = st.group_id
+			WHERE st.user_id = $1 AND sg.schedule_id = s.id
+		)`,
+			cancelledWhere: `WHERE cl.group_ids && ARRAY(SELECT group_id FROM students WHERE user_id = $1 AND group_id IS NOT NULL)`,
+			droppedWhere: `WHERE EXISTS (
+			SELECT 1
+			FROM students st
+			JOIN dropped_lesson_groups dg ON dg.group_id = st.group_id
+			WHERE st.user_id = $1 AND dg.schedule_id = s.id
+		)`,
+			args: []interface{}{user.ID},
+		}, "schedule.ics")
+	case "teacher":
+		var teacherID int
+		var teacherName string
+		err := db.QueryRow(`SELECT id, name FROM teachers WHERE user_id = $1`, user.ID).Scan(&teacherID, &teacherName)
+		if err != nil {
+			c.String(http.StatusNotFound, "Календарь не найден")
+			return
+		}
+		writeCalendarFeed(c, db, calendarFeed{
+			name:           "Расписание — " + teacherName,
+			lessonWhere:    `WHERE s.teacher_id = $1`,
+			cancelledWhere: `WHERE cl.teacher_id = $1`,
+			args:           []interface{}{teacherID},
+		}, "schedule.ics")
+	default:
+		c.String(http.StatusNotFound, "Календарь не найден")
+	}
+}
+
+// GroupCalendarFeedHandler отдаёт общедоступную ленту группы.
+func GroupCalendarFeedHandler(c *gin.Context, db *sql.DB) {
+	groupID, ok := feedID(c)
+	if !ok {
+		c.String(http.StatusNotFound, "Календарь не найден")
+		return
+	}
+	var name string
+	if err := db.QueryRow(`SELECT name FROM groups WHERE id = $1`, groupID).Scan(&name); err != nil {
+		c.String(http.StatusNotFound, "Календарь не найден")
+		return
+	}
+	writeCalendarFeed(c, db, calendarFeed{
+		name:           "Группа " + name,
+		lessonWhere:    `WHERE EXISTS (SELECT 1 FROM schedule_groups sg2 WHERE sg2.schedule_id = s.id AND sg2.group_id = $1)`,
+		cancelledWhere: `WHERE $1 = ANY(cl.group_ids)`,
+		droppedWhere:   `WHERE EXISTS (SELECT 1 FROM dropped_lesson_groups dg WHERE dg.schedule_id = s.id AND dg.group_id = $1)`,
+		args:           []interface{}{groupID},
+	}, fmt.Sprintf("group-%d.ics", groupID))
+}
+
+// ClassroomCalendarFeedHandler отдаёт общедоступную ленту занятости аудитории.
+func ClassroomCalendarFeedHandler(c *gin.Context, db *sql.DB) {
+	classroomID, ok := feedID(c)
+	if !ok {
+		c.String(http.StatusNotFound, "Календарь не найден")
+		return
+	}
+	var room string
+	if err := db.QueryRow(`SELECT room_number FROM classrooms WHERE id = $1`, classroomID).Scan(&room); err != nil {
+		c.String(http.StatusNotFound, "Календарь не найден")
+		return
+	}
+	writeCalendarFeed(c, db, calendarFeed{
+		name:           "Аудитория " + room,
+		lessonWhere:    `WHERE s.classroom_id = $1`,
+		cancelledWhere: `WHERE cl.classroom_id = $1`,
+		args:           []interface{}{classroomID},
+	}, fmt.Sprintf("classroom-%d.ics", classroomID))
+}
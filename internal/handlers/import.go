@@ -0,0 +1,678 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportRow — строка файла импорта расписания как она есть в файле; справочники
+// сопоставляются по названиям при проверке.
+type ImportRow struct {
+	Line     int    `json:"line"`
+	Subject  string `json:"subject"`
+	Teacher  string `json:"teacher"`
+	Room     string `json:"room"`
+	Building string `json:"building,omitempty"`
+	Groups   string `json:"groups"`
+	Date     string `json:"date,omitempty"`
+	Weekday  string `json:"weekday,omitempty"`
+	Time     string `json:"time,omitempty"`
+	EndTime  string `json:"end_time,omitempty"`
+	Pair     string `json:"pair,omitempty"`
+}
+
+// ImportRowResult — итог проверки одной строки: сколько занятий она даёт и что мешает импорту.
+type ImportRowResult struct {
+	Row     ImportRow
+	Lessons int
+	First   time.Time
+	Errors  []string
+}
+
+// ImportReport — предпросмотр или результат импорта.
+type ImportReport struct {
+	Rows        []ImportRowResult
+	LessonCount int
+	ErrorCount  int
+	Committed   bool
+}
+
+// ImportPeriod ограничивает строки с днём недели: такие строки разворачиваются
+// в еженедельные занятия с From по To включительно.
+type ImportPeriod struct {
+	From time.Time
+	To   time.Time
+}
+
+// importColumns сопоставляет заголовки столбцов (в нижнем регистре) с полями строки.
+var importColumns = map[string]string{
+	"subject": "subject", "предмет": "subject", "дисциплина": "subject",
+	"teacher": "teacher", "преподаватель": "teacher",
+	"room": "room", "classroom": "room", "аудитория": "room",
+	"building": "building", "корпус": "building",
+	"groups": "groups", "group": "groups", "группы": "groups", "группа": "groups",
+	"date": "date", "дата": "date",
+	"weekday": "weekday", "day": "weekday", "день": "weekday", "день недели": "weekday",
+	"time": "time", "start": "time", "start_time": "time", "время": "time", "начало": "time",
+	"end": "end_time", "end_time": "end_time", "конец": "end_time", "окончание": "end_time",
+	"pair": "pair", "pair_number": "pair", "пара": "pair", "номер пары": "pair",
+}
+
+// ParseTimetableFile читает CSV (разделитель «,» или «;») или XLSX (первый лист).
+// Первая строка — заголовки; пустые строки пропускаются.
+func ParseTimetableFile(filename string, r io.Reader) ([]ImportRow, error) {
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		text := strings.TrimPrefix(string(data), "\ufeff")
+		firstLine := text
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			firstLine = text[:i]
+		}
+		cr := csv.NewReader(strings.NewReader(text))
+		if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+			cr.Comma = ';'
+		}
+		cr.FieldsPerRecord = -1
+		records, err = cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CSV: %v", err)
+		}
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения XLSX: %v", err)
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("в файле XLSX нет листов")
+		}
+		records, err = f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения XLSX: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("поддерживаются только файлы .csv и .xlsx")
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("файл пуст")
+	}
+	fields := make([]string, len(records[0]))
+	known := make(map[string]bool)
+	for i, h := range records[0] {
+		fields[i] = importColumns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))]
+		known[fields[i]] = true
+	}
+	for _, required := range []string{"subject", "teacher", "room", "groups"} {
+		if !known[required] {
+			return nil, fmt.Errorf("в заголовке нет обязательного столбца %q", required)
+		}
+	}
+	if !known["date"] && !known["weekday"] {
+		return nil, fmt.Errorf("в заголовке нет столбца date или weekday")
+	}
+	if !known["time"] && !known["pair"] {
+		return nil, fmt.Errorf("в заголовке нет столбца time или pair")
+	}
+
+	var rows []ImportRow
+	for n, rec := range records[1:] {
+		row := ImportRow{Line: n + 2}
+		empty := true
+		for i, v := range rec {
+			if i >= len(fields) {
+				break
+			}
+			v = strings.TrimSpace(v)
+			if v != "" {
+				empty = false
+			}
+			switch fields[i] {
+			case "subject":
+				row.Subject = v
+			case "teacher":
+				row.Teacher = v
+			case "room":
+				row.Room = v
+			case "building":
+				row.Building = v
+			case "groups":
+				row.Groups = v
+			case "date":
+				row.Date = v
+			case "weekday":
+				row.Weekday = v
+			case "time":
+				row.Time = v
+			case "end_time":
+				row.EndTime = v
+			case "pair":
+				row.Pair = v
+			}
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("в файле нет строк с занятиями")
+	}
+	return rows, nil
+}
+
+// parseImportDate понимает 2006-01-02, 02.01.2006 и порядковые даты Excel.
+func parseImportDate(s string) (time.Time, error) {
+	for _, layout := range []string{dateLayout, "02.01.2006", "2.1.2006"} {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(s, 64); err == nil && serial > 0 {
+		d, err := excelize.ExcelDateToTime(serial, false)
+		if err == nil {
+			return dateOnly(d), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неверная дата %q", s)
+}
+
+// parseImportClock понимает 9:00, 09:00 и доли суток из ячеек времени Excel.
+func parseImportClock(s string) (time.Duration, error) {
+	if t, err := time.Parse("15:04", s); err == nil {
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+	if t, err := time.Parse("15:04:05", s); err == nil {
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 0 && f < 1 {
+		return (time.Duration(f*24*60+0.5) * time.Minute), nil
+	}
+	return 0, fmt.Errorf("неверное время %q", s)
+}
+
+var importWeekdays = map[string]time.Weekday{
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+	"понедельник": time.Monday, "вторник": time.Tuesday, "среда": time.Wednesday, "четверг": time.Thursday,
+	"пятница": time.Friday, "суббота": time.Saturday, "воскресенье": time.Sunday,
+}
+
+// parseImportWeekday понимает номер дня (1 — понедельник), полное название и сокращения.
+func parseImportWeekday(s string) (time.Weekday, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(v); err == nil && n >= 1 && n <= 7 {
+		return time.Weekday(n % 7), nil
+	}
+	if d, ok := importWeekdays[v]; ok {
+		return d, nil
+	}
+	if d, ok := importWeekdays[strings.TrimSuffix(v, ".")]; ok {
+		return d, nil
+	}
+	return 0, fmt.Errorf("неверный день недели %q", s)
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func importKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// importRefs — справочники для сопоставления названий с ID.
+type importRefs struct {
+	subjects   map[string]int
+	teachers   map[string]int
+	groups     map[string]int
+	classrooms map[string][]importClassroom
+}
+
+type importClassroom struct {
+	id       int
+	building string
+}
+
+func loadImportRefs(db DBQuerier) (importRefs, error) {
+	refs := importRefs{
+		subjects:   make(map[string]int),
+		teachers:   make(map[string]int),
+		groups:     make(map[string]int),
+		classrooms: make(map[string][]importClassroom),
+	}
+	for _, ref := range []struct {
+		table  string
+		target map[string]int
+	}{
+		{"subjects", refs.subjects},
+		{"teachers", refs.teachers},
+		{"groups", refs.groups},
+	} {
+		rows, err := db.Query(`SELECT id, name FROM ` + ref.table)
+		if err != nil {
+			return refs, err
+		}
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				rows.Close()
+				return refs, err
+			}
+			ref.target[importKey(name)] = id
+		}
+		rows.Close()
+	}
+	rows, err := db.Query(`SELECT id, room_number, COALESCE(building, '') FROM classrooms`)
+	if err != nil {
+		return refs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c importClassroom
+		var room string
+		if err := rows.Scan(&c.id, &room, &c.building); err != nil {
+			return refs, err
+		}
+		refs.classrooms[importKey(room)] = append(refs.classrooms[importKey(room)], c)
+	}
+	return refs, rows.Err()
+}
+
+func (refs importRefs) classroom(room, building string) (int, error) {
+	candidates := refs.classrooms[importKey(room)]
+	var matched []importClassroom
+	for _, c := range candidates {
+		if building == "" || importKey(c.building) == importKey(building) {
+			matched = append(matched, c)
+		}
+	}
+	switch {
+	case len(matched) == 1:
+		return matched[0].id, nil
+	case len(matched) == 0:
+		return 0, fmt.Errorf("аудитория %q не найдена", strings.TrimSpace(room+" "+building))
+	default:
+		return 0, fmt.Errorf("аудитория %q есть в нескольких корпусах, укажите корпус", room)
+	}
+}
+
+// importLesson — занятие, полученное из строки файла после сопоставления справочников.
+type importLesson struct {
+	subjectID, teacherID, classroomID, pairNumber int
+	groupIDs                                      []int
+	start, end                                    time.Time
+}
+
+// resolveImportRow сопоставляет названия и вычисляет время всех занятий строки.
+// Возвращает все найденные ошибки строки сразу, чтобы их можно было исправить за один проход.
+func resolveImportRow(db DBQuerier, refs importRefs, periods []models.CalendarPeriod, period ImportPeriod, row ImportRow) ([]importLesson, []string) {
+	var errs []string
+	var base importLesson
+	var ok bool
+
+	if base.subjectID, ok = refs.subjects[importKey(row.Subject)]; !ok {
+		errs = append(errs, fmt.Sprintf("предмет %q не найден", row.Subject))
+	}
+	if base.teacherID, ok = refs.teachers[importKey(row.Teacher)]; !ok {
+		errs = append(errs, fmt.Sprintf("преподаватель %q не найден", row.Teacher))
+	}
+	classroomID, err := refs.classroom(row.Room, row.Building)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	base.classroomID = classroomID
+	groupNames := 0
+	for _, name := range strings.FieldsFunc(row.Groups, func(r rune) bool { return r == ',' || r == ';' }) {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		groupNames++
+		id, ok := refs.groups[importKey(name)]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("группа %q не найдена", strings.TrimSpace(name)))
+			continue
+		}
+		if !containsInt(base.groupIDs, id) {
+			base.groupIDs = append(base.groupIDs, id)
+		}
+	}
+	if groupNames == 0 {
+		errs = append(errs, "не указаны группы")
+	}
+
+	var clock, endClock time.Duration
+	if row.Pair != "" {
+		if base.pairNumber, err = strconv.Atoi(row.Pair); err != nil || base.pairNumber < 1 {
+			errs = append(errs, fmt.Sprintf("неверный номер пары %q", row.Pair))
+		}
+	} else if row.Time != "" {
+		if clock, err = parseImportClock(row.Time); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if row.EndTime != "" {
+			if endClock, err = parseImportClock(row.EndTime); err != nil {
+				errs = append(errs, err.Error())
+			} else if endClock <= clock {
+				errs = append(errs, "время окончания раньше начала")
+			}
+		}
+	} else {
+		errs = append(errs, "не указаны ни пара, ни время начала")
+	}
+
+	var days []time.Time
+	switch {
+	case row.Date != "":
+		day, err := parseImportDate(row.Date)
+		if err != nil {
+			errs = append(errs, err.Error())
+			break
+		}
+		if p := calendar.NonTeachingPeriod(periods, day); p != nil {
+			errs = append(errs, nonTeachingDayMessage(p, day))
+			break
+		}
+		days = []time.Time{day}
+	case row.Weekday != "":
+		weekday, err := parseImportWeekday(row.Weekday)
+		if err != nil {
+			errs = append(errs, err.Error())
+			break
+		}
+		if period.From.IsZero() || period.To.IsZero() {
+			errs = append(errs, "для строк с днём недели укажите период импорта")
+			break
+		}
+		first := dateOnly(period.From)
+		for first.Weekday() != weekday {
+			first = first.AddDate(0, 0, 1)
+		}
+		days = ExpandLessonSeries(models.LessonSeries{
+			StartDate:     first,
+			EndDate:       period.To,
+			StartClock:    "00:00",
+			IntervalWeeks: 1,
+			Calendar:      periods,
+		})
+		if len(days) == 0 {
+			errs = append(errs, "в периоде импорта нет учебных дней с этим днём недели")
+		}
+	default:
+		errs = append(errs, "не указаны ни дата, ни день недели")
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	lessons := make([]importLesson, 0, len(days))
+	for _, day := range days {
+		l := base
+		if l.pairNumber > 0 {
+			l.start, l.end, err = ResolveLessonTime(db, l.classroomID, day, l.pairNumber, time.Time{}, 0)
+			if err != nil {
+				return nil, []string{err.Error()}
+			}
+		} else {
+			l.start = dateOnly(day).Add(clock)
+			if endClock > 0 {
+				l.end = dateOnly(day).Add(endClock)
+			} else {
+				l.end = l.start.Add(defaultLessonDuration)
+			}
+		}
+		lessons = append(lessons, l)
+	}
+	return lessons, nil
+}
+
+// ImportTimetable проверяет строки и вставляет занятия в одной транзакции.
+// Каждая строка проверяется на коллизии с существующим расписанием и с уже
+// разобранными строками файла. Без commit, а также при любой ошибке транзакция
+// откатывается, и отчёт служит предпросмотром.
+func ImportTimetable(db *sql.DB, rows []ImportRow, period ImportPeriod, commit bool) (ImportReport, error) {
+	var report ImportReport
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	refs, err := loadImportRefs(tx)
+	if err != nil {
+		return report, err
+	}
+	periods, err := loadCalendarPeriods(tx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, row := range rows {
+		result := ImportRowResult{Row: row}
+		lessons, errs := resolveImportRow(tx, refs, periods, period, row)
+		result.Errors = errs
+		if len(errs) == 0 {
+			if _, err := tx.Exec(`SAVEPOINT import_row`); err != nil {
+				return report, err
+			}
+			result.Errors, err = insertImportLessons(tx, lessons)
+			if err != nil {
+				return report, err
+			}
+			if len(result.Errors) > 0 {
+				if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT import_row`); err != nil {
+					return report, err
+				}
+			} else {
+				result.Lessons = len(lessons)
+				result.First = lessons[0].start
+			}
+		}
+		if len(result.Errors) > 0 {
+			report.ErrorCount++
+		}
+		report.LessonCount += result.Lessons
+		report.Rows = append(report.Rows, result)
+	}
+
+	if commit && report.ErrorCount == 0 {
+		if err := tx.Commit(); err != nil {
+			return report, err
+		}
+		report.Committed = true
+	}
+	return report, nil
+}
+
+// insertImportLessons вставляет занятия одной строки. Ошибки проверки возвращаются
+// списком сообщений, ошибки БД — через error. Превышение вместимости аудитории при
+// импорте не разрешается: такую строку нужно исправить в файле.
+func insertImportLessons(tx *sql.Tx, lessons []importLesson) ([]string, error) {
+	if len(lessons) == 0 {
+		return nil, nil
+	}
+	// Аудитория и группы у всех занятий строки одни и те же.
+	students, capacity, err := CheckClassroomCapacity(tx, lessons[0].classroomID, lessons[0].groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	if capacity > 0 && students > capacity {
+		return []string{fmt.Sprintf("аудитория рассчитана на %d мест, а в группах занятия %d студентов", capacity, students)}, nil
+	}
+
+	var errs []string
+	for _, l := range lessons {
+		collision, err := CheckScheduleCollisionForGroups(tx, l.teacherID, l.classroomID, l.groupIDs, l.start, l.end, 0)
+		if err != nil {
+			return nil, err
+		}
+		if collision {
+			errs = append(errs, fmt.Sprintf("коллизия %s: у преподавателя, в аудитории или у группы уже есть занятие",
+				l.start.Format("02.01.2006 15:04")))
+			continue
+		}
+		var scheduleID int
+		err = tx.QueryRow(`
+            INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time, pair_number)
+            VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+        `, l.subjectID, l.teacherID, l.classroomID, l.start, l.end, nullableInt(l.pairNumber)).Scan(&scheduleID)
+		if err != nil {
+			return nil, err
+		}
+		if err := setScheduleGroups(tx, scheduleID, l.groupIDs); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// defaultImportPeriod предлагает текущий или ближайший семестр.
+func defaultImportPeriod(terms []models.AcademicTerm, now time.Time) ImportPeriod {
+	today := dateOnly(now)
+	for _, t := range terms {
+		if !today.After(t.EndDate) {
+			return ImportPeriod{From: t.StartDate, To: t.EndDate}
+		}
+	}
+	return ImportPeriod{}
+}
+
+func parseImportPeriod(c *gin.Context) (ImportPeriod, error) {
+	var p ImportPeriod
+	var err error
+	if v := c.PostForm("date_from"); v != "" {
+		if p.From, err = time.Parse(dateLayout, v); err != nil {
+			return p, fmt.Errorf("Неверная дата начала периода")
+		}
+	}
+	if v := c.PostForm("date_to"); v != "" {
+		if p.To, err = time.Parse(dateLayout, v); err != nil {
+			return p, fmt.Errorf("Неверная дата окончания периода")
+		}
+	}
+	if !p.From.IsZero() && !p.To.IsZero() && p.To.Before(p.From) {
+		return p, fmt.Errorf("Дата окончания периода раньше даты начала")
+	}
+	return p, nil
+}
+
+func renderAdminImportPage(c *gin.Context, db *sql.DB, status int, errMsg string, period ImportPeriod, report *ImportReport, rows []ImportRow) {
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "import_admin", gin.H{
+			"Title": "Импорт расписания",
+			"Error": "Ошибка загрузки семестров: " + err.Error(),
+		})
+		return
+	}
+	if period.From.IsZero() && period.To.IsZero() {
+		period = defaultImportPeriod(terms, time.Now())
+	}
+	var rowsJSON string
+	if rows != nil {
+		data, err := json.Marshal(rows)
+		if err != nil {
+			errMsg = err.Error()
+		}
+		rowsJSON = string(data)
+	}
+	c.HTML(status, "import_admin", gin.H{
+		"Title":    "Импорт расписания",
+		"Period":   period,
+		"Report":   report,
+		"RowsJSON": rowsJSON,
+		"Error":    errMsg,
+		"Alarm":    c.Query("alarm"),
+	})
+}
+
+func RenderAdminImportPage(c *gin.Context, db *sql.DB) {
+	renderAdminImportPage(c, db, http.StatusOK, "", ImportPeriod{}, nil, nil)
+}
+
+// ImportPreviewHandler разбирает загруженный файл и показывает, что будет импортировано,
+// ничего не сохраняя.
+func ImportPreviewHandler(c *gin.Context, db *sql.DB) {
+	period, err := parseImportPeriod(c)
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusBadRequest, err.Error(), period, nil, nil)
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusBadRequest, "Выберите файл CSV или XLSX", period, nil, nil)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusBadRequest, "Не удалось открыть файл: "+err.Error(), period, nil, nil)
+		return
+	}
+	defer file.Close()
+
+	rows, err := ParseTimetableFile(fileHeader.Filename, file)
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusBadRequest, err.Error(), period, nil, nil)
+		return
+	}
+	report, err := ImportTimetable(db, rows, period, false)
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusInternalServerError, "Ошибка проверки файла: "+err.Error(), period, nil, nil)
+		return
+	}
+	renderAdminImportPage(c, db, http.StatusOK, "", period, &report, rows)
+}
+
+// ImportCommitHandler повторно проверяет строки из предпросмотра и сохраняет их одной
+// транзакцией. Если за это время появились ошибки, ничего не сохраняется.
+func ImportCommitHandler(c *gin.Context, db *sql.DB) {
+	period, err := parseImportPeriod(c)
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusBadRequest, err.Error(), period, nil, nil)
+		return
+	}
+	var rows []ImportRow
+	if err := json.Unmarshal([]byte(c.PostForm("rows")), &rows); err != nil || len(rows) == 0 {
+		renderAdminImportPage(c, db, http.StatusBadRequest, "Нет данных для импорта, загрузите файл заново", period, nil, nil)
+		return
+	}
+	report, err := ImportTimetable(db, rows, period, true)
+	if err != nil {
+		renderAdminImportPage(c, db, http.StatusInternalServerError, "Ошибка импорта: "+err.Error(), period, nil, nil)
+		return
+	}
+	if !report.Committed {
+		renderAdminImportPage(c, db, http.StatusConflict, "Импорт отменён: в файле есть ошибки", period, &report, rows)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/import?alarm="+url.QueryEscape(fmt.Sprintf("Импортировано занятий: %d", report.LessonCount)))
+}
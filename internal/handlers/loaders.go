@@ -3,11 +3,30 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"time"
+
+	"scheduleApp/internal/calendar"
 	"scheduleApp/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DBQuerier — общее подмножество методов *sql.DB и *sql.Tx, чтобы проверки
+// и загрузчики можно было выполнять как отдельно, так и внутри транзакции.
+type DBQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// nullableInt сохраняет нулевое значение как NULL.
+func nullableInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
 func joinClauses(clauses []string, sep string) string {
 	if len(clauses) == 0 {
 		return ""
@@ -74,7 +93,7 @@ func loadAllTeachers(db *sql.DB) ([]models.TeacherDisplay, error) {
 }
 
 func loadAllClassrooms(db *sql.DB) ([]models.ClassroomDisplay, error) {
-	rows, err := db.Query(`SELECT id, room_number FROM classrooms ORDER BY room_number;`)
+	rows, err := db.Query(`SELECT id, room_number, COALESCE(capacity, 0) FROM classrooms ORDER BY room_number;`)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +102,7 @@ func loadAllClassrooms(db *sql.DB) ([]models.ClassroomDisplay, error) {
 	var result []models.ClassroomDisplay
 	for rows.Next() {
 		var c models.ClassroomDisplay
-		if err := rows.Scan(&c.ID, &c.RoomNumber); err != nil {
+		if err := rows.Scan(&c.ID, &c.RoomNumber, &c.Capacity); err != nil {
 			return nil, err
 		}
 		result = append(result, c)
@@ -109,16 +128,11 @@ func loadAllDepartments(db *sql.DB) ([]models.DepartmentDisplay, error) {
 	return departments, nil
 }
 
-func RenderUserRequestsPage(c *gin.Context, db *sql.DB) {
+func renderUserRequestsPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
 	userIDVal, _ := c.Get("user_id")
 	userID, _ := userIDVal.(int)
 
-	rows, err := db.Query(`
-        SELECT id, user_id, schedule_id, desired_change, status
-        FROM requests
-        WHERE user_id=$1
-        ORDER BY id
-    `, userID)
+	requests, err := loadRequests(db, "r.user_id = $1", "r.id", userID)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "requests_user", gin.H{
 			"Title": "Мои запросы",
@@ -126,27 +140,49 @@ func RenderUserRequestsPage(c *gin.Context, db *sql.DB) {
 		})
 		return
 	}
-	defer rows.Close()
+	allTeachers, _ := loadAllTeachers(db)
+	allClassrooms, _ := loadAllClassrooms(db)
 
-	var requests []models.Request
-	for rows.Next() {
-		var r models.Request
-		if err := rows.Scan(&r.ID, &r.UserID, &r.ScheduleID, &r.DesiredChange, &r.Status); err != nil {
-			c.HTML(http.StatusInternalServerError, "requests_user", gin.H{
-				"Title": "Мои запросы",
-				"Error": err.Error(),
-			})
-			return
-		}
-		requests = append(requests, r)
-	}
-
-	c.HTML(http.StatusOK, "requests_user", gin.H{
-		"Title":    "Мои запросы",
-		"Requests": requests,
+	c.HTML(status, "requests_user", gin.H{
+		"Title":         "Мои запросы",
+		"Requests":      requests,
+		"KindNames":     requestKindNames,
+		"EventNames":    requestEventNames,
+		"AllTeachers":   allTeachers,
+		"AllClassrooms": allClassrooms,
+		"Error":         errMsg,
+		"Alarm":         c.Query("alarm"),
 	})
 }
 
+func RenderUserRequestsPage(c *gin.Context, db *sql.DB) {
+	renderUserRequestsPage(c, db, http.StatusOK, "")
+}
+
+// CreateUserRequestFormHandler создаёт запрос студента из формы на странице «Мои запросы».
+func CreateUserRequestFormHandler(c *gin.Context, db *sql.DB) {
+	userIDVal, _ := c.Get("user_id")
+	userID, _ := userIDVal.(int)
+
+	r, err := parseRequestForm(c)
+	if err == nil {
+		_, err = createChangeRequest(db, userID, r)
+	}
+	if err == errNotOwnLesson {
+		renderUserRequestsPage(c, db, http.StatusForbidden, err.Error())
+		return
+	}
+	if isProposalError(err) {
+		renderUserRequestsPage(c, db, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		renderUserRequestsPage(c, db, http.StatusInternalServerError, "Ошибка при создании запроса: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/student/requests?alarm=Запрос+отправлен")
+}
+
 func RenderLoginPage(c *gin.Context) {
 	alarm := c.Query("alarm")
 	c.HTML(http.StatusOK, "login", gin.H{
@@ -154,3 +190,108 @@ func RenderLoginPage(c *gin.Context) {
 		"Alarm": alarm,
 	})
 }
+
+func loadAllLessonSeries(db *sql.DB) ([]models.LessonSeriesDisplay, error) {
+	rows, err := db.Query(`
+		SELECT
+			ls.id,
+			sub.name,
+			t.name,
+			c.room_number,
+			COALESCE((SELECT string_agg(g.name, ', ') FROM lesson_series_groups lsg
+				JOIN groups g ON g.id = lsg.group_id WHERE lsg.series_id = ls.id), '') AS group_names,
+			ls.start_date,
+			ls.end_date,
+			to_char(ls.start_clock, 'HH24:MI'),
+			ls.interval_weeks,
+			ls.week_parity,
+			COALESCE(array_to_string(ls.week_numbers, ','), ''),
+			(SELECT COUNT(*) FROM schedule s WHERE s.series_id = ls.id) AS occurrences
+		FROM lesson_series ls
+		JOIN subjects sub ON ls.subject_id = sub.id
+		JOIN teachers t ON ls.teacher_id = t.id
+		JOIN classrooms c ON ls.classroom_id = c.id
+		ORDER BY ls.start_date, ls.start_clock;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.LessonSeriesDisplay
+	for rows.Next() {
+		var s models.LessonSeriesDisplay
+		if err := rows.Scan(&s.ID, &s.SubjectName, &s.TeacherName, &s.RoomNumber, &s.GroupNames,
+			&s.StartDate, &s.EndDate, &s.StartClock, &s.IntervalWeeks, &s.WeekParity, &s.WeekNumbers, &s.Occurrences); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func loadAcademicTerms(db DBQuerier) ([]models.AcademicTerm, error) {
+	rows, err := db.Query(`SELECT id, name, start_date, end_date FROM academic_terms ORDER BY start_date;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.AcademicTerm
+	for rows.Next() {
+		var t models.AcademicTerm
+		if err := rows.Scan(&t.ID, &t.Name, &t.StartDate, &t.EndDate); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func loadBellSchedule(db DBQuerier) ([]models.BellPeriod, error) {
+	rows, err := db.Query(`
+		SELECT id, pair_number, building, to_char(start_clock, 'HH24:MI'), to_char(end_clock, 'HH24:MI')
+		FROM bell_schedule
+		ORDER BY building, pair_number;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.BellPeriod
+	for rows.Next() {
+		var b models.BellPeriod
+		if err := rows.Scan(&b.ID, &b.PairNumber, &b.Building, &b.StartClock, &b.EndClock); err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+func loadCalendarPeriods(db DBQuerier) ([]models.CalendarPeriod, error) {
+	rows, err := db.Query(`
+		SELECT id, kind, name, start_date, end_date, COALESCE(transfer_from, 'epoch')
+		FROM academic_calendar
+		ORDER BY start_date, kind;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.CalendarPeriod
+	for rows.Next() {
+		var p models.CalendarPeriod
+		var transferFrom time.Time
+		if err := rows.Scan(&p.ID, &p.Kind, &p.Name, &p.StartDate, &p.EndDate, &transferFrom); err != nil {
+			return nil, err
+		}
+		if p.Kind == calendar.KindWorkday {
+			p.TransferFrom = transferFrom
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
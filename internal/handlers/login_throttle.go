@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errInvalidCredentials — единый ответ на неверное имя пользователя и неверный пароль,
+// чтобы по нему нельзя было узнать, существует ли учётная запись.
+var errInvalidCredentials = errors.New("Неверный логин или пароль")
+
+// loginLockedError — вход временно запрещён после серии неудачных попыток.
+type loginLockedError struct {
+	wait time.Duration
+}
+
+func (e *loginLockedError) Error() string {
+	return "Слишком много неудачных попыток входа. Повторите через " + formatWait(e.wait)
+}
+
+func formatWait(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d с", int(math.Ceil(d.Seconds())))
+	}
+	return fmt.Sprintf("%d мин", int(math.Ceil(d.Minutes())))
+}
+
+// throttlePolicy задаёт, как растёт задержка между попытками входа с одним ключом.
+type throttlePolicy struct {
+	scope        string
+	freeAttempts int           // неудачных попыток без задержки
+	baseDelay    time.Duration // задержка после первой лишней попытки; дальше удваивается
+	lockAfter    int           // после стольких неудач вход блокируется на lockout
+	lockout      time.Duration
+}
+
+// delay возвращает, сколько ждать после failures неудачных попыток подряд.
+func (p throttlePolicy) delay(failures int) time.Duration {
+	if failures >= p.lockAfter {
+		return p.lockout
+	}
+	extra := failures - p.freeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	if extra > 20 {
+		return p.lockout
+	}
+	if d := p.baseDelay << (extra - 1); d < p.lockout {
+		return d
+	}
+	return p.lockout
+}
+
+var (
+	// accountThrottle ограничивает подбор пароля к одному имени пользователя.
+	accountThrottle = throttlePolicy{scope: "account", freeAttempts: 3, baseDelay: time.Second, lockAfter: 10, lockout: 15 * time.Minute}
+	// ipThrottle ограничивает перебор разных имён с одного адреса.
+	ipThrottle = throttlePolicy{scope: "ip", freeAttempts: 20, baseDelay: time.Second, lockAfter: 100, lockout: 30 * time.Minute}
+	// failureWindow — через сколько после последней неудачи счётчик начинается заново.
+	failureWindow = time.Hour
+)
+
+// dummyPasswordHash сравнивается с паролем, когда пользователь не найден, чтобы время
+// ответа не выдавало существование учётной записи.
+const dummyPasswordHash = "$2a$10$wfPru./pUqHQbU34LTXwp.5y7C8S4SKic0eWQpSy0QY2b/Q07Pq4S"
+
+// loginWait возвращает, сколько ещё запрещён вход для имени пользователя или IP-адреса.
+func loginWait(db *sql.DB, username, ip string) (time.Duration, error) {
+	var seconds float64
+	err := db.QueryRow(`
+        SELECT COALESCE(EXTRACT(EPOCH FROM MAX(locked_until) - NOW()), 0)
+        FROM login_throttle
+        WHERE locked_until > NOW()
+          AND ((scope = 'account' AND key = $1) OR (scope = 'ip' AND key = $2))
+    `, username, ip).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// recordLoginFailure увеличивает счётчики неудач для имени и адреса и назначает
+// задержку до следующей попытки.
+func recordLoginFailure(db *sql.DB, username, ip string) error {
+	for _, t := range []struct {
+		policy throttlePolicy
+		key    string
+	}{{accountThrottle, username}, {ipThrottle, ip}} {
+		var failures int
+		err := db.QueryRow(`
+            INSERT INTO login_throttle (scope, key, failures, last_failure_at)
+            VALUES ($1, $2, 1, NOW())
+            ON CONFLICT (scope, key) DO UPDATE SET
+                failures = CASE WHEN login_throttle.last_failure_at < NOW() - make_interval(secs => $3)
+                                THEN 1 ELSE login_throttle.failures + 1 END,
+                last_failure_at = NOW()
+            RETURNING failures
+        `, t.policy.scope, t.key, failureWindow.Seconds()).Scan(&failures)
+		if err != nil {
+			return err
+		}
+		if d := t.policy.delay(failures); d > 0 {
+			_, err = db.Exec(`UPDATE login_throttle SET locked_until = NOW() + make_interval(secs => $3) WHERE scope = $1 AND key = $2`,
+				t.policy.scope, t.key, d.Seconds())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verifyCredentials проверяет логин и пароль с учётом ограничения попыток. Возвращает
+// errInvalidCredentials или *loginLockedError; статус учётной записи и второй фактор
+// не проверяет.
+func verifyCredentials(db *sql.DB, username, password, ip string) (models.User, error) {
+	var user models.User
+	wait, err := loginWait(db, username, ip)
+	if err != nil {
+		return user, err
+	}
+	if wait > 0 {
+		return user, &loginLockedError{wait: wait}
+	}
+
+	err = db.QueryRow(`
+        SELECT id, username, password, COALESCE(email, ''), role, status, email_verified_at IS NOT NULL,
+               totp_enabled_at IS NOT NULL,
+               EXISTS (SELECT 1 FROM two_factor_policy p WHERE p.role = users.role AND p.required)
+        FROM users
+        WHERE username=$1
+    `, username).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.Role, &user.Status, &user.EmailVerified,
+		&user.TwoFactorEnabled, &user.TwoFactorRequired)
+	if err != nil && err != sql.ErrNoRows {
+		return user, err
+	}
+	found := err == nil
+	hash := dummyPasswordHash
+	if found {
+		hash = user.Password
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !found {
+		if err := recordLoginFailure(db, username, ip); err != nil {
+			return user, err
+		}
+		return user, errInvalidCredentials
+	}
+
+	// С включённым вторым фактором счётчик сбрасывается только после верного кода,
+	// иначе знание пароля позволяло бы перебирать коды без задержек.
+	if !user.TwoFactorEnabled {
+		if err := clearLoginFailures(db, username); err != nil {
+			return user, err
+		}
+	}
+	return user, nil
+}
+
+// clearLoginFailures сбрасывает счётчик неудачных попыток входа под именем username.
+func clearLoginFailures(db DBQuerier, username string) error {
+	_, err := db.Exec(`DELETE FROM login_throttle WHERE scope = 'account' AND key = $1`, username)
+	return err
+}
+
+// loginFailure подбирает HTTP-статус и сообщение для ошибки verifyCredentials или
+// проверки второго фактора; подробности
+// ошибок базы пишутся в журнал, а не показываются пользователю.
+func loginFailure(err error) (int, string) {
+	var locked *loginLockedError
+	switch {
+	case errors.As(err, &locked):
+		return http.StatusTooManyRequests, err.Error()
+	case err == errInvalidCredentials, err == errInvalidTOTPCode, err == errLoginChallengeExpired:
+		return http.StatusUnauthorized, err.Error()
+	case err == errTwoFactorSetupRequired:
+		return http.StatusForbidden, err.Error()
+	default:
+		log.Printf("Ошибка проверки учётных данных: %v", err)
+		return http.StatusInternalServerError, "Ошибка входа, попробуйте позже"
+	}
+}
+
+// loadLoginLockouts возвращает действующие блокировки и недавние неудачные попытки входа.
+func loadLoginLockouts(db *sql.DB) ([]models.LoginLockout, error) {
+	rows, err := db.Query(`
+        SELECT id, scope, key, failures, last_failure_at, locked_until, COALESCE(locked_until > NOW(), FALSE)
+        FROM login_throttle
+        WHERE locked_until > NOW() OR last_failure_at > NOW() - make_interval(secs => $1)
+        ORDER BY COALESCE(locked_until > NOW(), FALSE) DESC, last_failure_at DESC
+    `, failureWindow.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.LoginLockout
+	for rows.Next() {
+		var l models.LoginLockout
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&l.ID, &l.Scope, &l.Key, &l.Failures, &l.LastFailureAt, &lockedUntil, &l.Locked); err != nil {
+			return nil, err
+		}
+		l.LockedUntil = lockedUntil.Time
+		result = append(result, l)
+	}
+	return result, rows.Err()
+}
+
+// ClearLoginLockoutHandler снимает блокировку входа и сбрасывает счётчик неудач.
+func ClearLoginLockoutHandler(c *gin.Context, db *sql.DB) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Неверный ID блокировки",
+		})
+		return
+	}
+	res, err := db.Exec(`DELETE FROM login_throttle WHERE id = $1`, id)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка снятия блокировки: " + err.Error(),
+		})
+		return
+	}
+	alarm := "Блокировка снята"
+	if n, _ := res.RowsAffected(); n == 0 {
+		alarm = "Блокировка не найдена"
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/users?alarm="+url.QueryEscape(alarm))
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Типы запросов на изменение расписания.
+const (
+	RequestKindText      = "text"
+	RequestKindTime      = "time"
+	RequestKindClassroom = "classroom"
+	RequestKindTeacher   = "teacher"
+	RequestKindSwap      = "swap"
+	RequestKindCancel    = "cancel"
+)
+
+var requestKindNames = map[string]string{
+	RequestKindText:      "Текстовый запрос",
+	RequestKindTime:      "Перенос времени",
+	RequestKindClassroom: "Смена аудитории",
+	RequestKindTeacher:   "Замена преподавателя",
+	RequestKindSwap:      "Обмен занятиями",
+	RequestKindCancel:    "Отмена занятия",
+}
+
+// proposalError — предложение нельзя принять или применить: неверные параметры,
+// коллизия или неучебный день. В отличие от ошибок БД показывается пользователю как есть.
+type proposalError struct {
+	msg string
+}
+
+func (e *proposalError) Error() string { return e.msg }
+
+func proposalErrorf(format string, args ...interface{}) error {
+	return &proposalError{msg: fmt.Sprintf(format, args...)}
+}
+
+func isProposalError(err error) bool {
+	var pe *proposalError
+	return errors.As(err, &pe)
+}
+
+// errNotOwnLesson — структурированное предложение касается чужого занятия.
+var errNotOwnLesson = errors.New("Предлагать изменения можно только по своим занятиям: для остальных отправьте текстовый запрос")
+
+// ownsLesson сообщает, ведёт ли пользователь занятие как преподаватель или учится
+// в одной из его групп.
+func ownsLesson(db DBQuerier, userID, scheduleID int) (bool, error) {
+	var owns bool
+	err := db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM schedule s
+            JOIN teachers t ON t.id = s.teacher_id
+            WHERE s.id = $1 AND t.user_id = $2
+            UNION ALL
+            SELECT 1 FROM schedule_groups sg
+            JOIN students st ON st.group_id = sg.group_id
+            WHERE sg.schedule_id = $1 AND st.user_id = $2
+        )
+    `, scheduleID, userID).Scan(&owns)
+	return owns, err
+}
+
+// requestLesson — занятие, которого касается запрос.
+type requestLesson struct {
+	ID          int
+	TeacherID   int
+	ClassroomID int
+	PairNumber  int
+	StartTime   time.Time
+	EndTime     time.Time
+	GroupIDs    []int
+}
+
+func loadRequestLesson(db DBQuerier, scheduleID int) (requestLesson, error) {
+	var l requestLesson
+	var groupIDs []int64
+	err := db.QueryRow(`
+        SELECT s.id, s.teacher_id, s.classroom_id, COALESCE(s.pair_number, 0), s.start_time, s.end_time,
+               COALESCE(array_agg(sg.group_id) FILTER (WHERE sg.group_id IS NOT NULL), '{}')
+        FROM schedule s
+        LEFT JOIN schedule_groups sg ON sg.schedule_id = s.id
+        WHERE s.id = $1
+        GROUP BY s.id
+    `, scheduleID).Scan(&l.ID, &l.TeacherID, &l.ClassroomID, &l.PairNumber, &l.StartTime, &l.EndTime, pq.Array(&groupIDs))
+	if err == sql.ErrNoRows {
+		return l, proposalErrorf("Занятие #%d не найдено", scheduleID)
+	}
+	if err != nil {
+		return l, err
+	}
+	for _, id := range groupIDs {
+		l.GroupIDs = append(l.GroupIDs, int(id))
+	}
+	return l, nil
+}
+
+// checkLessonPlacement проверяет уже изменённое занятие: коллизии с другими занятиями,
+// недоступность преподавателя и вместимость аудитории.
+func checkLessonPlacement(db DBQuerier, scheduleID int) error {
+	l, err := loadRequestLesson(db, scheduleID)
+	if err != nil {
+		return err
+	}
+	collision, err := CheckScheduleCollisionForGroups(db, l.TeacherID, l.ClassroomID, l.GroupIDs, l.StartTime, l.EndTime, l.ID)
+	if err != nil {
+		return err
+	}
+	if collision {
+		return proposalErrorf("Коллизия: занятие #%d пересекается с другим занятием преподавателя, аудитории или группы либо преподаватель недоступен %s",
+			l.ID, l.StartTime.Format("02.01.2006 15:04"))
+	}
+	students, capacity, err := CheckClassroomCapacity(db, l.ClassroomID, l.GroupIDs)
+	if err != nil {
+		return err
+	}
+	if capacity > 0 && students > capacity {
+		return proposalErrorf("Аудитория занятия #%d вмещает %d мест, а в группах %d студентов", l.ID, capacity, students)
+	}
+	return nil
+}
+
+// applyRequestProposal применяет предложение запроса к расписанию в рамках транзакции tx.
+// Изменённые занятия отвязываются от серий и заново проверяются на коллизии; при ошибке
+// вызывающий откатывает транзакцию. Текстовые запросы расписание не меняют.
+func applyRequestProposal(tx DBQuerier, r models.Request) error {
+	if r.Kind == "" || r.Kind == RequestKindText {
+		return nil
+	}
+	lesson, err := loadRequestLesson(tx, r.ScheduleID)
+	if err != nil {
+		return err
+	}
+
+	switch r.Kind {
+	case RequestKindTime:
+		if r.ProposedStart == nil {
+			return proposalErrorf("Не указано новое время занятия")
+		}
+		start := *r.ProposedStart
+		end := start.Add(lesson.EndTime.Sub(lesson.StartTime))
+		dayOff, err := CheckNonTeachingDay(tx, start)
+		if err != nil {
+			return err
+		}
+		if dayOff != nil {
+			return proposalErrorf("Нельзя перенести занятие: %s", nonTeachingDayMessage(dayOff, start))
+		}
+		if err := detachFromSeries(tx, lesson.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE schedule SET start_time = $1, end_time = $2, pair_number = NULL WHERE id = $3`,
+			start, end, lesson.ID); err != nil {
+			return err
+		}
+		return checkLessonPlacement(tx, lesson.ID)
+
+	case RequestKindClassroom:
+		if r.ProposedClassroomID == 0 {
+			return proposalErrorf("Не указана новая аудитория")
+		}
+		if err := detachFromSeries(tx, lesson.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE schedule SET classroom_id = $1 WHERE id = $2`, r.ProposedClassroomID, lesson.ID); err != nil {
+			return err
+		}
+		return checkLessonPlacement(tx, lesson.ID)
+
+	case RequestKindTeacher:
+		if r.ProposedTeacherID == 0 {
+			return proposalErrorf("Не указан новый преподаватель")
+		}
+		if err := detachFromSeries(tx, lesson.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE schedule SET teacher_id = $1 WHERE id = $2`, r.ProposedTeacherID, lesson.ID); err != nil {
+			return err
+		}
+		return checkLessonPlacement(tx, lesson.ID)
+
+	case RequestKindSwap:
+		if r.SwapScheduleID == 0 || r.SwapScheduleID == lesson.ID {
+			return proposalErrorf("Укажите другое занятие для обмена")
+		}
+		other, err := loadRequestLesson(tx, r.SwapScheduleID)
+		if err != nil {
+			return err
+		}
+		for _, id := range []int{lesson.ID, other.ID} {
+			if err := detachFromSeries(tx, id); err != nil {
+				return err
+			}
+		}
+		swap := `UPDATE schedule SET start_time = $1, end_time = $2, pair_number = $3, classroom_id = $4 WHERE id = $5`
+		if _, err := tx.Exec(swap, other.StartTime, other.EndTime, nullableInt(other.PairNumber), other.ClassroomID, lesson.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(swap, lesson.StartTime, lesson.EndTime, nullableInt(lesson.PairNumber), lesson.ClassroomID, other.ID); err != nil {
+			return err
+		}
+		if err := checkLessonPlacement(tx, lesson.ID); err != nil {
+			return err
+		}
+		return checkLessonPlacement(tx, other.ID)
+
+	case RequestKindCancel:
+		if err := addSeriesException(tx, lesson.ID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM schedule WHERE id = $1`, lesson.ID)
+		return err
+	}
+	return proposalErrorf("Неизвестный тип запроса")
+}
+
+// checkRequestProposal проверяет предложение на момент подачи запроса: применяет его
+// в транзакции и откатывает её, так что проверка совпадает с тем, что сделает одобрение.
+func checkRequestProposal(db *sql.DB, r models.Request) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return applyRequestProposal(tx, r)
+}
+
+// parseRequestForm читает из формы занятие, тип запроса, параметры предложения и текст.
+func parseRequestForm(c *gin.Context) (models.Request, error) {
+	var r models.Request
+	scheduleID, err := strconv.Atoi(c.PostForm("schedule_id"))
+	if err != nil {
+		return r, proposalErrorf("Неверный ID занятия")
+	}
+	r.ScheduleID = scheduleID
+	r.DesiredChange = strings.TrimSpace(c.PostForm("desired_change"))
+	r.Kind = c.DefaultPostForm("kind", RequestKindText)
+
+	switch r.Kind {
+	case RequestKindTime:
+		start, err := time.Parse("2006-01-02T15:04", c.PostForm("proposed_start"))
+		if err != nil {
+			return r, proposalErrorf("Неверное новое время занятия")
+		}
+		r.ProposedStart = &start
+	case RequestKindClassroom:
+		r.ProposedClassroomID, _ = strconv.Atoi(c.PostForm("proposed_classroom_id"))
+	case RequestKindTeacher:
+		r.ProposedTeacherID, _ = strconv.Atoi(c.PostForm("proposed_teacher_id"))
+	case RequestKindSwap:
+		r.SwapScheduleID, _ = strconv.Atoi(c.PostForm("swap_schedule_id"))
+	}
+	return r, nil
+}
+
+// createChangeRequest проверяет и сохраняет запрос пользователя userID вместе с событием created.
+func createChangeRequest(db *sql.DB, userID int, r models.Request) (int, error) {
+	if _, ok := requestKindNames[r.Kind]; !ok {
+		return 0, proposalErrorf("Неизвестный тип запроса")
+	}
+	if r.Kind == RequestKindText && r.DesiredChange == "" {
+		return 0, proposalErrorf("Запрос не может быть пустым")
+	}
+	if _, err := loadRequestLesson(db, r.ScheduleID); err != nil {
+		return 0, err
+	}
+	// Текстовый запрос — просьба к администратору; структурированное предложение
+	// меняет расписание при одобрении, поэтому допускается только по своим занятиям.
+	if r.Kind != RequestKindText {
+		lessons := []int{r.ScheduleID}
+		if r.Kind == RequestKindSwap && r.SwapScheduleID != 0 {
+			lessons = append(lessons, r.SwapScheduleID)
+		}
+		for _, id := range lessons {
+			owns, err := ownsLesson(db, userID, id)
+			if err != nil {
+				return 0, err
+			}
+			if !owns {
+				return 0, errNotOwnLesson
+			}
+		}
+	}
+	if err := checkRequestProposal(db, r); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var requestID int
+	err = tx.QueryRow(`
+        INSERT INTO requests (user_id, schedule_id, desired_change, status, kind,
+                              proposed_start, proposed_classroom_id, proposed_teacher_id, swap_schedule_id)
+        VALUES ($1, $2, $3, 'pending', $4, $5, $6, $7, $8)
+        RETURNING id
+    `, userID, r.ScheduleID, r.DesiredChange, r.Kind, r.ProposedStart,
+		nullableInt(r.ProposedClassroomID), nullableInt(r.ProposedTeacherID), nullableInt(r.SwapScheduleID)).Scan(&requestID)
+	if err != nil {
+		return 0, err
+	}
+	if err := addRequestEvent(tx, requestID, userID, RequestEventCreated, r.DesiredChange); err != nil {
+		return 0, err
+	}
+	return requestID, tx.Commit()
+}
+
+// requestSelect выбирает запрос вместе с автором, занятием и описанием предложения.
+// Занятие может быть уже удалено (например, отменено по этому запросу).
+const requestSelect = `
+        SELECT r.id, r.user_id, COALESCE(r.schedule_id, 0), COALESCE(r.desired_change, ''), r.status,
+               r.kind, r.proposed_start, COALESCE(r.proposed_classroom_id, 0), COALESCE(r.proposed_teacher_id, 0),
+               COALESCE(r.swap_schedule_id, 0), COALESCE(pc.room_number, ''), COALESCE(pt.name, ''), r.created_at,
+               COALESCE(rt.name, rs.name, u.username), u.role,
+               COALESCE(sub.name, ''), COALESCE(t.name, ''), COALESCE(c.room_number, ''), s.start_time,
+               COALESCE((SELECT string_agg(g.name, ', ' ORDER BY g.name)
+                         FROM schedule_groups sg JOIN groups g ON g.id = sg.group_id
+                         WHERE sg.schedule_id = r.schedule_id), '')
+        FROM requests r
+        JOIN users u ON u.id = r.user_id
+        LEFT JOIN teachers rt ON rt.user_id = u.id
+        LEFT JOIN students rs ON rs.user_id = u.id
+        LEFT JOIN schedule s ON s.id = r.schedule_id
+        LEFT JOIN subjects sub ON sub.id = s.subject_id
+        LEFT JOIN teachers t ON t.id = s.teacher_id
+        LEFT JOIN classrooms c ON c.id = s.classroom_id
+        LEFT JOIN classrooms pc ON pc.id = r.proposed_classroom_id
+        LEFT JOIN teachers pt ON pt.id = r.proposed_teacher_id`
+
+func scanRequest(row interface{ Scan(...interface{}) error }) (models.Request, error) {
+	var r models.Request
+	var roomNumber, teacherName string
+	err := row.Scan(&r.ID, &r.UserID, &r.ScheduleID, &r.DesiredChange, &r.Status,
+		&r.Kind, &r.ProposedStart, &r.ProposedClassroomID, &r.ProposedTeacherID,
+		&r.SwapScheduleID, &roomNumber, &teacherName, &r.CreatedAt,
+		&r.RequesterName, &r.RequesterRole,
+		&r.SubjectName, &r.TeacherName, &r.RoomNumber, &r.LessonStart, &r.GroupNames)
+	if err != nil {
+		return r, err
+	}
+	r.Proposal = describeProposal(r, roomNumber, teacherName)
+	return r, nil
+}
+
+// loadRequests загружает запросы вместе с описанием предложений и историей. where — условие
+// без слова WHERE (пустое — все запросы), order — порядок сортировки, при необходимости
+// с LIMIT и OFFSET.
+func loadRequests(db DBQuerier, where, order string, args ...interface{}) ([]models.Request, error) {
+	query := requestSelect
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY " + order
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+	for rows.Next() {
+		r, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := attachRequestEvents(db, requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func describeProposal(r models.Request, roomNumber, teacherName string) string {
+	switch r.Kind {
+	case RequestKindTime:
+		if r.ProposedStart != nil {
+			return "Перенос на " + r.ProposedStart.Format("02.01.2006 15:04")
+		}
+	case RequestKindClassroom:
+		return "Аудитория " + roomNumber
+	case RequestKindTeacher:
+		return "Преподаватель: " + teacherName
+	case RequestKindSwap:
+		return fmt.Sprintf("Обмен временем и аудиторией с занятием #%d", r.SwapScheduleID)
+	case RequestKindCancel:
+		return "Отмена занятия"
+	}
+	return ""
+}
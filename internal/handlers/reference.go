@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// referenceError — отказ в изменении справочника (предметы, аудитории, группы)
+// с HTTP-статусом, который получит форма или клиент API.
+type referenceError struct {
+	status int
+	msg    string
+}
+
+func (e *referenceError) Error() string { return e.msg }
+
+func referenceErrorf(status int, format string, args ...interface{}) error {
+	return &referenceError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// referenceStatus возвращает HTTP-статус для ошибки сохранения или удаления записи справочника.
+func referenceStatus(err error) int {
+	var refErr *referenceError
+	if errors.As(err, &refErr) {
+		return refErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// checkLength проверяет обязательность и длину текстового поля (по размеру столбца в БД).
+func checkLength(value, label string, max int, required bool) error {
+	if required && value == "" {
+		return referenceErrorf(http.StatusBadRequest, "Укажите %s", label)
+	}
+	if utf8.RuneCountInString(value) > max {
+		return referenceErrorf(http.StatusBadRequest, "Поле «%s» длиннее %d символов", label, max)
+	}
+	return nil
+}
+
+// referenceUsage — запрос, считающий ссылки на запись справочника ($1 — её id).
+type referenceUsage struct {
+	label string
+	query string
+}
+
+var (
+	subjectUsages = []referenceUsage{
+		{"занятий в расписании", `SELECT COUNT(*) FROM schedule WHERE subject_id = $1`},
+		{"серий занятий", `SELECT COUNT(*) FROM lesson_series WHERE subject_id = $1`},
+		{"строк учебного плана", `SELECT COUNT(*) FROM curriculum WHERE subject_id = $1`},
+	}
+	classroomUsages = []referenceUsage{
+		{"занятий в расписании", `SELECT COUNT(*) FROM schedule WHERE classroom_id = $1`},
+		{"серий занятий", `SELECT COUNT(*) FROM lesson_series WHERE classroom_id = $1`},
+	}
+	groupUsages = []referenceUsage{
+		{"занятий в расписании", `SELECT COUNT(*) FROM schedule_groups WHERE group_id = $1`},
+		{"серий занятий", `SELECT COUNT(*) FROM lesson_series_groups WHERE group_id = $1`},
+		{"строк учебного плана", `SELECT COUNT(*) FROM curriculum WHERE group_id = $1`},
+		{"студентов", `SELECT COUNT(*) FROM students WHERE group_id = $1`},
+	}
+)
+
+// deleteReference удаляет запись справочника, только если на неё ничего не ссылается.
+// Внешние ключи расписания запрещают удаление и сами по себе; проверка нужна, чтобы
+// объяснить, что именно мешает.
+func deleteReference(db *sql.DB, table, what string, id int, usages []referenceUsage) error {
+	var used []string
+	for _, u := range usages {
+		var n int
+		if err := db.QueryRow(u.query, id).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			used = append(used, fmt.Sprintf("%s — %d", u.label, n))
+		}
+	}
+	if len(used) > 0 {
+		return referenceErrorf(http.StatusConflict, "Нельзя удалить %s: %s. Сначала перенесите или удалите их", what, strings.Join(used, ", "))
+	}
+
+	res, err := db.Exec(`DELETE FROM `+table+` WHERE id = $1`, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return referenceErrorf(http.StatusConflict, "Нельзя удалить %s: на запись ссылается расписание", what)
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return referenceErrorf(http.StatusNotFound, "Запись не найдена")
+	}
+	return nil
+}
+
+// saveReference вставляет запись (id == 0) или обновляет существующую и возвращает её id.
+func saveReference(db DBQuerier, id int, insert, update string, args ...interface{}) (int, error) {
+	if id == 0 {
+		err := db.QueryRow(insert, args...).Scan(&id)
+		return id, err
+	}
+	res, err := db.Exec(update, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, referenceErrorf(http.StatusNotFound, "Запись не найдена")
+	}
+	return id, nil
+}
+
+func saveSubject(db DBQuerier, s *models.Subject) error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Description = strings.TrimSpace(s.Description)
+	if err := checkLength(s.Name, "название предмета", 255, true); err != nil {
+		return err
+	}
+
+	var duplicates int
+	err := db.QueryRow(`SELECT COUNT(*) FROM subjects WHERE lower(name) = lower($1) AND id <> $2`, s.Name, s.ID).Scan(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return referenceErrorf(http.StatusConflict, "Предмет «%s» уже есть", s.Name)
+	}
+
+	s.ID, err = saveReference(db, s.ID,
+		`INSERT INTO subjects (name, description) VALUES ($1, NULLIF($2, '')) RETURNING id`,
+		`UPDATE subjects SET name = $2, description = NULLIF($3, '') WHERE id = $1`,
+		s.Name, s.Description)
+	return err
+}
+
+func saveClassroom(db DBQuerier, cl *models.Classroom) error {
+	cl.RoomNumber = strings.TrimSpace(cl.RoomNumber)
+	cl.Building = strings.TrimSpace(cl.Building)
+	if err := checkLength(cl.RoomNumber, "номер аудитории", 50, true); err != nil {
+		return err
+	}
+	if err := checkLength(cl.Building, "корпус", 255, false); err != nil {
+		return err
+	}
+	if cl.Capacity < 0 {
+		return referenceErrorf(http.StatusBadRequest, "Вместимость не может быть отрицательной")
+	}
+
+	var duplicates int
+	err := db.QueryRow(`
+        SELECT COUNT(*) FROM classrooms
+        WHERE lower(room_number) = lower($1) AND lower(COALESCE(building, '')) = lower($2) AND id <> $3
+    `, cl.RoomNumber, cl.Building, cl.ID).Scan(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return referenceErrorf(http.StatusConflict, "Аудитория %s уже есть в этом корпусе", cl.RoomNumber)
+	}
+
+	// Нулевая вместимость означает «не указана», как и в loadAllClassrooms.
+	cl.ID, err = saveReference(db, cl.ID,
+		`INSERT INTO classrooms (room_number, building, capacity) VALUES ($1, NULLIF($2, ''), NULLIF($3, 0)) RETURNING id`,
+		`UPDATE classrooms SET room_number = $2, building = NULLIF($3, ''), capacity = NULLIF($4, 0) WHERE id = $1`,
+		cl.RoomNumber, cl.Building, cl.Capacity)
+	return err
+}
+
+func saveGroup(db DBQuerier, g *models.Group) error {
+	g.Name = strings.TrimSpace(g.Name)
+	g.Course = strings.TrimSpace(g.Course)
+	if err := checkLength(g.Name, "название группы", 50, true); err != nil {
+		return err
+	}
+	if err := checkLength(g.Course, "курс", 50, false); err != nil {
+		return err
+	}
+
+	var duplicates int
+	err := db.QueryRow(`SELECT COUNT(*) FROM groups WHERE lower(name) = lower($1) AND id <> $2`, g.Name, g.ID).Scan(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return referenceErrorf(http.StatusConflict, "Группа %s уже есть", g.Name)
+	}
+
+	g.ID, err = saveReference(db, g.ID,
+		`INSERT INTO groups (name, course) VALUES ($1, NULLIF($2, '')) RETURNING id`,
+		`UPDATE groups SET name = $2, course = NULLIF($3, '') WHERE id = $1`,
+		g.Name, g.Course)
+	return err
+}
+
+func loadReferenceSubjects(db *sql.DB) ([]models.Subject, error) {
+	rows, err := db.Query(`
+        SELECT sub.id, sub.name, COALESCE(sub.description, ''),
+               (SELECT COUNT(*) FROM schedule s WHERE s.subject_id = sub.id)
+        FROM subjects sub
+        ORDER BY sub.name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Subject
+	for rows.Next() {
+		var s models.Subject
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.LessonCount); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+func loadReferenceClassrooms(db *sql.DB) ([]models.Classroom, error) {
+	rows, err := db.Query(`
+        SELECT c.id, c.room_number, COALESCE(c.building, ''), COALESCE(c.capacity, 0),
+               (SELECT COUNT(*) FROM schedule s WHERE s.classroom_id = c.id)
+        FROM classrooms c
+        ORDER BY c.building NULLS FIRST, c.room_number
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Classroom
+	for rows.Next() {
+		var cl models.Classroom
+		if err := rows.Scan(&cl.ID, &cl.RoomNumber, &cl.Building, &cl.Capacity, &cl.LessonCount); err != nil {
+			return nil, err
+		}
+		result = append(result, cl)
+	}
+	return result, rows.Err()
+}
+
+func loadReferenceGroups(db *sql.DB) ([]models.Group, error) {
+	rows, err := db.Query(`
+        SELECT g.id, g.name, COALESCE(g.course, ''),
+               (SELECT COUNT(*) FROM students st WHERE st.group_id = g.id),
+               (SELECT COUNT(*) FROM schedule_groups sg WHERE sg.group_id = g.id)
+        FROM groups g
+        ORDER BY g.name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Group
+	for rows.Next() {
+		var g models.Group
+		if err := rows.Scan(&g.ID, &g.Name, &g.Course, &g.StudentCount, &g.LessonCount); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
+
+func renderAdminReferencePage(c *gin.Context, db *sql.DB, status int, errMsg string) {
+	subjects, err := loadReferenceSubjects(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "reference_admin", gin.H{
+			"Title": "Справочники",
+			"Error": "Ошибка загрузки предметов: " + err.Error(),
+		})
+		return
+	}
+	classrooms, err := loadReferenceClassrooms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "reference_admin", gin.H{
+			"Title": "Справочники",
+			"Error": "Ошибка загрузки аудиторий: " + err.Error(),
+		})
+		return
+	}
+	groups, err := loadReferenceGroups(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "reference_admin", gin.H{
+			"Title": "Справочники",
+			"Error": "Ошибка загрузки групп: " + err.Error(),
+		})
+		return
+	}
+	departments, err := loadDepartments(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "reference_admin", gin.H{
+			"Title": "Справочники",
+			"Error": "Ошибка загрузки кафедр: " + err.Error(),
+		})
+		return
+	}
+	teachers, err := loadAllTeachers(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "reference_admin", gin.H{
+			"Title": "Справочники",
+			"Error": "Ошибка загрузки преподавателей: " + err.Error(),
+		})
+		return
+	}
+	c.HTML(status, "reference_admin", gin.H{
+		"Title":       "Справочники",
+		"Subjects":    subjects,
+		"Classrooms":  classrooms,
+		"Groups":      groups,
+		"Departments": departments,
+		"Teachers":    teachers,
+		"Error":       errMsg,
+		"Alarm":       c.Query("alarm"),
+	})
+}
+
+func RenderAdminReferencePage(c *gin.Context, db *sql.DB) {
+	renderAdminReferencePage(c, db, http.StatusOK, "")
+}
+
+// finishReferenceForm перерисовывает страницу с ошибкой или возвращает на неё с сообщением об успехе.
+func finishReferenceForm(c *gin.Context, db *sql.DB, err error, alarm string) {
+	if err != nil {
+		renderAdminReferencePage(c, db, referenceStatus(err), err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/reference?alarm="+url.QueryEscape(alarm))
+}
+
+// referenceFormID читает id записи из пути; для неверного id возвращает ошибку формы.
+func referenceFormID(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		return 0, referenceErrorf(http.StatusBadRequest, "Неверный ID записи")
+	}
+	return id, nil
+}
+
+func SaveSubjectFormHandler(c *gin.Context, db *sql.DB) {
+	s := models.Subject{Name: c.PostForm("name"), Description: c.PostForm("description")}
+	var err error
+	if c.Param("id") != "" {
+		s.ID, err = referenceFormID(c)
+	}
+	if err == nil {
+		err = saveSubject(db, &s)
+	}
+	finishReferenceForm(c, db, err, "Предмет «"+s.Name+"» сохранён")
+}
+
+func DeleteSubjectFormHandler(c *gin.Context, db *sql.DB) {
+	id, err := referenceFormID(c)
+	if err == nil {
+		err = deleteReference(db, "subjects", "предмет", id, subjectUsages)
+	}
+	finishReferenceForm(c, db, err, "Предмет удалён")
+}
+
+// classroomFromForm читает аудиторию из формы; пустая вместимость — «не указана».
+func classroomFromForm(c *gin.Context) (models.Classroom, error) {
+	cl := models.Classroom{RoomNumber: c.PostForm("room_number"), Building: c.PostForm("building")}
+	if v := strings.TrimSpace(c.PostForm("capacity")); v != "" {
+		capacity, err := strconv.Atoi(v)
+		if err != nil {
+			return cl, referenceErrorf(http.StatusBadRequest, "Вместимость должна быть числом")
+		}
+		cl.Capacity = capacity
+	}
+	return cl, nil
+}
+
+func SaveClassroomFormHandler(c *gin.Context, db *sql.DB) {
+	cl, err := classroomFromForm(c)
+	if err == nil && c.Param("id") != "" {
+		cl.ID, err = referenceFormID(c)
+	}
+	if err == nil {
+		err = saveClassroom(db, &cl)
+	}
+	finishReferenceForm(c, db, err, "Аудитория "+cl.RoomNumber+" сохранена")
+}
+
+func DeleteClassroomFormHandler(c *gin.Context, db *sql.DB) {
+	id, err := referenceFormID(c)
+	if err == nil {
+		err = deleteReference(db, "classrooms", "аудиторию", id, classroomUsages)
+	}
+	finishReferenceForm(c, db, err, "Аудитория удалена")
+}
+
+func SaveGroupFormHandler(c *gin.Context, db *sql.DB) {
+	g := models.Group{Name: c.PostForm("name"), Course: c.PostForm("course")}
+	var err error
+	if c.Param("id") != "" {
+		g.ID, err = referenceFormID(c)
+	}
+	if err == nil {
+		err = saveGroup(db, &g)
+	}
+	finishReferenceForm(c, db, err, "Группа "+g.Name+" сохранена")
+}
+
+func DeleteGroupFormHandler(c *gin.Context, db *sql.DB) {
+	id, err := referenceFormID(c)
+	if err == nil {
+		err = deleteReference(db, "groups", "группу", id, groupUsages)
+	}
+	finishReferenceForm(c, db, err, "Группа удалена")
+}
+
+// saveReferenceAPI разбирает JSON-тело в item, сохраняет запись и отвечает ею:
+// 201 при создании (POST), 200 при изменении (PUT /:id).
+func saveReferenceAPI(c *gin.Context, item interface{}, setID func(int), save func() error) {
+	if err := c.ShouldBindJSON(item); err != nil {
+		apiError(c, http.StatusBadRequest, "Неверный JSON: "+err.Error())
+		return
+	}
+	status := http.StatusCreated
+	setID(0)
+	if c.Param("id") != "" {
+		id, err := referenceFormID(c)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		setID(id)
+		status = http.StatusOK
+	}
+	if err := save(); err != nil {
+		apiError(c, referenceStatus(err), err.Error())
+		return
+	}
+	c.JSON(status, item)
+}
+
+func deleteReferenceAPI(c *gin.Context, db *sql.DB, table, what string, usages []referenceUsage) {
+	id, err := referenceFormID(c)
+	if err == nil {
+		err = deleteReference(db, table, what, id, usages)
+	}
+	if err != nil {
+		apiError(c, referenceStatus(err), err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func SaveSubjectAPIHandler(c *gin.Context, db *sql.DB) {
+	var s models.Subject
+	saveReferenceAPI(c, &s, func(id int) { s.ID = id }, func() error { return saveSubject(db, &s) })
+}
+
+func DeleteSubjectAPIHandler(c *gin.Context, db *sql.DB) {
+	deleteReferenceAPI(c, db, "subjects", "предмет", subjectUsages)
+}
+
+func SaveClassroomAPIHandler(c *gin.Context, db *sql.DB) {
+	var cl models.Classroom
+	saveReferenceAPI(c, &cl, func(id int) { cl.ID = id }, func() error { return saveClassroom(db, &cl) })
+}
+
+func DeleteClassroomAPIHandler(c *gin.Context, db *sql.DB) {
+	deleteReferenceAPI(c, db, "classrooms", "аудиторию", classroomUsages)
+}
+
+func SaveGroupAPIHandler(c *gin.Context, db *sql.DB) {
+	var g models.Group
+	saveReferenceAPI(c, &g, func(id int) { g.ID = id }, func() error { return saveGroup(db, &g) })
+}
+
+func DeleteGroupAPIHandler(c *gin.Context, db *sql.DB) {
+	deleteReferenceAPI(c, db, "groups", "группу", groupUsages)
+}
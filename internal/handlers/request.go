@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"net/http"
 	"scheduleApp/internal/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,20 +23,38 @@ func CreateRequestHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 	var body struct {
-		ScheduleID    int    `json:"schedule_id"`
-		DesiredChange string `json:"desired_change"`
+		ScheduleID          int        `json:"schedule_id"`
+		DesiredChange       string     `json:"desired_change"`
+		Kind                string     `json:"kind"`
+		ProposedStart       *time.Time `json:"proposed_start"`
+		ProposedClassroomID int        `json:"proposed_classroom_id"`
+		ProposedTeacherID   int        `json:"proposed_teacher_id"`
+		SwapScheduleID      int        `json:"swap_schedule_id"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
-	var requestID int
-	query := `
-        INSERT INTO requests (user_id, schedule_id, desired_change, status)
-        VALUES ($1, $2, $3, 'pending')
-        RETURNING id
-    `
-	err := db.QueryRow(query, userID, body.ScheduleID, body.DesiredChange).Scan(&requestID)
+	if body.Kind == "" {
+		body.Kind = RequestKindText
+	}
+	requestID, err := createChangeRequest(db, userID, models.Request{
+		ScheduleID:          body.ScheduleID,
+		DesiredChange:       body.DesiredChange,
+		Kind:                body.Kind,
+		ProposedStart:       body.ProposedStart,
+		ProposedClassroomID: body.ProposedClassroomID,
+		ProposedTeacherID:   body.ProposedTeacherID,
+		SwapScheduleID:      body.SwapScheduleID,
+	})
+	if err == errNotOwnLesson {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if isProposalError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,86 +66,102 @@ func CreateRequestHandler(c *gin.Context, db *sql.DB) {
 	})
 }
 
-func ProcessRequestFormHandler(c *gin.Context, db *sql.DB, action string) {
-	reqID := c.Param("id")
-	status := ""
-	if action == "approve" {
-		status = "approved"
-	} else if action == "reject" {
-		status = "rejected"
-	} else {
-		c.HTML(http.StatusBadRequest, "requests_admin", gin.H{
-			"Title": "Запросы (Admin)",
-			"Error": "Неверное действие",
-		})
-		return
+// requestActionStatus переводит действие формы в новый статус запроса.
+func requestActionStatus(action string) (string, bool) {
+	switch action {
+	case "approve":
+		return "approved", true
+	case "reject":
+		return "rejected", true
 	}
+	return "", false
+}
 
-	_, err := db.Exec(`UPDATE requests SET status=$1 WHERE id=$2`, status, reqID)
+// processRequest одобряет или отклоняет запрос от имени администратора adminID.
+// Одобрение применяет структурированное предложение к расписанию в одной транзакции
+// со сменой статуса и записью в историю; для отклонения обязательна причина.
+// Ошибки, которые нужно показать администратору, возвращаются как proposalError.
+func processRequest(db *sql.DB, reqID, adminID int, status, reason string) error {
+	tx, err := db.Begin()
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "requests_admin", gin.H{
-			"Title": "Запросы (Admin)",
-			"Error": err.Error(),
-		})
-		return
+		return err
 	}
-}
+	defer tx.Rollback()
 
-func RenderAdminRequestsPage(c *gin.Context, db *sql.DB) {
-	rows, err := db.Query(`
-        SELECT id, user_id, schedule_id, desired_change, status
-        FROM requests
-				WHERE status != 'rejected'
-        ORDER BY id
-    `)
+	r, err := scanRequest(tx.QueryRow(requestSelect+` WHERE r.id = $1 FOR UPDATE OF r`, reqID))
+	if err == sql.ErrNoRows {
+		return proposalErrorf("Запрос #%d не найден", reqID)
+	}
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "requests_admin", gin.H{
-			"Title": "Запросы (Admin)",
-			"Error": err.Error(),
-		})
-		return
+		return err
+	}
+	if r.Status != "pending" {
+		return proposalErrorf("Запрос #%d уже обработан", reqID)
+	}
+	if status == "rejected" && reason == "" {
+		return proposalErrorf("Укажите причину отклонения запроса #%d", reqID)
 	}
-	defer rows.Close()
 
-	var requests []models.Request
-	for rows.Next() {
-		var r models.Request
-		if err := rows.Scan(&r.ID, &r.UserID, &r.ScheduleID, &r.DesiredChange, &r.Status); err != nil {
-			c.HTML(http.StatusInternalServerError, "requests_admin", gin.H{
-				"Title": "Запросы (Admin)",
-				"Error": err.Error(),
-			})
-			return
+	if status == "approved" {
+		if err := applyRequestProposal(tx, r); err != nil {
+			if isProposalError(err) {
+				return proposalErrorf("Не удалось применить запрос #%d: %s", reqID, err.Error())
+			}
+			return err
 		}
-		requests = append(requests, r)
 	}
 
-	c.HTML(http.StatusOK, "requests_admin", gin.H{
-		"Title":    "Запросы (Admin)",
-		"Requests": requests,
-	})
+	if _, err := tx.Exec(`UPDATE requests SET status=$1 WHERE id=$2`, status, reqID); err != nil {
+		return err
+	}
+	if err := addRequestEvent(tx, reqID, adminID, status, reason); err != nil {
+		return err
+	}
+	if status == "approved" && r.Kind != RequestKindText {
+		if err := addRequestEvent(tx, reqID, adminID, RequestEventApplied, r.Proposal); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func GetAllRequestsHandler(c *gin.Context, db *sql.DB) {
-	rows, err := db.Query(`
-        SELECT id, user_id, schedule_id, desired_change, status
-        FROM requests
-        ORDER BY id
-    `)
+// ProcessRequestFormHandler одобряет или отклоняет один запрос. Если с момента подачи
+// появилась коллизия, запрос остаётся на рассмотрении.
+func ProcessRequestFormHandler(c *gin.Context, db *sql.DB, action string) {
+	reqID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		renderAdminRequestsPage(c, db, http.StatusBadRequest, "Неверный ID запроса")
+		return
+	}
+	status, ok := requestActionStatus(action)
+	if !ok {
+		renderAdminRequestsPage(c, db, http.StatusBadRequest, "Неверное действие")
 		return
 	}
-	defer rows.Close()
+	adminIDVal, _ := c.Get("user_id")
+	adminID, _ := adminIDVal.(int)
 
-	var requests []models.Request
-	for rows.Next() {
-		var r models.Request
-		if err := rows.Scan(&r.ID, &r.UserID, &r.ScheduleID, &r.DesiredChange, &r.Status); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		requests = append(requests, r)
+	err = processRequest(db, reqID, adminID, status, strings.TrimSpace(c.PostForm("reason")))
+	if isProposalError(err) {
+		renderAdminRequestsPage(c, db, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		renderAdminRequestsPage(c, db, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if status == "approved" {
+		redirectToInbox(c, "Запрос одобрен")
+		return
+	}
+	redirectToInbox(c, "Запрос отклонён")
+}
+
+func GetAllRequestsHandler(c *gin.Context, db *sql.DB) {
+	requests, err := loadRequests(db, "", "r.id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, requests)
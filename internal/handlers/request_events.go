@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// События в истории запроса на изменение расписания.
+const (
+	RequestEventCreated   = "created"
+	RequestEventCommented = "commented"
+	RequestEventApproved  = "approved"
+	RequestEventRejected  = "rejected"
+	RequestEventApplied   = "applied"
+	RequestEventWithdrawn = "withdrawn"
+)
+
+var requestEventNames = map[string]string{
+	RequestEventCreated:   "Создан",
+	RequestEventCommented: "Комментарий",
+	RequestEventApproved:  "Одобрен",
+	RequestEventRejected:  "Отклонён",
+	RequestEventApplied:   "Применён к расписанию",
+	RequestEventWithdrawn: "Отозван автором",
+}
+
+func addRequestEvent(db DBQuerier, requestID, actorID int, event, message string) error {
+	_, err := db.Exec(`
+        INSERT INTO request_events (request_id, actor_id, event, message)
+        VALUES ($1, $2, $3, $4)
+    `, requestID, nullableInt(actorID), event, message)
+	return err
+}
+
+// attachRequestEvents загружает историю для списка запросов одним запросом и
+// заполняет Events и DecisionReason.
+func attachRequestEvents(db DBQuerier, requests []models.Request) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(requests))
+	index := make(map[int]int, len(requests))
+	for i, r := range requests {
+		ids[i] = int64(r.ID)
+		index[r.ID] = i
+	}
+
+	rows, err := db.Query(`
+        SELECT e.id, e.request_id, COALESCE(e.actor_id, 0), COALESCE(u.username, ''), COALESCE(u.role, ''),
+               e.event, e.message, e.created_at
+        FROM request_events e
+        LEFT JOIN users u ON u.id = e.actor_id
+        WHERE e.request_id = ANY($1)
+        ORDER BY e.created_at, e.id
+    `, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.RequestEvent
+		if err := rows.Scan(&e.ID, &e.RequestID, &e.ActorID, &e.ActorName, &e.ActorRole,
+			&e.Event, &e.Message, &e.CreatedAt); err != nil {
+			return err
+		}
+		r := &requests[index[e.RequestID]]
+		r.Events = append(r.Events, e)
+		if e.Event == RequestEventRejected {
+			r.DecisionReason = e.Message
+		}
+	}
+	return rows.Err()
+}
+
+// requestsPagePath возвращает страницу запросов для роли пользователя.
+func requestsPagePath(role string) string {
+	switch role {
+	case "admin":
+		return "/admin/requests"
+	case "teacher":
+		return "/teacher/requests"
+	}
+	return "/student/requests"
+}
+
+func redirectToRequests(c *gin.Context, role, alarm string) {
+	c.Redirect(http.StatusSeeOther, requestsPagePath(role)+"?alarm="+url.QueryEscape(alarm))
+}
+
+// requestForActor находит запрос и проверяет, что текущий пользователь — его автор
+// или администратор. Возвращает автора и статус запроса.
+func requestForActor(c *gin.Context, db *sql.DB) (requestID, actorID int, role, status string, ok bool) {
+	userIDVal, _ := c.Get("user_id")
+	roleVal, _ := c.Get("role")
+	actorID, _ = userIDVal.(int)
+	role, _ = roleVal.(string)
+
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		redirectToRequests(c, role, "Неверный ID запроса")
+		return 0, 0, role, "", false
+	}
+	var ownerID int
+	err = db.QueryRow(`SELECT user_id, status FROM requests WHERE id = $1`, requestID).Scan(&ownerID, &status)
+	if err == sql.ErrNoRows || (err == nil && role != "admin" && ownerID != actorID) {
+		redirectToRequests(c, role, "Запрос не найден")
+		return 0, 0, role, "", false
+	}
+	if err != nil {
+		redirectToRequests(c, role, "Ошибка загрузки запроса: "+err.Error())
+		return 0, 0, role, "", false
+	}
+	return requestID, actorID, role, status, true
+}
+
+// AddRequestCommentHandler добавляет комментарий в обсуждение запроса. Писать могут
+// автор запроса и администраторы.
+func AddRequestCommentHandler(c *gin.Context, db *sql.DB) {
+	requestID, actorID, role, _, ok := requestForActor(c, db)
+	if !ok {
+		return
+	}
+	message := strings.TrimSpace(c.PostForm("message"))
+	if message == "" {
+		redirectToRequests(c, role, "Комментарий не может быть пустым")
+		return
+	}
+	if err := addRequestEvent(db, requestID, actorID, RequestEventCommented, message); err != nil {
+		redirectToRequests(c, role, "Ошибка сохранения комментария: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, requestsPagePath(role)+"#request-"+strconv.Itoa(requestID))
+}
+
+// WithdrawRequestHandler отзывает запрос, пока он не рассмотрен.
+func WithdrawRequestHandler(c *gin.Context, db *sql.DB) {
+	requestID, actorID, role, status, ok := requestForActor(c, db)
+	if !ok {
+		return
+	}
+	if status != "pending" {
+		redirectToRequests(c, role, "Отозвать можно только запрос на рассмотрении")
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		redirectToRequests(c, role, err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE requests SET status = 'withdrawn' WHERE id = $1 AND status = 'pending'`, requestID)
+	if err != nil {
+		redirectToRequests(c, role, "Ошибка отзыва запроса: "+err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		redirectToRequests(c, role, "Отозвать можно только запрос на рассмотрении")
+		return
+	}
+	if err := addRequestEvent(tx, requestID, actorID, RequestEventWithdrawn, strings.TrimSpace(c.PostForm("message"))); err != nil {
+		redirectToRequests(c, role, "Ошибка отзыва запроса: "+err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		redirectToRequests(c, role, "Ошибка отзыва запроса: "+err.Error())
+		return
+	}
+	redirectToRequests(c, role, "Запрос отозван")
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestsPerPage = 25
+
+// requestInboxFilter — фильтры входящих запросов администратора из строки запроса.
+type requestInboxFilter struct {
+	Status   string
+	Role     string
+	GroupID  string
+	DateFrom string
+	DateTo   string
+	Search   string
+	Page     int
+}
+
+// parseRequestInboxFilter читает фильтры. Без явного статуса показываются запросы
+// на рассмотрении; status=all снимает фильтр по статусу.
+func parseRequestInboxFilter(c *gin.Context) requestInboxFilter {
+	f := requestInboxFilter{
+		Status:   c.DefaultQuery("status", "pending"),
+		Role:     c.Query("role"),
+		GroupID:  c.Query("group"),
+		DateFrom: c.Query("date_from"),
+		DateTo:   c.Query("date_to"),
+		Search:   strings.TrimSpace(c.Query("q")),
+	}
+	f.Page, _ = strconv.Atoi(c.Query("page"))
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	return f
+}
+
+// values возвращает фильтры в виде параметров строки запроса (без номера страницы).
+func (f requestInboxFilter) values() url.Values {
+	v := url.Values{}
+	v.Set("status", f.Status)
+	for key, value := range map[string]string{
+		"role": f.Role, "group": f.GroupID, "date_from": f.DateFrom, "date_to": f.DateTo, "q": f.Search,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v
+}
+
+func (f requestInboxFilter) pageURL(page int) string {
+	v := f.values()
+	v.Set("page", strconv.Itoa(page))
+	return "/admin/requests?" + v.Encode()
+}
+
+// where строит условие выборки. Условия ссылаются только на requests r и users u,
+// поэтому подходят и для подсчёта, и для выборки страницы.
+func (f requestInboxFilter) where() (string, []interface{}) {
+	clauses := []string{}
+	args := []interface{}{}
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		clauses = append(clauses, fmt.Sprintf(clause, len(args)))
+	}
+
+	if f.Status != "all" {
+		add("r.status = $%d", f.Status)
+	}
+	if f.Role != "" {
+		add("u.role = $%d", f.Role)
+	}
+	if groupID, err := strconv.Atoi(f.GroupID); err == nil {
+		add("EXISTS (SELECT 1 FROM schedule_groups fg WHERE fg.schedule_id = r.schedule_id AND fg.group_id = $%d)", groupID)
+	}
+	if from, err := time.Parse(dateLayout, f.DateFrom); err == nil {
+		add("r.created_at >= $%d", from)
+	}
+	if to, err := time.Parse(dateLayout, f.DateTo); err == nil {
+		add("r.created_at < $%d", to.AddDate(0, 0, 1))
+	}
+	if f.Search != "" {
+		add("to_tsvector('russian', COALESCE(r.desired_change, '')) @@ plainto_tsquery('russian', $%d)", f.Search)
+	}
+	return joinClauses(clauses, " AND "), args
+}
+
+func renderAdminRequestsPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
+	f := parseRequestInboxFilter(c)
+	where, args := f.where()
+
+	countQuery := `SELECT COUNT(*) FROM requests r JOIN users u ON u.id = r.user_id`
+	if where != "" {
+		countQuery += " WHERE " + where
+	}
+	var total int
+	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		c.HTML(http.StatusInternalServerError, "requests_admin", gin.H{
+			"Title": "Запросы (Admin)",
+			"Error": err.Error(),
+		})
+		return
+	}
+	pages := (total + requestsPerPage - 1) / requestsPerPage
+	if pages == 0 {
+		pages = 1
+	}
+	if f.Page > pages {
+		f.Page = pages
+	}
+
+	order := fmt.Sprintf("r.created_at DESC, r.id DESC LIMIT %d OFFSET %d", requestsPerPage, (f.Page-1)*requestsPerPage)
+	requests, err := loadRequests(db, where, order, args...)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "requests_admin", gin.H{
+			"Title": "Запросы (Admin)",
+			"Error": err.Error(),
+		})
+		return
+	}
+	allGroups, err := loadAllGroups(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "requests_admin", gin.H{
+			"Title": "Запросы (Admin)",
+			"Error": "Ошибка загрузки групп: " + err.Error(),
+		})
+		return
+	}
+
+	var prevURL, nextURL string
+	if f.Page > 1 {
+		prevURL = f.pageURL(f.Page - 1)
+	}
+	if f.Page < pages {
+		nextURL = f.pageURL(f.Page + 1)
+	}
+	filters := f.values()
+	filters.Set("page", strconv.Itoa(f.Page))
+
+	c.HTML(status, "requests_admin", gin.H{
+		"Title":      "Запросы (Admin)",
+		"Requests":   requests,
+		"KindNames":  requestKindNames,
+		"EventNames": requestEventNames,
+		"AllGroups":  allGroups,
+		"Filter":     f,
+		"Filters":    filters.Encode(),
+		"Total":      total,
+		"Page":       f.Page,
+		"Pages":      pages,
+		"PrevURL":    prevURL,
+		"NextURL":    nextURL,
+		"Error":      errMsg,
+		"Alarm":      c.Query("alarm"),
+	})
+}
+
+func RenderAdminRequestsPage(c *gin.Context, db *sql.DB) {
+	renderAdminRequestsPage(c, db, http.StatusOK, "")
+}
+
+// redirectToInbox возвращает администратора во входящие с теми же фильтрами, которые
+// форма передала в поле filters, и сообщением alarm.
+func redirectToInbox(c *gin.Context, alarm string) {
+	v, err := url.ParseQuery(c.PostForm("filters"))
+	if err != nil {
+		v = url.Values{}
+	}
+	v.Set("alarm", alarm)
+	c.Redirect(http.StatusSeeOther, "/admin/requests?"+v.Encode())
+}
+
+// BulkProcessRequestsHandler одобряет или отклоняет отмеченные запросы. Каждый запрос
+// обрабатывается в своей транзакции: коллизия в одном не мешает остальным.
+func BulkProcessRequestsHandler(c *gin.Context, db *sql.DB, action string) {
+	status, ok := requestActionStatus(action)
+	if !ok {
+		renderAdminRequestsPage(c, db, http.StatusBadRequest, "Неверное действие")
+		return
+	}
+	ids := c.PostFormArray("request_id")
+	if len(ids) == 0 {
+		redirectToInbox(c, "Не выбрано ни одного запроса")
+		return
+	}
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	if status == "rejected" && reason == "" {
+		redirectToInbox(c, "Укажите причину отклонения")
+		return
+	}
+	adminIDVal, _ := c.Get("user_id")
+	adminID, _ := adminIDVal.(int)
+
+	done := 0
+	var failures []string
+	for _, idStr := range ids {
+		reqID, err := strconv.Atoi(idStr)
+		if err != nil {
+			failures = append(failures, "неверный ID "+idStr)
+			continue
+		}
+		if err := processRequest(db, reqID, adminID, status, reason); err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		done++
+	}
+
+	alarm := fmt.Sprintf("Обработано запросов: %d.", done)
+	if len(failures) > 0 {
+		alarm += " Не удалось: " + strings.Join(failures, "; ")
+	}
+	redirectToInbox(c, alarm)
+}
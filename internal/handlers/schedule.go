@@ -2,61 +2,165 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
-func CreateScheduleHandler(c *gin.Context, db *sql.DB) {
-	var body struct {
-		SubjectID   int       `json:"subject_id"`
-		TeacherID   int       `json:"teacher_id"`
-		ClassroomID int       `json:"classroom_id"`
-		StartTime   time.Time `json:"start_time"`
+// scheduleJSONBody — тело JSON-запросов создания и изменения занятия. Время задаётся
+// либо явно через start_time, либо датой и номером пары из расписания звонков.
+// capacity_override разрешает сохранить занятие, группы которого не помещаются в аудиторию.
+type scheduleJSONBody struct {
+	SubjectID        int       `json:"subject_id"`
+	TeacherID        int       `json:"teacher_id"`
+	ClassroomID      int       `json:"classroom_id"`
+	StartTime        time.Time `json:"start_time"`
+	Date             string    `json:"date"`
+	PairNumber       int       `json:"pair_number"`
+	DurationMinutes  int       `json:"duration_minutes"`
+	GroupIDs         []int     `json:"group_ids"`
+	CapacityOverride bool      `json:"capacity_override"`
+}
+
+// checkCapacityJSON проверяет вместимость аудитории для занятия из JSON-запроса и при
+// отказе сама отвечает клиенту; ok=false означает, что обработку нужно прервать.
+func checkCapacityJSON(c *gin.Context, db DBQuerier, body scheduleJSONBody, groupIDs []int) (warning string, ok bool) {
+	warning, err := checkCapacity(db, body.ClassroomID, groupIDs, body.CapacityOverride)
+	var ce *capacityError
+	if errors.As(err, &ce) {
+		ce.hint = " Передайте capacity_override: true, чтобы всё равно сохранить занятие."
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return warning, true
+}
+
+// checkGroupIDs проверяет список групп занятия из JSON-запроса так же, как
+// parseGroupIDsForm — форму: нужна хотя бы одна группа, повторы отбрасываются.
+func checkGroupIDs(groupIDs []int) ([]int, error) {
+	seen := make(map[int]bool)
+	var result []int
+	for _, id := range groupIDs {
+		if id <= 0 {
+			return nil, fmt.Errorf("Неверный ID группы: %d", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("не указаны группы")
+	}
+	return result, nil
+}
+
+// scheduleSavedJSON дополняет ответ о сохранении занятия предупреждением о вместимости.
+func scheduleSavedJSON(resp gin.H, warning string) gin.H {
+	if warning != "" {
+		resp["warning"] = strings.TrimSpace(warning)
+	}
+	return resp
+}
+
+func (b scheduleJSONBody) resolve(db DBQuerier) (time.Time, time.Time, error) {
+	var day time.Time
+	if b.PairNumber > 0 {
+		var err error
+		day, err = time.Parse(dateLayout, b.Date)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid date for pair_number: %v", err)
+		}
 	}
+	return ResolveLessonTime(db, b.ClassroomID, day, b.PairNumber, b.StartTime, b.DurationMinutes)
+}
+
+func CreateScheduleHandler(c *gin.Context, db *sql.DB) {
+	var body scheduleJSONBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
+	groupIDs, err := checkGroupIDs(body.GroupIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.GroupIDs = groupIDs
 
-	endTime := body.StartTime.Add(90 * time.Minute)
+	startTime, endTime, err := body.resolve(db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.StartTime = startTime
 
-	var collisionCount int
-	collisionQuery := `
-        SELECT COUNT(*) FROM schedule 
-        WHERE (teacher_id = $1 OR classroom_id = $2)
-          AND start_time < $3
-          AND end_time > $4
-    `
-	err := db.QueryRow(collisionQuery, body.TeacherID, body.ClassroomID, endTime, body.StartTime).Scan(&collisionCount)
+	dayOff, err := CheckNonTeachingDay(db, startTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки учебного календаря: " + err.Error()})
+		return
+	}
+	if dayOff != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": nonTeachingDayMessage(dayOff, startTime)})
+		return
+	}
+
+	collision, err := CheckScheduleCollisionForGroups(db, body.TeacherID, body.ClassroomID, body.GroupIDs, body.StartTime, endTime, 0)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки коллизий: " + err.Error()})
 		return
 	}
-	if collisionCount > 0 {
+	if collision {
 		c.JSON(http.StatusConflict, gin.H{"error": "Коллизия обнаружена: занятие пересекается с уже существующим."})
 		return
 	}
+	capacityWarning, ok := checkCapacityJSON(c, db, body, body.GroupIDs)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка начала транзакции: " + err.Error()})
+		return
+	}
+	defer tx.Rollback()
 
 	var scheduleID int
 	insertQuery := `
-        INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time)
-        VALUES ($1, $2, $3, $4, $5) RETURNING id
+        INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time, pair_number)
+        VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
     `
-	err = db.QueryRow(insertQuery, body.SubjectID, body.TeacherID, body.ClassroomID,
-		body.StartTime, endTime).Scan(&scheduleID)
+	err = tx.QueryRow(insertQuery, body.SubjectID, body.TeacherID, body.ClassroomID,
+		body.StartTime, endTime, nullableInt(body.PairNumber)).Scan(&scheduleID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании расписания: " + err.Error()})
 		return
 	}
+	if err := setScheduleGroups(tx, scheduleID, body.GroupIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания связи с группой: " + err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании расписания: " + err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, scheduleSavedJSON(gin.H{
 		"message":     "Schedule created",
 		"schedule_id": scheduleID,
-	})
+	}, capacityWarning))
 }
 
 func UpdateScheduleHandler(c *gin.Context, db *sql.DB) {
@@ -66,50 +170,167 @@ func UpdateScheduleHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	var body struct {
-		SubjectID   int       `json:"subject_id"`
-		TeacherID   int       `json:"teacher_id"`
-		ClassroomID int       `json:"classroom_id"`
-		StartTime   time.Time `json:"start_time"`
-	}
+	var body scheduleJSONBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
+	if body.GroupIDs != nil {
+		groupIDs, err := checkGroupIDs(body.GroupIDs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		body.GroupIDs = groupIDs
+	}
 
-	endTime := body.StartTime.Add(90 * time.Minute)
+	startTime, endTime, err := body.resolve(db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body.StartTime = startTime
 
-	var collisionCount int
-	collisionQuery := `
-        SELECT COUNT(*) FROM schedule 
-        WHERE id <> $1
-          AND (teacher_id = $2 OR classroom_id = $3)
-          AND start_time < $4
-          AND end_time > $5
-    `
-	err = db.QueryRow(collisionQuery, scheduleID, body.TeacherID, body.ClassroomID, endTime, body.StartTime).Scan(&collisionCount)
+	dayOff, err := CheckNonTeachingDay(db, startTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки учебного календаря: " + err.Error()})
+		return
+	}
+	if dayOff != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": nonTeachingDayMessage(dayOff, startTime)})
+		return
+	}
+
+	groupIDs := body.GroupIDs
+	if groupIDs == nil {
+		lesson, err := loadRequestLesson(db, scheduleID)
+		if isProposalError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		groupIDs = lesson.GroupIDs
+	}
+	collision, err := CheckScheduleCollisionForGroups(db, body.TeacherID, body.ClassroomID, groupIDs, body.StartTime, endTime, scheduleID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки коллизий: " + err.Error()})
 		return
 	}
-	if collisionCount > 0 {
+	if collision {
 		c.JSON(http.StatusConflict, gin.H{"error": "Коллизия обнаружена: занятие пересекается с уже существующим."})
 		return
 	}
+	capacityWarning, ok := checkCapacityJSON(c, db, body, groupIDs)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка начала транзакции: " + err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Как и форма, API правит одно занятие: оно уходит из серии, и следующее изменение
+	// всей серии его не перезапишет.
+	if err := detachFromSeries(tx, scheduleID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отвязки занятия от серии: " + err.Error()})
+		return
+	}
 
 	updateQuery := `
         UPDATE schedule
-        SET subject_id=$1, teacher_id=$2, classroom_id=$3, start_time=$4, end_time=$5
-        WHERE id=$6
+        SET subject_id=$1, teacher_id=$2, classroom_id=$3, start_time=$4, end_time=$5, pair_number=$6
+        WHERE id=$7
     `
-	_, err = db.Exec(updateQuery, body.SubjectID, body.TeacherID, body.ClassroomID,
-		body.StartTime, endTime, scheduleID)
+	res, err := tx.Exec(updateQuery, body.SubjectID, body.TeacherID, body.ClassroomID,
+		body.StartTime, endTime, nullableInt(body.PairNumber), scheduleID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления расписания: " + err.Error()})
 		return
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	// Без group_ids набор групп занятия не меняется.
+	if body.GroupIDs != nil {
+		if err := setScheduleGroups(tx, scheduleID, body.GroupIDs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления групп: " + err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления расписания: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduleSavedJSON(gin.H{"message": "Schedule updated"}, capacityWarning))
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Schedule updated"})
+// parseGroupIDsForm читает список групп занятия: форма отправляет по одному полю group_id
+// на каждую выбранную группу (поток из нескольких групп на одной лекции).
+func parseGroupIDsForm(c *gin.Context) ([]int, error) {
+	seen := make(map[int]bool)
+	var groupIDs []int
+	for _, v := range c.PostFormArray("group_id") {
+		if v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("Неверный ID группы: %s", v)
+		}
+		if !seen[id] {
+			seen[id] = true
+			groupIDs = append(groupIDs, id)
+		}
+	}
+	if len(groupIDs) == 0 {
+		return nil, fmt.Errorf("Выберите хотя бы одну группу")
+	}
+	return groupIDs, nil
+}
+
+// setScheduleGroups заменяет набор групп занятия. Снятые группы запоминаются в
+// dropped_lesson_groups, чтобы их ленты iCalendar показали занятие отменённым, а при
+// любом изменении состава повышается редакция занятия: триггер schedule_revision
+// срабатывает только на UPDATE schedule.
+func setScheduleGroups(db DBQuerier, scheduleID int, groupIDs []int) error {
+	res, err := db.Exec(`
+        WITH removed AS (
+            DELETE FROM schedule_groups WHERE schedule_id = $1 AND NOT (group_id = ANY($2)) RETURNING group_id
+        )
+        INSERT INTO dropped_lesson_groups (schedule_id, group_id)
+        SELECT $1, group_id FROM removed
+        ON CONFLICT DO NOTHING
+    `, scheduleID, pq.Array(groupIDs))
+	if err != nil {
+		return err
+	}
+	changed, _ := res.RowsAffected()
+	for _, groupID := range groupIDs {
+		res, err := db.Exec(`INSERT INTO schedule_groups (schedule_id, group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			scheduleID, groupID)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		changed += n
+	}
+	if changed == 0 {
+		return nil
+	}
+	if _, err := db.Exec(`DELETE FROM dropped_lesson_groups WHERE schedule_id = $1 AND group_id = ANY($2)`,
+		scheduleID, pq.Array(groupIDs)); err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE schedule SET revision = revision + 1 WHERE id = $1`, scheduleID)
+	return err
 }
 
 func UpdateScheduleFormHandler(c *gin.Context, db *sql.DB) {
@@ -118,21 +339,18 @@ func UpdateScheduleFormHandler(c *gin.Context, db *sql.DB) {
 	subjectID, _ := strconv.Atoi(c.PostForm("subject_id"))
 	teacherID, _ := strconv.Atoi(c.PostForm("teacher_id"))
 	classroomID, _ := strconv.Atoi(c.PostForm("classroom_id"))
-	groupID, _ := strconv.Atoi(c.PostForm("group_id"))
-	startTimeStr := c.PostForm("start_time")
-	if startTimeStr == "" {
-		c.Set("Alarm", "Поле времени начала не заполнено.")
+	groupIDs, err := parseGroupIDsForm(c)
+	if err != nil {
+		c.Set("Alarm", err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
-	layout := "2006-01-02T15:04"
-	startTime, err := time.Parse(layout, startTimeStr)
+	startTime, endTime, pairNumber, err := parseLessonTimeForm(c, db, classroomID)
 	if err != nil {
-		c.Set("Alarm", "Неверный формат времени начала: "+err.Error())
+		c.Set("Alarm", err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
-	endTime := startTime.Add(90 * time.Minute)
 
 	idInt, err := strconv.Atoi(scheduleID)
 	if err != nil {
@@ -141,29 +359,89 @@ func UpdateScheduleFormHandler(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	collision, err := CheckScheduleCollision(db, teacherID, classroomID, groupID, startTime, endTime, idInt)
+	capacityWarning, err := checkCapacityForm(c, db, classroomID, groupIDs)
+	if err != nil {
+		c.Set("Alarm", err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	if c.PostForm("scope") == "series" {
+		conflicts, err := updateLessonSeriesFromOccurrence(db, idInt, subjectID, teacherID, classroomID, groupIDs, pairNumber, startTime)
+		if err != nil {
+			c.Set("Alarm", "Ошибка обновления серии: "+err.Error())
+			RenderAdminSchedulesPageWithFilters(c, db)
+			return
+		}
+		if len(conflicts) > 0 {
+			c.Set("Alarm", "Коллизия или неучебный день на даты "+formatDates(conflicts)+". Серия не изменена.")
+			RenderAdminSchedulesPageWithFilters(c, db)
+			return
+		}
+		c.Set("Alarm", "Серия занятий успешно обновлена."+capacityWarning)
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	dayOff, err := CheckNonTeachingDay(db, startTime)
+	if err != nil {
+		c.Set("Alarm", "Ошибка проверки учебного календаря: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if dayOff != nil {
+		c.Set("Alarm", "Нельзя перенести занятие: "+nonTeachingDayMessage(dayOff, startTime)+".")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	collision, err := CheckScheduleCollisionForGroups(db, teacherID, classroomID, groupIDs, startTime, endTime, idInt)
 	if err != nil {
 		c.Set("Alarm", "Ошибка проверки коллизий: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
 	if collision {
-		c.Set("Alarm", "Коллизия обнаружена: у преподавателя, в аудитории или у группы уже существует пересекающееся занятие.")
+		c.Set("Alarm", "Коллизия обнаружена: у преподавателя, в аудитории или у одной из групп уже существует пересекающееся занятие, либо преподаватель недоступен в это время.")
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
 
-	_, err = db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		c.Set("Alarm", "Ошибка начала транзакции: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := detachFromSeries(tx, idInt); err != nil {
+		c.Set("Alarm", "Ошибка отвязки занятия от серии: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	_, err = tx.Exec(`
         UPDATE schedule
-        SET subject_id=$1, teacher_id=$2, classroom_id=$3, start_time=$4, end_time=$5
-        WHERE id=$6
-    `, subjectID, teacherID, classroomID, startTime, endTime, scheduleID)
+        SET subject_id=$1, teacher_id=$2, classroom_id=$3, start_time=$4, end_time=$5, pair_number=$6
+        WHERE id=$7
+    `, subjectID, teacherID, classroomID, startTime, endTime, nullableInt(pairNumber), idInt)
 	if err != nil {
 		c.Set("Alarm", "Ошибка обновления расписания: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
-	c.Set("Alarm", "Расписание успешно обновлено.")
+	if err := setScheduleGroups(tx, idInt, groupIDs); err != nil {
+		c.Set("Alarm", "Ошибка обновления групп занятия: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.Set("Alarm", "Ошибка обновления расписания: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	c.Set("Alarm", "Расписание успешно обновлено."+capacityWarning)
 	RenderAdminSchedulesPageWithFilters(c, db)
 }
 
@@ -171,65 +449,89 @@ func CreateScheduleFormHandler(c *gin.Context, db *sql.DB) {
 	subjectID, err1 := strconv.Atoi(c.PostForm("subject_id"))
 	teacherID, err2 := strconv.Atoi(c.PostForm("teacher_id"))
 	classroomID, err3 := strconv.Atoi(c.PostForm("classroom_id"))
-	groupID, err4 := strconv.Atoi(c.PostForm("group_id"))
-	startTimeStr := c.PostForm("start_time")
 
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || startTimeStr == "" {
+	if err1 != nil || err2 != nil || err3 != nil {
 		c.Set("Alarm", "Неверные данные формы")
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
+	groupIDs, err := parseGroupIDsForm(c)
+	if err != nil {
+		c.Set("Alarm", err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
 
-	layout := "2006-01-02T15:04"
-	startTime, err := time.Parse(layout, startTimeStr)
+	startTime, endTime, pairNumber, err := parseLessonTimeForm(c, db, classroomID)
 	if err != nil {
-		c.Set("Alarm", "Неверный формат времени начала: "+err.Error())
+		c.Set("Alarm", err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
-	endTime := startTime.Add(90 * time.Minute)
 
-	collision, err := CheckScheduleCollision(db, teacherID, classroomID, groupID, startTime, endTime, 0)
+	dayOff, err := CheckNonTeachingDay(db, startTime)
+	if err != nil {
+		c.Set("Alarm", "Ошибка проверки учебного календаря: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if dayOff != nil {
+		c.Set("Alarm", "Нельзя создать занятие: "+nonTeachingDayMessage(dayOff, startTime)+".")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	collision, err := CheckScheduleCollisionForGroups(db, teacherID, classroomID, groupIDs, startTime, endTime, 0)
 	if err != nil {
 		c.Set("Alarm", "Ошибка проверки коллизий: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
 	if collision {
-		c.Set("Alarm", "Коллизия обнаружена: у преподавателя, в аудитории или у группы уже существует пересекающееся занятие.")
+		c.Set("Alarm", "Коллизия обнаружена: у преподавателя, в аудитории или у одной из групп уже существует пересекающееся занятие, либо преподаватель недоступен в это время.")
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
 
-	insertQuery := `
-        INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time)
-        VALUES ($1, $2, $3, $4, $5) RETURNING id
-    `
-	stmt, err := db.Prepare(insertQuery)
+	capacityWarning, err := checkCapacityForm(c, db, classroomID, groupIDs)
+	if err != nil {
+		c.Set("Alarm", err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
-		log.Printf("ERROR: Не удалось подготовить запрос: %v", err)
-		c.Set("Alarm", "Ошибка подготовки запроса: "+err.Error())
+		c.Set("Alarm", "Ошибка начала транзакции: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
 	var scheduleID int
-	err = stmt.QueryRow(subjectID, teacherID, classroomID, startTime, endTime).Scan(&scheduleID)
+	err = tx.QueryRow(`
+        INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time, pair_number)
+        VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+    `, subjectID, teacherID, classroomID, startTime, endTime, nullableInt(pairNumber)).Scan(&scheduleID)
 	if err != nil {
+		log.Printf("ERROR: Не удалось создать занятие: %v", err)
 		c.Set("Alarm", "Ошибка при создании записи: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
 
-	_, err = db.Exec(`INSERT INTO schedule_groups (schedule_id, group_id) VALUES ($1, $2)`, scheduleID, groupID)
-	if err != nil {
+	if err := setScheduleGroups(tx, scheduleID, groupIDs); err != nil {
 		c.Set("Alarm", "Ошибка создания связи с группой: "+err.Error())
 		RenderAdminSchedulesPageWithFilters(c, db)
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		c.Set("Alarm", "Ошибка при создании записи: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
 
-	c.Set("Alarm", "Занятие успешно создано.")
+	c.Set("Alarm", "Занятие успешно создано."+capacityWarning)
 	RenderAdminSchedulesPageWithFilters(c, db)
 }
 
@@ -243,12 +545,16 @@ func GetScheduleJSON(c *gin.Context, db *sql.DB) {
 			s.teacher_id,
 			s.classroom_id,
 			s.start_time,
-			COALESCE(MIN(g.id), 0) AS group_id
+			s.end_time,
+			COALESCE(s.pair_number, 0) AS pair_number,
+			COALESCE(MIN(g.id), 0) AS group_id,
+			COALESCE(array_agg(g.id ORDER BY g.id) FILTER (WHERE g.id IS NOT NULL), '{}') AS group_ids,
+			COALESCE(s.series_id, 0) AS series_id
 		FROM schedule s
 		LEFT JOIN schedule_groups sg ON s.id = sg.schedule_id
 		LEFT JOIN groups g ON g.id = sg.group_id
 		WHERE s.id = $1
-		GROUP BY s.id, s.subject_id, s.teacher_id, s.classroom_id, s.start_time
+		GROUP BY s.id, s.subject_id, s.teacher_id, s.classroom_id, s.start_time, s.end_time, s.pair_number, s.series_id
 	`
 	row := db.QueryRow(query, scheduleID)
 
@@ -258,9 +564,14 @@ func GetScheduleJSON(c *gin.Context, db *sql.DB) {
 		TeacherID   int       `json:"teacher_id"`
 		ClassroomID int       `json:"classroom_id"`
 		GroupID     int       `json:"group_id"`
+		GroupIDs    []int64   `json:"group_ids"`
+		SeriesID    int       `json:"series_id"`
+		PairNumber  int       `json:"pair_number"`
 		StartTime   time.Time `json:"start_time"`
+		EndTime     time.Time `json:"end_time"`
 	}
-	err := row.Scan(&obj.ID, &obj.SubjectID, &obj.TeacherID, &obj.ClassroomID, &obj.StartTime, &obj.GroupID)
+	err := row.Scan(&obj.ID, &obj.SubjectID, &obj.TeacherID, &obj.ClassroomID, &obj.StartTime, &obj.EndTime,
+		&obj.PairNumber, &obj.GroupID, pq.Array(&obj.GroupIDs), &obj.SeriesID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"scheduleApp/internal/models"
+)
+
+// scheduleViewSelect — общий запрос занятий для страниц расписания студента
+// и преподавателя и для лент iCalendar; условие отбора подставляет вызывающий.
+const scheduleViewSelect = `
+		SELECT 
+			s.id,
+			sub.name AS subject_name,
+			s.subject_id,
+			t.name AS teacher_name,
+			s.teacher_id,
+			c.room_number,
+			COALESCE(c.building, '') AS building,
+			s.classroom_id,
+			s.start_time,
+			s.end_time,
+			s.created_at,
+			s.revision,
+			COALESCE(string_agg(g.name, ', ' ORDER BY g.name), '') AS group_names,
+			COALESCE(MIN(g.id), 0) AS group_id
+		FROM schedule s
+		JOIN subjects sub ON s.subject_id = sub.id
+		JOIN teachers t ON s.teacher_id = t.id
+		JOIN classrooms c ON s.classroom_id = c.id
+		LEFT JOIN schedule_groups sg ON s.id = sg.schedule_id
+		LEFT JOIN groups g ON sg.group_id = g.id
+	`
+
+const scheduleViewGroupBy = `
+		GROUP BY s.id, sub.name, s.subject_id, t.name, s.teacher_id, c.room_number, c.building, s.classroom_id, s.start_time, s.end_time, s.created_at
+		ORDER BY s.start_time ASC
+	`
+
+// loadScheduleView выполняет scheduleViewSelect с условием whereClause (вместе с WHERE).
+func loadScheduleView(db DBQuerier, whereClause string, args ...interface{}) ([]models.ScheduleDisplay, error) {
+	rows, err := db.Query(scheduleViewSelect+" "+whereClause+" "+scheduleViewGroupBy, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lessons []models.ScheduleDisplay
+	for rows.Next() {
+		var sch models.ScheduleDisplay
+		err := rows.Scan(&sch.ID, &sch.SubjectName, &sch.SubjectID, &sch.TeacherName, &sch.TeacherID,
+			&sch.RoomNumber, &sch.Building, &sch.ClassroomID, &sch.StartTime, &sch.EndTime, &sch.CreatedAt,
+			&sch.Revision, &sch.GroupNames, &sch.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, sch)
+	}
+	return lessons, rows.Err()
+}
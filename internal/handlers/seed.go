@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SeedData — справочники для начального заполнения базы. Ключи файла совпадают
+// с полями JSON API: {"groups": [{"name": "ИВТ-21", "course": "2"}], ...}.
+type SeedData struct {
+	Departments []models.Department `json:"departments"`
+	Groups      []models.Group      `json:"groups"`
+	Subjects    []models.Subject    `json:"subjects"`
+	Classrooms  []models.Classroom  `json:"classrooms"`
+	Bells       []models.BellPeriod `json:"bells"`
+}
+
+// SeedReport — сколько записей каждого справочника добавлено и обновлено.
+type SeedReport struct {
+	Created map[string]int
+	Updated map[string]int
+}
+
+// ParseSeedFile читает справочники из YAML (.yaml, .yml) или JSON (.json).
+func ParseSeedFile(name string, r io.Reader) (SeedData, error) {
+	var data SeedData
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return data, err
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+	case ".yaml", ".yml":
+		// YAML переводится в JSON, чтобы у обоих форматов были одни и те же ключи.
+		var doc interface{}
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return data, fmt.Errorf("ошибка разбора YAML: %v", err)
+		}
+		if raw, err = json.Marshal(doc); err != nil {
+			return data, fmt.Errorf("ошибка разбора YAML: %v", err)
+		}
+	default:
+		return data, fmt.Errorf("неподдерживаемый формат файла %q: ожидается .yaml, .yml или .json", name)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&data); err != nil {
+		return data, fmt.Errorf("ошибка разбора файла: %v", err)
+	}
+	return data, nil
+}
+
+// SeedReferenceData добавляет справочники в одной транзакции. Записи сопоставляются
+// по названию (аудитории — по номеру и корпусу, пары — по корпусу и номеру), поэтому
+// повторный запуск обновляет существующие записи, а не создаёт дубликаты.
+func SeedReferenceData(db *sql.DB, data SeedData) (SeedReport, error) {
+	report := SeedReport{Created: map[string]int{}, Updated: map[string]int{}}
+	count := func(kind string, existing bool) {
+		if existing {
+			report.Updated[kind]++
+		} else {
+			report.Created[kind]++
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	for _, d := range data.Departments {
+		name := strings.TrimSpace(d.Name)
+		if err := checkLength(name, "название кафедры", 255, true); err != nil {
+			return report, err
+		}
+		res, err := tx.Exec(`INSERT INTO departments (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+		if err != nil {
+			return report, err
+		}
+		n, _ := res.RowsAffected()
+		count("кафедры", n == 0)
+	}
+	for _, g := range data.Groups {
+		if g.ID, err = findIDByName(tx, "groups", g.Name); err != nil {
+			return report, err
+		}
+		existing := g.ID != 0
+		if err := saveGroup(tx, &g); err != nil {
+			return report, err
+		}
+		count("группы", existing)
+	}
+	for _, s := range data.Subjects {
+		if s.ID, err = findIDByName(tx, "subjects", s.Name); err != nil {
+			return report, err
+		}
+		existing := s.ID != 0
+		if err := saveSubject(tx, &s); err != nil {
+			return report, err
+		}
+		count("предметы", existing)
+	}
+	for _, cl := range data.Classrooms {
+		err := tx.QueryRow(`
+            SELECT id FROM classrooms
+            WHERE lower(room_number) = lower($1) AND lower(COALESCE(building, '')) = lower($2)
+        `, strings.TrimSpace(cl.RoomNumber), strings.TrimSpace(cl.Building)).Scan(&cl.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return report, err
+		}
+		existing := cl.ID != 0
+		if err := saveClassroom(tx, &cl); err != nil {
+			return report, err
+		}
+		count("аудитории", existing)
+	}
+	for _, b := range data.Bells {
+		existing, err := seedBellPeriod(tx, b)
+		if err != nil {
+			return report, err
+		}
+		count("пары", existing)
+	}
+	return report, tx.Commit()
+}
+
+func seedBellPeriod(db DBQuerier, b models.BellPeriod) (bool, error) {
+	building := strings.TrimSpace(b.Building)
+	if b.PairNumber <= 0 {
+		return false, fmt.Errorf("неверный номер пары: %d", b.PairNumber)
+	}
+	start, err1 := time.Parse("15:04", b.StartClock)
+	end, err2 := time.Parse("15:04", b.EndClock)
+	if err1 != nil || err2 != nil {
+		return false, fmt.Errorf("пара %d: неверное время начала или окончания (ожидается ЧЧ:ММ)", b.PairNumber)
+	}
+	if !end.After(start) {
+		return false, fmt.Errorf("пара %d: окончание должно быть позже начала", b.PairNumber)
+	}
+
+	var existing bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM bell_schedule WHERE building = $1 AND pair_number = $2)`,
+		building, b.PairNumber).Scan(&existing)
+	if err != nil {
+		return false, err
+	}
+	_, err = db.Exec(`
+        INSERT INTO bell_schedule (pair_number, building, start_clock, end_clock)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (building, pair_number) DO UPDATE
+        SET start_clock = EXCLUDED.start_clock, end_clock = EXCLUDED.end_clock
+    `, b.PairNumber, building, b.StartClock, b.EndClock)
+	return existing, err
+}
+
+// DemoPassword — пароль всех демонстрационных учётных записей.
+const DemoPassword = "demo"
+
+// demoData — справочники демонстрационной базы. Предметы ведут
+// преподаватели demoTeachers с тем же индексом.
+var demoData = SeedData{
+	Departments: []models.Department{{Name: "Кафедра информатики"}, {Name: "Кафедра математики"}},
+	Groups: []models.Group{
+		{Name: "ИВТ-21", Course: "2"},
+		{Name: "ИВТ-22", Course: "2"},
+		{Name: "ПМИ-31", Course: "3"},
+	},
+	Subjects: []models.Subject{
+		{Name: "Программирование", Description: "Основы программирования на Go"},
+		{Name: "Базы данных", Description: "Реляционные СУБД и SQL"},
+		{Name: "Математический анализ"},
+		{Name: "Линейная алгебра"},
+	},
+	Classrooms: []models.Classroom{
+		{RoomNumber: "101", Building: "Главный", Capacity: 30},
+		{RoomNumber: "102", Building: "Главный", Capacity: 30},
+		{RoomNumber: "201", Building: "Главный", Capacity: 60},
+	},
+	Bells: []models.BellPeriod{
+		{PairNumber: 1, StartClock: "09:00", EndClock: "10:30"},
+		{PairNumber: 2, StartClock: "10:40", EndClock: "12:10"},
+		{PairNumber: 3, StartClock: "12:40", EndClock: "14:10"},
+		{PairNumber: 4, StartClock: "14:20", EndClock: "15:50"},
+	},
+}
+
+var demoTeachers = []NewAccount{
+	{Username: "teacher1", Name: "Иванов Иван Иванович", Department: "Кафедра информатики"},
+	{Username: "teacher2", Name: "Петрова Анна Сергеевна", Department: "Кафедра информатики"},
+	{Username: "teacher3", Name: "Сидоров Пётр Алексеевич", Department: "Кафедра математики"},
+	{Username: "teacher4", Name: "Кузнецова Мария Олеговна", Department: "Кафедра математики"},
+}
+
+// DemoReport — что создала команда demo.
+type DemoReport struct {
+	Reference SeedReport
+	Accounts  []string
+	Lessons   int
+}
+
+// SeedDemoData наполняет базу для локальной разработки: справочники, администратора
+// admin, преподавателей teacher1..4, по два студента в группе (все с паролем DemoPassword),
+// учебный план и занятия на неделю weekStart (понедельник). Существующие учётные записи
+// не меняются; занятия не создаются, если на этой неделе расписание уже заполнено.
+func SeedDemoData(db *sql.DB, weekStart time.Time) (DemoReport, error) {
+	var report DemoReport
+	var err error
+	if report.Reference, err = SeedReferenceData(db, demoData); err != nil {
+		return report, err
+	}
+
+	accounts := []NewAccount{{Username: "admin", Role: "admin", Name: "Администратор"}}
+	for _, t := range demoTeachers {
+		t.Role = "teacher"
+		accounts = append(accounts, t)
+	}
+	for i, g := range demoData.Groups {
+		for j := 1; j <= 2; j++ {
+			accounts = append(accounts, NewAccount{
+				Username: fmt.Sprintf("student%d", i*2+j),
+				Role:     "student",
+				Name:     fmt.Sprintf("Студент %d группы %s", j, g.Name),
+				Group:    g.Name,
+			})
+		}
+	}
+	for _, a := range accounts {
+		var exists bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, a.Username).Scan(&exists); err != nil {
+			return report, err
+		}
+		if exists {
+			continue
+		}
+		a.Password = DemoPassword
+		if _, err := CreateAccount(db, a); err != nil {
+			return report, err
+		}
+		report.Accounts = append(report.Accounts, a.Username)
+	}
+
+	ids := func(table, column string, names []string) ([]int, error) {
+		result := make([]int, len(names))
+		for i, name := range names {
+			err := db.QueryRow(`SELECT id FROM `+table+` WHERE `+column+` = $1 ORDER BY id LIMIT 1`, name).Scan(&result[i])
+			if err != nil {
+				return nil, fmt.Errorf("%s %q: %v", table, name, err)
+			}
+		}
+		return result, nil
+	}
+	var groupNames, subjectNames, roomNumbers, teacherUsers []string
+	for _, g := range demoData.Groups {
+		groupNames = append(groupNames, g.Name)
+	}
+	for _, s := range demoData.Subjects {
+		subjectNames = append(subjectNames, s.Name)
+	}
+	for _, cl := range demoData.Classrooms {
+		roomNumbers = append(roomNumbers, cl.RoomNumber)
+	}
+	for _, t := range demoTeachers {
+		teacherUsers = append(teacherUsers, t.Username)
+	}
+	groupIDs, err := ids("groups", "name", groupNames)
+	if err != nil {
+		return report, err
+	}
+	subjectIDs, err := ids("subjects", "name", subjectNames)
+	if err != nil {
+		return report, err
+	}
+	classroomIDs, err := ids("classrooms", "room_number", roomNumbers)
+	if err != nil {
+		return report, err
+	}
+	teacherIDs, err := ids("teachers t JOIN users u ON u.id = t.user_id", "u.username", teacherUsers)
+	if err != nil {
+		return report, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	for _, groupID := range groupIDs {
+		for i, subjectID := range subjectIDs {
+			_, err := tx.Exec(`
+                INSERT INTO curriculum (group_id, subject_id, teacher_id, hours_per_week)
+                VALUES ($1, $2, $3, 4)
+                ON CONFLICT (group_id, subject_id, teacher_id) DO NOTHING
+            `, groupID, subjectID, teacherIDs[i])
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	var busy bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schedule WHERE start_time >= $1 AND start_time < $2)`,
+		weekStart, weekStart.AddDate(0, 0, 7)).Scan(&busy)
+	if err != nil {
+		return report, err
+	}
+	if !busy {
+		// Группа g на паре p дня d занимается предметом (g+d+p) mod 4 в своей аудитории:
+		// в одной паре у групп разные предметы, а значит и разные преподаватели.
+		for day := 0; day < 5; day++ {
+			date := weekStart.AddDate(0, 0, day)
+			for _, bell := range demoData.Bells[:3] {
+				for g, groupID := range groupIDs {
+					k := (g + day + bell.PairNumber) % len(subjectIDs)
+					var scheduleID int
+					err := tx.QueryRow(`
+                        INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time, pair_number)
+                        VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+                    `, subjectIDs[k], teacherIDs[k], classroomIDs[g], atClock(date, bell.StartClock),
+						atClock(date, bell.EndClock), bell.PairNumber).Scan(&scheduleID)
+					if err != nil {
+						return report, err
+					}
+					if _, err := tx.Exec(`INSERT INTO schedule_groups (schedule_id, group_id) VALUES ($1, $2)`, scheduleID, groupID); err != nil {
+						return report, err
+					}
+					report.Lessons++
+				}
+			}
+		}
+	}
+	return report, tx.Commit()
+}
@@ -0,0 +1,592 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const dateLayout = "2006-01-02"
+
+// ExpandLessonSeries разворачивает серию в список дат и времени начала занятий:
+// от start_date до end_date включительно с шагом interval_weeks недель, пропуская исключения.
+// Если у серии задана чётность или номера недель, берутся только подходящие учебные недели,
+// отсчитываемые от начала семестра (TermStart, а при его отсутствии — от start_date).
+// Дни, на которые по учебному календарю приходятся праздники, каникулы или сессия, пропускаются;
+// занятия дня, для которого задан перенос рабочего дня, переносятся на дату переноса.
+func ExpandLessonSeries(s models.LessonSeries) []time.Time {
+	clock, err := time.Parse("15:04", s.StartClock)
+	if err != nil {
+		return nil
+	}
+	interval := s.IntervalWeeks
+	if interval < 1 {
+		interval = 1
+	}
+
+	skip := make(map[string]bool, len(s.Exceptions))
+	for _, e := range s.Exceptions {
+		skip[e.Format(dateLayout)] = true
+	}
+
+	termStart := s.TermStart
+	if termStart.IsZero() {
+		termStart = s.StartDate
+	}
+	weekFiltered := (s.WeekParity != "" && s.WeekParity != calendar.ParityAny) || len(s.WeekNumbers) > 0
+
+	var result []time.Time
+	end := dateOnly(s.EndDate)
+	for d := dateOnly(s.StartDate); !d.After(end); d = d.AddDate(0, 0, 7*interval) {
+		if skip[d.Format(dateLayout)] {
+			continue
+		}
+		if weekFiltered && !calendar.MatchesWeek(calendar.WeekNumber(termStart, d), s.WeekParity, s.WeekNumbers) {
+			continue
+		}
+		day := d
+		if target, ok := calendar.TransferTarget(s.Calendar, d); ok {
+			if skip[target.Format(dateLayout)] {
+				continue
+			}
+			day = target
+		} else if calendar.NonTeachingPeriod(s.Calendar, d) != nil {
+			continue
+		}
+		result = append(result, time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return result
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekNumbersArg превращает пустой список недель в NULL, чтобы «без ограничений» хранилось единообразно.
+func weekNumbersArg(weeks []int) interface{} {
+	if len(weeks) == 0 {
+		return nil
+	}
+	return pq.Array(weeks)
+}
+
+func formatDates(dates []time.Time) string {
+	parts := make([]string, 0, len(dates))
+	for _, d := range dates {
+		parts = append(parts, d.Format("02.01.2006"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseLessonSeriesForm(c *gin.Context) (models.LessonSeries, error) {
+	var s models.LessonSeries
+	var err1, err2, err3 error
+	s.SubjectID, err1 = strconv.Atoi(c.PostForm("subject_id"))
+	s.TeacherID, err2 = strconv.Atoi(c.PostForm("teacher_id"))
+	s.ClassroomID, err3 = strconv.Atoi(c.PostForm("classroom_id"))
+	if err1 != nil || err2 != nil || err3 != nil {
+		return s, fmt.Errorf("Неверные данные формы")
+	}
+
+	var err error
+	s.GroupIDs, err = parseGroupIDsForm(c)
+	if err != nil {
+		return s, err
+	}
+	s.StartDate, err = time.Parse(dateLayout, c.PostForm("start_date"))
+	if err != nil {
+		return s, fmt.Errorf("Неверная дата начала серии")
+	}
+	s.EndDate, err = time.Parse(dateLayout, c.PostForm("end_date"))
+	if err != nil {
+		return s, fmt.Errorf("Неверная дата окончания серии")
+	}
+	if s.EndDate.Before(s.StartDate) {
+		return s, fmt.Errorf("Дата окончания серии раньше даты начала")
+	}
+
+	if v := c.PostForm("pair_number"); v != "" {
+		s.PairNumber, err = strconv.Atoi(v)
+		if err != nil || s.PairNumber <= 0 {
+			return s, fmt.Errorf("Неверный номер пары")
+		}
+	} else {
+		s.StartClock = c.PostForm("start_clock")
+		if _, err := time.Parse("15:04", s.StartClock); err != nil {
+			return s, fmt.Errorf("Укажите пару или время начала занятия")
+		}
+	}
+	if v := c.PostForm("duration_minutes"); v != "" {
+		s.DurationMinutes, err = strconv.Atoi(v)
+		if err != nil || s.DurationMinutes <= 0 || s.DurationMinutes > maxLessonDurationMinutes {
+			return s, fmt.Errorf("Неверная длительность занятия")
+		}
+	}
+
+	s.IntervalWeeks, err = strconv.Atoi(c.DefaultPostForm("interval_weeks", "1"))
+	if err != nil || (s.IntervalWeeks != 1 && s.IntervalWeeks != 2) {
+		return s, fmt.Errorf("Периодичность может быть только еженедельной или раз в две недели")
+	}
+
+	s.WeekParity = c.DefaultPostForm("week_parity", calendar.ParityAny)
+	if s.WeekParity != calendar.ParityAny && s.WeekParity != calendar.ParityOdd && s.WeekParity != calendar.ParityEven {
+		return s, fmt.Errorf("Неверная чётность недели")
+	}
+	s.WeekNumbers, err = calendar.ParseWeekNumbers(c.PostForm("week_numbers"))
+	if err != nil {
+		return s, fmt.Errorf("Неверный список недель: %v", err)
+	}
+	if s.IntervalWeeks != 1 && (s.WeekParity != calendar.ParityAny || len(s.WeekNumbers) > 0) {
+		return s, fmt.Errorf("Чётность и номера недель задаются только для еженедельной серии")
+	}
+
+	for _, field := range strings.FieldsFunc(c.PostForm("exceptions"), func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		d, err := time.Parse(dateLayout, field)
+		if err != nil {
+			return s, fmt.Errorf("Неверная дата исключения: %s", field)
+		}
+		s.Exceptions = append(s.Exceptions, d)
+	}
+	return s, nil
+}
+
+// resolveSeriesTime заполняет время начала и длительность занятий серии: по номеру пары
+// из расписания звонков или по явно указанному времени. Явная длительность имеет приоритет.
+func resolveSeriesTime(db DBQuerier, s *models.LessonSeries) error {
+	if s.PairNumber > 0 {
+		startClock, endClock, err := lookupBellPeriod(db, s.ClassroomID, s.PairNumber)
+		if err != nil {
+			return err
+		}
+		s.StartClock = startClock
+		if s.DurationMinutes == 0 {
+			day := dateOnly(s.StartDate)
+			s.DurationMinutes = int(atClock(day, endClock).Sub(atClock(day, startClock)).Minutes())
+		}
+	}
+	if s.DurationMinutes == 0 {
+		s.DurationMinutes = int(defaultLessonDuration.Minutes())
+	}
+	return nil
+}
+
+// insertSeriesOccurrences проверяет каждое занятие серии на коллизии и, если коллизий нет,
+// создаёт строки schedule и schedule_groups. Возвращает даты занятий, на которые найдены коллизии.
+func insertSeriesOccurrences(tx DBQuerier, seriesID int, s models.LessonSeries, starts []time.Time) ([]time.Time, error) {
+	duration := time.Duration(s.DurationMinutes) * time.Minute
+
+	var conflicts []time.Time
+	for _, start := range starts {
+		collision, err := CheckScheduleCollisionForGroups(tx, s.TeacherID, s.ClassroomID, s.GroupIDs, start, start.Add(duration), 0)
+		if err != nil {
+			return nil, err
+		}
+		if collision {
+			conflicts = append(conflicts, start)
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	for _, start := range starts {
+		var scheduleID int
+		err := tx.QueryRow(`
+            INSERT INTO schedule (subject_id, teacher_id, classroom_id, start_time, end_time, series_id, pair_number)
+            VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+        `, s.SubjectID, s.TeacherID, s.ClassroomID, start, start.Add(duration), seriesID, nullableInt(s.PairNumber)).Scan(&scheduleID)
+		if err != nil {
+			return nil, err
+		}
+		if err := setScheduleGroups(tx, scheduleID, s.GroupIDs); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// createLessonSeries сохраняет серию с группой и исключениями и создаёт её занятия occurrences.
+// Если какое-либо занятие даёт коллизию, возвращает даты коллизий; вызывающий должен откатить транзакцию.
+func createLessonSeries(tx DBQuerier, series models.LessonSeries, occurrences []time.Time) ([]time.Time, error) {
+	var seriesID int
+	err := tx.QueryRow(`
+        INSERT INTO lesson_series (subject_id, teacher_id, classroom_id, start_date, end_date, start_clock, pair_number,
+                                   duration_minutes, interval_weeks, week_parity, week_numbers)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id
+    `, series.SubjectID, series.TeacherID, series.ClassroomID, series.StartDate, series.EndDate,
+		series.StartClock, nullableInt(series.PairNumber), series.DurationMinutes, series.IntervalWeeks,
+		series.WeekParity, weekNumbersArg(series.WeekNumbers)).Scan(&seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setSeriesGroups(tx, seriesID, series.GroupIDs); err != nil {
+		return nil, fmt.Errorf("связь серии с группами: %v", err)
+	}
+	for _, d := range series.Exceptions {
+		if _, err := tx.Exec(`
+            INSERT INTO lesson_series_exceptions (series_id, exception_date) VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+        `, seriesID, d); err != nil {
+			return nil, fmt.Errorf("исключения серии: %v", err)
+		}
+	}
+
+	return insertSeriesOccurrences(tx, seriesID, series, occurrences)
+}
+
+func CreateLessonSeriesFormHandler(c *gin.Context, db *sql.DB) {
+	series, err := parseLessonSeriesForm(c)
+	if err != nil {
+		c.Set("Alarm", err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	capacityWarning, err := checkCapacityForm(c, db, series.ClassroomID, series.GroupIDs)
+	if err != nil {
+		c.Set("Alarm", err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	if err := resolveSeriesTime(db, &series); err != nil {
+		c.Set("Alarm", "Ошибка определения времени занятий: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		c.Set("Alarm", "Ошибка загрузки семестров: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if term := calendar.FindTerm(terms, series.StartDate); term != nil {
+		series.TermStart = term.StartDate
+	} else if series.WeekParity != calendar.ParityAny || len(series.WeekNumbers) > 0 {
+		c.Set("Alarm", "Не найден учебный семестр, содержащий дату начала серии: без него нельзя определить номера недель.")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	series.Calendar, err = loadCalendarPeriods(db)
+	if err != nil {
+		c.Set("Alarm", "Ошибка загрузки учебного календаря: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	occurrences := ExpandLessonSeries(series)
+	if len(occurrences) == 0 {
+		c.Set("Alarm", "В заданном периоде нет ни одного учебного дня для занятий серии.")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.Set("Alarm", "Ошибка начала транзакции: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	defer tx.Rollback()
+
+	conflicts, err := createLessonSeries(tx, series, occurrences)
+	if err != nil {
+		c.Set("Alarm", "Ошибка при создании серии: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if len(conflicts) > 0 {
+		c.Set("Alarm", "Коллизия обнаружена: у преподавателя, в аудитории или у группы уже есть занятия либо преподаватель недоступен на даты "+formatDates(conflicts)+". Серия не создана.")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.Set("Alarm", "Ошибка сохранения серии: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	c.Set("Alarm", fmt.Sprintf("Серия занятий создана: %d занятий.", len(occurrences))+capacityWarning)
+	RenderAdminSchedulesPageWithFilters(c, db)
+}
+
+// updateLessonSeriesFromOccurrence применяет изменения одного занятия ко всей серии:
+// сдвигает по времени все ещё не прошедшие занятия серии на ту же величину и меняет
+// предмет, преподавателя, аудиторию и группы. Если у серии уже есть прошедшие занятия,
+// серия делится: прошедшие остаются в прежней серии, а изменённые переходят в новую.
+// Возвращает даты, на которых возникли коллизии или которые по учебному календарю не
+// являются учебными.
+func updateLessonSeriesFromOccurrence(db *sql.DB, scheduleID, subjectID, teacherID, classroomID int, groupIDs []int, pairNumber int, newStart time.Time) ([]time.Time, error) {
+	var seriesID int
+	var oldStart time.Time
+	err := db.QueryRow(`SELECT COALESCE(series_id, 0), start_time FROM schedule WHERE id = $1`, scheduleID).Scan(&seriesID, &oldStart)
+	if err != nil {
+		return nil, err
+	}
+	if seriesID == 0 {
+		return nil, fmt.Errorf("занятие не входит в серию")
+	}
+	delta := newStart.Sub(oldStart)
+	dayShift := int(dateOnly(newStart).Sub(dateOnly(oldStart)).Hours() / 24)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	oldSeriesID := seriesID
+	var lastKept sql.NullTime
+	err = tx.QueryRow(`
+        SELECT MAX(start_time) FROM schedule
+        WHERE series_id = $1 AND start_time <= NOW() AND id <> $2
+    `, seriesID, scheduleID).Scan(&lastKept)
+	if err != nil {
+		return nil, err
+	}
+	if lastKept.Valid {
+		seriesID, err = splitLessonSeries(tx, seriesID, lastKept.Time)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+        UPDATE lesson_series
+        SET subject_id = $1, teacher_id = $2, classroom_id = $3, start_clock = $4, pair_number = $5,
+            start_date = start_date + $6::int, end_date = end_date + $6::int
+        WHERE id = $7
+    `, subjectID, teacherID, classroomID, newStart.Format("15:04"), nullableInt(pairNumber), dayShift, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE lesson_series_exceptions SET exception_date = exception_date + $1::int WHERE series_id = $2`, dayShift, seriesID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+        UPDATE schedule
+        SET subject_id = $1, teacher_id = $2, classroom_id = $3, pair_number = $4,
+            start_time = start_time + make_interval(secs => $5),
+            end_time = end_time + make_interval(secs => $5),
+            series_id = $8
+        WHERE series_id = $6 AND (start_time > NOW() OR id = $7)
+        RETURNING id, start_time, end_time
+    `, subjectID, teacherID, classroomID, nullableInt(pairNumber), delta.Seconds(), oldSeriesID, scheduleID, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	type occurrence struct {
+		id         int
+		start, end time.Time
+	}
+	var updated []occurrence
+	for rows.Next() {
+		var o occurrence
+		if err := rows.Scan(&o.id, &o.start, &o.end); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		updated = append(updated, o)
+	}
+	rows.Close()
+
+	if len(groupIDs) > 0 {
+		if err := setSeriesGroups(tx, seriesID, groupIDs); err != nil {
+			return nil, err
+		}
+		for _, o := range updated {
+			if err := setScheduleGroups(tx, o.id, groupIDs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	periods, err := loadCalendarPeriods(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []time.Time
+	for _, o := range updated {
+		if calendar.NonTeachingPeriod(periods, o.start) != nil {
+			conflicts = append(conflicts, o.start)
+			continue
+		}
+		collision, err := CheckScheduleCollisionForGroups(tx, teacherID, classroomID, groupIDs, o.start, o.end, o.id)
+		if err != nil {
+			return nil, err
+		}
+		if collision {
+			conflicts = append(conflicts, o.start)
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+	return nil, tx.Commit()
+}
+
+// splitLessonSeries делит серию перед изменением: прежняя серия заканчивается днём
+// последнего оставшегося в ней занятия lastKept, а продолжение — копия серии с той же
+// сеткой дат, начинающаяся со следующей даты по этой сетке, — получает группы и
+// более поздние исключения. Возвращает id новой серии.
+func splitLessonSeries(tx DBQuerier, seriesID int, lastKept time.Time) (int, error) {
+	var startDate, endDate time.Time
+	var interval int
+	err := tx.QueryRow(`SELECT start_date, end_date, interval_weeks FROM lesson_series WHERE id = $1 FOR UPDATE`, seriesID).
+		Scan(&startDate, &endDate, &interval)
+	if err != nil {
+		return 0, err
+	}
+	if interval < 1 {
+		interval = 1
+	}
+	keptUntil := dateOnly(lastKept)
+	step := 7 * interval
+	days := int(keptUntil.Sub(dateOnly(startDate)).Hours() / 24)
+	cut := dateOnly(startDate).AddDate(0, 0, (days/step+1)*step)
+	if cut.After(endDate) {
+		cut = dateOnly(endDate)
+	}
+	if keptUntil.Before(startDate) {
+		keptUntil = dateOnly(startDate)
+	}
+
+	var newID int
+	err = tx.QueryRow(`
+        INSERT INTO lesson_series (subject_id, teacher_id, classroom_id, start_date, end_date, start_clock, pair_number,
+                                   duration_minutes, interval_weeks, week_parity, week_numbers)
+        SELECT subject_id, teacher_id, classroom_id, $2, end_date, start_clock, pair_number,
+               duration_minutes, interval_weeks, week_parity, week_numbers
+        FROM lesson_series WHERE id = $1
+        RETURNING id
+    `, seriesID, cut).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO lesson_series_groups (series_id, group_id)
+        SELECT $2, group_id FROM lesson_series_groups WHERE series_id = $1
+    `, seriesID, newID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE lesson_series_exceptions SET series_id = $2 WHERE series_id = $1 AND exception_date >= $3`,
+		seriesID, newID, cut); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE lesson_series SET end_date = $2 WHERE id = $1`, seriesID, keptUntil); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+// setSeriesGroups заменяет набор групп серии.
+func setSeriesGroups(db DBQuerier, seriesID int, groupIDs []int) error {
+	if _, err := db.Exec(`DELETE FROM lesson_series_groups WHERE series_id = $1`, seriesID); err != nil {
+		return err
+	}
+	for _, groupID := range groupIDs {
+		if _, err := db.Exec(`INSERT INTO lesson_series_groups (series_id, group_id) VALUES ($1, $2)`, seriesID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSeriesException записывает дату занятия в исключения его серии, чтобы серия
+// больше не считала эту дату своей. Для занятий вне серии ничего не делает.
+func addSeriesException(db DBQuerier, scheduleID int) error {
+	_, err := db.Exec(`
+        INSERT INTO lesson_series_exceptions (series_id, exception_date)
+        SELECT series_id, start_time::date FROM schedule WHERE id = $1 AND series_id IS NOT NULL
+        ON CONFLICT DO NOTHING
+    `, scheduleID)
+	return err
+}
+
+// deleteLesson удаляет занятие scheduleID и в той же транзакции записывает его дату в
+// исключения серии. Сообщает, было ли такое занятие.
+func deleteLesson(db *sql.DB, scheduleID int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := addSeriesException(tx, scheduleID); err != nil {
+		return false, fmt.Errorf("исключение серии: %v", err)
+	}
+	res, err := tx.Exec(`DELETE FROM schedule WHERE id = $1`, scheduleID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	return true, tx.Commit()
+}
+
+// detachFromSeries отвязывает отдельно отредактированное занятие от серии.
+func detachFromSeries(db DBQuerier, scheduleID int) error {
+	if err := addSeriesException(db, scheduleID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`UPDATE schedule SET series_id = NULL WHERE id = $1`, scheduleID)
+	return err
+}
+
+// DeleteLessonSeriesHandler удаляет серию и все её ещё не прошедшие занятия.
+// Прошедшие занятия остаются в расписании как отдельные записи.
+func DeleteLessonSeriesHandler(c *gin.Context, db *sql.DB) {
+	seriesID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Set("Alarm", "Неверный ID серии")
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.Set("Alarm", "Ошибка начала транзакции: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM schedule WHERE series_id = $1 AND start_time > NOW()`, seriesID)
+	if err != nil {
+		c.Set("Alarm", "Ошибка удаления занятий серии: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	deleted, _ := res.RowsAffected()
+	if _, err := tx.Exec(`DELETE FROM lesson_series WHERE id = $1`, seriesID); err != nil {
+		c.Set("Alarm", "Ошибка удаления серии: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.Set("Alarm", "Ошибка удаления серии: "+err.Error())
+		RenderAdminSchedulesPageWithFilters(c, db)
+		return
+	}
+
+	c.Set("Alarm", fmt.Sprintf("Серия удалена, удалено будущих занятий: %d.", deleted))
+	RenderAdminSchedulesPageWithFilters(c, db)
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// activeSessionCondition отбирает незавершённые сеансы, которые ещё можно продлить.
+const activeSessionCondition = `
+    s.revoked_at IS NULL AND EXISTS (
+        SELECT 1 FROM refresh_tokens rt
+        WHERE rt.session_id = s.id AND rt.revoked_at IS NULL AND rt.expires_at > NOW()
+    )`
+
+// loadActiveSessions возвращает активные сеансы пользователя, начиная с последних;
+// сеанс currentID отмечается как текущий.
+func loadActiveSessions(db *sql.DB, userID, currentID int) ([]models.Session, error) {
+	rows, err := db.Query(`
+        SELECT s.id, s.user_agent, s.ip_address, s.created_at, s.last_seen_at
+        FROM sessions s
+        WHERE s.user_id = $1 AND`+activeSessionCondition+`
+        ORDER BY s.last_seen_at DESC, s.id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		s.Current = s.ID == currentID
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// loadActiveSessionCounts возвращает число активных сеансов каждого пользователя.
+func loadActiveSessionCounts(db *sql.DB) (map[int]int, error) {
+	rows, err := db.Query(`
+        SELECT s.user_id, COUNT(*)
+        FROM sessions s
+        WHERE` + activeSessionCondition + `
+        GROUP BY s.user_id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var userID, n int
+		if err := rows.Scan(&userID, &n); err != nil {
+			return nil, err
+		}
+		counts[userID] = n
+	}
+	return counts, rows.Err()
+}
+
+func currentSession(c *gin.Context) (userID, sessionID int, role string) {
+	userIDVal, _ := c.Get("user_id")
+	roleVal, _ := c.Get("role")
+	userID, _ = userIDVal.(int)
+	role, _ = roleVal.(string)
+	return userID, c.GetInt("session_id"), role
+}
+
+// RenderSessionsPage показывает пользователю его активные сеансы.
+func RenderSessionsPage(c *gin.Context, db *sql.DB) {
+	userID, sessionID, role := currentSession(c)
+	sessions, err := loadActiveSessions(db, userID, sessionID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "sessions", gin.H{
+			"Title": "Сеансы",
+			"Role":  role,
+			"Error": "Ошибка загрузки сеансов: " + err.Error(),
+		})
+		return
+	}
+	c.HTML(http.StatusOK, "sessions", gin.H{
+		"Title":    "Сеансы",
+		"Role":     role,
+		"Sessions": sessions,
+		"Alarm":    c.Query("alarm"),
+	})
+}
+
+// RevokeSessionHandler завершает один сеанс пользователя. После завершения текущего
+// сеанса пользователь попадает на страницу входа.
+func RevokeSessionHandler(c *gin.Context, db *sql.DB) {
+	userID, current, _ := currentSession(c)
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, "/sessions?alarm="+url.QueryEscape("Неверный ID сеанса"))
+		return
+	}
+	found, err := middleware.RevokeSession(db, userID, sessionID)
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, "/sessions?alarm="+url.QueryEscape("Ошибка завершения сеанса: "+err.Error()))
+		return
+	}
+	if !found {
+		c.Redirect(http.StatusSeeOther, "/sessions?alarm="+url.QueryEscape("Сеанс не найден или уже завершён"))
+		return
+	}
+	if sessionID == current {
+		middleware.ClearAuthCookies(c)
+		c.Redirect(http.StatusSeeOther, "/login?alarm="+url.QueryEscape("Сеанс завершён"))
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/sessions?alarm="+url.QueryEscape("Сеанс завершён"))
+}
+
+// RevokeAllSessionsHandler завершает все сеансы пользователя, включая текущий
+// («выйти на всех устройствах»).
+func RevokeAllSessionsHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	if _, err := middleware.RevokeUserSessions(db, userID); err != nil {
+		c.Redirect(http.StatusSeeOther, "/sessions?alarm="+url.QueryEscape("Ошибка завершения сеансов: "+err.Error()))
+		return
+	}
+	middleware.ClearAuthCookies(c)
+	c.Redirect(http.StatusSeeOther, "/login?alarm="+url.QueryEscape("Выполнен выход на всех устройствах"))
+}
+
+// RevokeUserSessionsHandler завершает все сеансы выбранного пользователя по
+// решению администратора.
+func RevokeUserSessionsHandler(c *gin.Context, db *sql.DB) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Неверный ID пользователя",
+		})
+		return
+	}
+	n, err := middleware.RevokeUserSessions(db, userID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка завершения сеансов: " + err.Error(),
+		})
+		return
+	}
+	alarm := fmt.Sprintf("Завершено сеансов пользователя %d: %d", userID, n)
+	c.Redirect(http.StatusSeeOther, "/admin/users?alarm="+url.QueryEscape(alarm))
+}
+
+// ListSessionsAPIHandler возвращает активные сеансы текущего пользователя.
+func ListSessionsAPIHandler(c *gin.Context, db *sql.DB) {
+	userID, sessionID, _ := currentSession(c)
+	sessions, err := loadActiveSessions(db, userID, sessionID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSessionAPIHandler завершает сеанс текущего пользователя.
+func RevokeSessionAPIHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Неверный ID сеанса")
+		return
+	}
+	found, err := middleware.RevokeSession(db, userID, sessionID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		apiError(c, http.StatusNotFound, "Сеанс не найден или уже завершён")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllSessionsAPIHandler завершает все сеансы текущего пользователя, включая
+// сеанс самого запроса.
+func RevokeAllSessionsAPIHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	n, err := middleware.RevokeUserSessions(db, userID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
+}
+
+// RevokeUserSessionsAPIHandler завершает все сеансы пользователя :id.
+func RevokeUserSessionsAPIHandler(c *gin.Context, db *sql.DB) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+	n, err := middleware.RevokeUserSessions(db, userID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
+}
@@ -50,27 +50,23 @@ func RenderStudentSchedule(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	baseQuery := `
-		SELECT 
-			s.id,
-			sub.name AS subject_name,
-			s.subject_id,
-			t.name AS teacher_name,
-			s.teacher_id,
-			c.room_number,
-			s.classroom_id,
-			s.start_time,
-			s.end_time,
-			s.created_at,
-			COALESCE(string_agg(g.name, ', '), '') AS group_names,
-			COALESCE(MIN(g.id), 0) AS group_id
-		FROM schedule s
-		JOIN subjects sub ON s.subject_id = sub.id
-		JOIN teachers t ON s.teacher_id = t.id
-		JOIN classrooms c ON s.classroom_id = c.id
-		LEFT JOIN schedule_groups sg ON s.id = sg.schedule_id
-		LEFT JOIN groups g ON sg.group_id = g.id
-	`
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_user", gin.H{
+			"Title": "Расписание",
+			"Error": "Ошибка загрузки семестров: " + err.Error(),
+		})
+		return
+	}
+
+	periods, err := loadCalendarPeriods(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "schedules_user", gin.H{
+			"Title": "Расписание",
+			"Error": "Ошибка загрузки учебного календаря: " + err.Error(),
+		})
+		return
+	}
 
 	whereClause := `
 		WHERE EXISTS (
@@ -111,14 +107,7 @@ func RenderStudentSchedule(c *gin.Context, db *sql.DB) {
 		argIndex++
 	}
 
-	groupByClause := `
-		GROUP BY s.id, sub.name, s.subject_id, t.name, s.teacher_id, c.room_number, s.classroom_id, s.start_time, s.end_time, s.created_at
-		ORDER BY s.start_time ASC
-	`
-
-	fullQuery := baseQuery + " " + whereClause + " " + groupByClause
-
-	rows, err := db.Query(fullQuery, args...)
+	lessons, err := loadScheduleView(db, whereClause, args...)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "schedules_user", gin.H{
 			"Title": "Расписание",
@@ -126,30 +115,34 @@ func RenderStudentSchedule(c *gin.Context, db *sql.DB) {
 		})
 		return
 	}
-	defer rows.Close()
 
 	groupedSchedules := make(map[time.Time][]models.ScheduleDisplay)
-	for rows.Next() {
-		var sch models.ScheduleDisplay
-		err := rows.Scan(&sch.ID, &sch.SubjectName, &sch.SubjectID, &sch.TeacherName, &sch.TeacherID,
-			&sch.RoomNumber, &sch.ClassroomID, &sch.StartTime, &sch.EndTime, &sch.CreatedAt,
-			&sch.GroupNames, &sch.GroupID)
-		if err != nil {
-			c.HTML(http.StatusInternalServerError, "schedules_user", gin.H{
-				"Title": "Расписание",
-				"Error": err.Error(),
-			})
-			return
-		}
+	for _, sch := range lessons {
 		dayKey := time.Date(sch.StartTime.Year(), sch.StartTime.Month(), sch.StartTime.Day(), 0, 0, 0, 0, sch.StartTime.Location())
 		groupedSchedules[dayKey] = append(groupedSchedules[dayKey], sch)
 	}
 
+	feed, err := calendarFeedLinks(c, db, userID)
+	if err != nil {
+		log.Printf("ERROR: Не удалось выдать ссылку на календарь: %v", err)
+	}
+	var groupFeedURL string
+	var studentGroupID int
+	if err := db.QueryRow(`SELECT COALESCE(group_id, 0) FROM students WHERE user_id = $1`, userID).Scan(&studentGroupID); err == nil && studentGroupID > 0 {
+		groupFeedURL = absoluteURL(c, fmt.Sprintf("/ical/group/%d.ics", studentGroupID))
+	}
+
 	c.HTML(http.StatusOK, "schedules_user", gin.H{
 		"Title":         "Расписание",
+		"Alarm":         c.Query("alarm"),
+		"CalendarFeed":  feed,
+		"GroupFeedURL":  groupFeedURL,
 		"Schedules":     groupedSchedules,
 		"AllTeachers":   allTeachers,
 		"AllSubjects":   allSubjects,
+		"Terms":         terms,
+		"Calendar":      periods,
+		"DaysOff":       upcomingCalendarPeriods(periods, time.Now()),
 		"TeacherFilter": teacherFilter,
 		"SubjectFilter": subjectFilter,
 	})
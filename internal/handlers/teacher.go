@@ -35,9 +35,15 @@ func RenderIndexTeacher(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	var teacherName string
-	var departmentID int
-	err := db.QueryRow(`SELECT name, department_id FROM teachers WHERE user_id = $1`, userID).Scan(&teacherName, &departmentID)
+	// Кафедра может быть не назначена: department_id и заведующий допускают NULL.
+	var teacherName, departmentName string
+	var isHead bool
+	err := db.QueryRow(`
+        SELECT t.name, COALESCE(d.name, ''), COALESCE(d.head_teacher_id = t.id, false)
+        FROM teachers t
+        LEFT JOIN departments d ON d.id = t.department_id
+        WHERE t.user_id = $1
+    `, userID).Scan(&teacherName, &departmentName, &isHead)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "index_teacher", gin.H{
 			"Title": "Главная (Преподаватель)",
@@ -47,9 +53,11 @@ func RenderIndexTeacher(c *gin.Context, db *sql.DB) {
 	}
 
 	c.HTML(http.StatusOK, "index_teacher", gin.H{
-		"Title":       "Главная (Преподаватель)",
-		"TeacherName": teacherName,
-		"Message":     "Добро пожаловать, " + teacherName + "!",
+		"Title":          "Главная (Преподаватель)",
+		"TeacherName":    teacherName,
+		"DepartmentName": departmentName,
+		"IsHead":         isHead,
+		"Message":        "Добро пожаловать, " + teacherName + "!",
 	})
 }
 
@@ -105,28 +113,24 @@ func RenderTeacherSchedule(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	// Базовый запрос для получения расписания для данного преподавателя (предстоящие занятия)
-	baseQuery := `
-		SELECT 
-			s.id,
-			sub.name AS subject_name,
-			s.subject_id,
-			t.name AS teacher_name,
-			s.teacher_id,
-			c.room_number,
-			s.classroom_id,
-			s.start_time,
-			s.end_time,
-			s.created_at,
-			COALESCE(string_agg(g.name, ', '), '') AS group_names,
-			COALESCE(MIN(g.id), 0) AS group_id
-		FROM schedule s
-		JOIN subjects sub ON s.subject_id = sub.id
-		JOIN teachers t ON s.teacher_id = t.id
-		JOIN classrooms c ON s.classroom_id = c.id
-		LEFT JOIN schedule_groups sg ON s.id = sg.schedule_id
-		LEFT JOIN groups g ON sg.group_id = g.id
-	`
+	terms, err := loadAcademicTerms(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "teacher_schedule", gin.H{
+			"Title": "Расписание учителя",
+			"Error": "Ошибка загрузки семестров: " + err.Error(),
+		})
+		return
+	}
+
+	periods, err := loadCalendarPeriods(db)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "teacher_schedule", gin.H{
+			"Title": "Расписание учителя",
+			"Error": "Ошибка загрузки учебного календаря: " + err.Error(),
+		})
+		return
+	}
+
 	// Основное условие: занятия назначены данному преподавателю и будущие (start_time > NOW())
 	whereClause := `WHERE s.teacher_id = $1 AND s.start_time > NOW()`
 	args := []interface{}{teacherID}
@@ -161,14 +165,7 @@ func RenderTeacherSchedule(c *gin.Context, db *sql.DB) {
 		argIndex++
 	}
 
-	groupByClause := `
-		GROUP BY s.id, sub.name, s.subject_id, t.name, s.teacher_id, c.room_number, s.classroom_id, s.start_time, s.end_time, s.created_at
-		ORDER BY s.start_time ASC
-	`
-
-	fullQuery := baseQuery + " " + whereClause + " " + groupByClause
-
-	rows, err := db.Query(fullQuery, args...)
+	lessons, err := loadScheduleView(db, whereClause, args...)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "teacher_schedule", gin.H{
 			"Title": "Расписание учителя",
@@ -176,32 +173,39 @@ func RenderTeacherSchedule(c *gin.Context, db *sql.DB) {
 		})
 		return
 	}
-	defer rows.Close()
 
 	groupedSchedules := make(map[time.Time][]models.ScheduleDisplay)
-	for rows.Next() {
-		var sch models.ScheduleDisplay
-		err := rows.Scan(&sch.ID, &sch.SubjectName, &sch.SubjectID, &sch.TeacherName, &sch.TeacherID,
-			&sch.RoomNumber, &sch.ClassroomID, &sch.StartTime, &sch.EndTime, &sch.CreatedAt,
-			&sch.GroupNames, &sch.GroupID)
-		if err != nil {
-			c.HTML(http.StatusInternalServerError, "teacher_schedule", gin.H{
-				"Title": "Расписание учителя",
-				"Error": err.Error(),
-			})
-			return
-		}
+	for _, sch := range lessons {
 		dayKey := time.Date(sch.StartTime.Year(), sch.StartTime.Month(), sch.StartTime.Day(), 0, 0, 0, 0, sch.StartTime.Location())
 		groupedSchedules[dayKey] = append(groupedSchedules[dayKey], sch)
 	}
 
+	feed, err := calendarFeedLinks(c, db, userID)
+	if err != nil {
+		log.Printf("ERROR: Не удалось выдать ссылку на календарь: %v", err)
+	}
+	var groupFeedURL, classroomFeedURL string
+	if groupFilter != "" {
+		groupFeedURL = absoluteURL(c, "/ical/group/"+groupFilter+".ics")
+	}
+	if classroomFilter != "" {
+		classroomFeedURL = absoluteURL(c, "/ical/classroom/"+classroomFilter+".ics")
+	}
+
 	c.HTML(http.StatusOK, "teacher_schedule", gin.H{
-		"Title":           "Расписание учителя",
-		"Schedules":       groupedSchedules,
-		"AllGroups":       allGroups,
-		"AllClassrooms":   allClassrooms,
-		"GroupFilter":     groupFilter,
-		"ClassroomFilter": classroomFilter,
+		"Title":            "Расписание учителя",
+		"Alarm":            c.Query("alarm"),
+		"CalendarFeed":     feed,
+		"GroupFeedURL":     groupFeedURL,
+		"ClassroomFeedURL": classroomFeedURL,
+		"Schedules":        groupedSchedules,
+		"AllGroups":        allGroups,
+		"AllClassrooms":    allClassrooms,
+		"Terms":            terms,
+		"Calendar":         periods,
+		"DaysOff":          upcomingCalendarPeriods(periods, time.Now()),
+		"GroupFilter":      groupFilter,
+		"ClassroomFilter":  classroomFilter,
 	})
 }
 
@@ -408,7 +412,7 @@ func CreateTeacherComment(c *gin.Context, db *sql.DB) {
 	c.Redirect(http.StatusSeeOther, "/teacher/comments")
 }
 
-func RenderTeacherRequests(c *gin.Context, db *sql.DB) {
+func renderTeacherRequestsPage(c *gin.Context, db *sql.DB, status int, errMsg string) {
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
 		c.HTML(http.StatusUnauthorized, "teacher_requests", gin.H{
@@ -426,12 +430,7 @@ func RenderTeacherRequests(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	rows, err := db.Query(`
-		SELECT id, user_id, schedule_id, desired_change, status
-		FROM requests
-		WHERE user_id = $1
-		ORDER BY id DESC
-	`, userID)
+	requests, err := loadRequests(db, "r.user_id = $1", "r.id DESC", userID)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "teacher_requests", gin.H{
 			"Title": "Запросы на изменения",
@@ -439,41 +438,26 @@ func RenderTeacherRequests(c *gin.Context, db *sql.DB) {
 		})
 		return
 	}
-	defer rows.Close()
-
-	var requests []models.Request
-	for rows.Next() {
-		var r models.Request
-		err := rows.Scan(&r.ID, &r.UserID, &r.ScheduleID, &r.DesiredChange, &r.Status)
-		if err != nil {
-			c.HTML(http.StatusInternalServerError, "teacher_requests", gin.H{
-				"Title": "Запросы на изменения",
-				"Error": err.Error(),
-			})
-			return
-		}
-		requests = append(requests, r)
-	}
-
-	c.HTML(http.StatusOK, "teacher_requests", gin.H{
-		"Title":    "Запросы на изменения расписания",
-		"Requests": requests,
+	allTeachers, _ := loadAllTeachers(db)
+	allClassrooms, _ := loadAllClassrooms(db)
+
+	c.HTML(status, "teacher_requests", gin.H{
+		"Title":         "Запросы на изменения расписания",
+		"Requests":      requests,
+		"KindNames":     requestKindNames,
+		"EventNames":    requestEventNames,
+		"AllTeachers":   allTeachers,
+		"AllClassrooms": allClassrooms,
+		"Error":         errMsg,
+		"Alarm":         c.Query("alarm"),
 	})
 }
 
-func CreateTeacherRequest(c *gin.Context, db *sql.DB) {
-	scheduleIDStr := c.PostForm("schedule_id")
-	scheduleID, err := strconv.Atoi(scheduleIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID занятия"})
-		return
-	}
-	desiredChange := c.PostForm("desired_change")
-	if desiredChange == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Запрос не может быть пустым"})
-		return
-	}
+func RenderTeacherRequests(c *gin.Context, db *sql.DB) {
+	renderTeacherRequestsPage(c, db, http.StatusOK, "")
+}
 
+func CreateTeacherRequest(c *gin.Context, db *sql.DB) {
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
@@ -485,14 +469,22 @@ func CreateTeacherRequest(c *gin.Context, db *sql.DB) {
 		return
 	}
 
-	_, err = db.Exec(`
-		INSERT INTO requests (user_id, schedule_id, desired_change, status)
-		VALUES ($1, $2, $3, 'pending')
-	`, userID, scheduleID, desiredChange)
+	r, err := parseRequestForm(c)
+	if err == nil {
+		_, err = createChangeRequest(db, userID, r)
+	}
+	if err == errNotOwnLesson {
+		renderTeacherRequestsPage(c, db, http.StatusForbidden, err.Error())
+		return
+	}
+	if isProposalError(err) {
+		renderTeacherRequestsPage(c, db, http.StatusConflict, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании запроса: " + err.Error()})
+		renderTeacherRequestsPage(c, db, http.StatusInternalServerError, "Ошибка при создании запроса: "+err.Error())
 		return
 	}
 
-	c.Redirect(http.StatusSeeOther, "/teacher/requests")
+	c.Redirect(http.StatusSeeOther, "/teacher/requests?alarm=Запрос+отправлен")
 }
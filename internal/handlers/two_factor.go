@@ -0,0 +1,766 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"image/png"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// totpIssuer — название, под которым учётная запись видна в приложении-аутентификаторе.
+	totpIssuer = "scheduleApp"
+	// totpPeriod — длительность интервала TOTP в секундах. Принимаются и коды соседних
+	// интервалов, чтобы не мешало расхождение часов телефона и сервера.
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+	// loginChallengeAttempts — сколько неверных кодов можно ввести за один вход.
+	loginChallengeAttempts = 5
+)
+
+// loginChallengeTTL — сколько после проверки пароля ждём код из приложения.
+var loginChallengeTTL = 5 * time.Minute
+
+var (
+	errInvalidTOTPCode        = errors.New("Неверный код подтверждения")
+	errLoginChallengeExpired  = errors.New("Время на ввод кода истекло, войдите снова")
+	errTwoFactorSetupRequired = errors.New("Для вашей роли обязательна двухфакторная аутентификация: настройте её, войдя через сайт")
+)
+
+var (
+	totpOpts       = totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// totpKey описывает секрет в формате otpauth:// для приложения-аутентификатора.
+func totpKey(username string, secret []byte) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: username,
+		Period:      totpPeriod,
+		Secret:      secret,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+}
+
+// totpQRCode возвращает QR-код ключа для сканирования приложением в виде data URI.
+func totpQRCode(username, secret string) (template.URL, error) {
+	raw, err := secretEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+	key, err := totpKey(username, raw)
+	if err != nil {
+		return "", err
+	}
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+// prepareTOTPSecret начинает настройку второго фактора: сохраняет новый секрет, если
+// у пользователя ещё нет неподтверждённого, и возвращает действующий.
+func prepareTOTPSecret(db *sql.DB, userID int, username string) (string, error) {
+	key, err := totpKey(username, nil)
+	if err != nil {
+		return "", err
+	}
+	var secret string
+	err = db.QueryRow(`
+        UPDATE users SET totp_secret = COALESCE(totp_secret, $2), totp_last_step = NULL
+        WHERE id = $1 AND totp_enabled_at IS NULL
+        RETURNING totp_secret
+    `, userID, key.Secret()).Scan(&secret)
+	return secret, err
+}
+
+// normalizeCode убирает из введённого кода пробелы и дефисы.
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != int(otp.DigitsSix) {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTOTPStep ищет интервал, которому соответствует код: текущий или соседний.
+func matchTOTPStep(secret, code string, now time.Time) (int64, bool) {
+	step := now.Unix() / totpPeriod
+	for _, s := range []int64{step, step - 1, step + 1} {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(s*totpPeriod, 0), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// useTOTPCode проверяет код из приложения. Код принимается один раз: интервалы не
+// позже последнего принятого отклоняются.
+func useTOTPCode(db DBQuerier, userID int, secret, code string) (bool, error) {
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		return false, nil
+	}
+	step, ok := matchTOTPStep(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	res, err := db.Exec(`UPDATE users SET totp_last_step = $2 WHERE id = $1 AND COALESCE(totp_last_step, 0) < $2`,
+		userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// useRecoveryCode расходует код восстановления пользователя.
+func useRecoveryCode(db DBQuerier, userID int, code string) (bool, error) {
+	res, err := db.Exec(`
+        UPDATE totp_recovery_codes SET used_at = NOW()
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// checkSecondFactor проверяет код из приложения, а при allowRecovery — и код
+// восстановления. Неверные коды учитываются тем же ограничением попыток, что и пароли.
+func checkSecondFactor(db *sql.DB, userID int, username, secret, code, ip string, allowRecovery bool) error {
+	wait, err := loginWait(db, username, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &loginLockedError{wait: wait}
+	}
+
+	var ok bool
+	if allowRecovery && !isTOTPCode(normalizeCode(code)) {
+		ok, err = useRecoveryCode(db, userID, code)
+	} else {
+		ok, err = useTOTPCode(db, userID, secret, code)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := recordLoginFailure(db, username, ip); err != nil {
+			return err
+		}
+		return errInvalidTOTPCode
+	}
+	return nil
+}
+
+// newRecoveryCodes создаёт набор кодов восстановления вида xxxxx-xxxxx.
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	buf := make([]byte, 6)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(secretEncoding.EncodeToString(buf))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes, nil
+}
+
+// replaceRecoveryCodes заменяет коды восстановления пользователя новыми и возвращает их.
+func replaceRecoveryCodes(tx DBQuerier, userID int) ([]string, error) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashRecoveryCode(code)
+	}
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`INSERT INTO totp_recovery_codes (user_id, code_hash) SELECT $1, UNNEST($2::text[])`,
+		userID, pq.Array(hashes)); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// enableTwoFactor завершает настройку второго фактора и выдаёт коды восстановления.
+func enableTwoFactor(db *sql.DB, userID int) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE users SET totp_enabled_at = NOW() WHERE id = $1 AND totp_enabled_at IS NULL`, userID); err != nil {
+		return nil, err
+	}
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+func regenerateRecoveryCodes(db *sql.DB, userID int) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// disableTwoFactor удаляет секрет и коды восстановления пользователя. Незавершённые
+// входы с его кодом после этого не принимаются.
+func disableTwoFactor(db *sql.DB, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := clearTwoFactor(tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resetUserTwoFactor отключает второй фактор по решению администратора и завершает
+// все сеансы пользователя: сброс означает, что устройство потеряно или скомпрометировано.
+func resetUserTwoFactor(db *sql.DB, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := clearTwoFactor(tx, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func clearTwoFactor(tx DBQuerier, userID int) error {
+	if _, err := tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
+
+// loginChallenge — вход, ожидающий второго фактора.
+type loginChallenge struct {
+	id     int
+	user   models.User
+	secret string
+}
+
+// createLoginChallenge запоминает, что пароль пользователя проверен, и возвращает токен
+// для второго шага входа.
+func createLoginChallenge(db *sql.DB, userID int) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	_, err := db.Exec(`
+        INSERT INTO login_challenges (user_id, token_hash, expires_at)
+        VALUES ($1, $2, NOW() + make_interval(secs => $3))
+    `, userID, hashAccountToken(token), loginChallengeTTL.Seconds())
+	return token, err
+}
+
+// completeLoginChallenge проверяет код второго шага входа. Если второй фактор ещё не
+// настроен (он обязателен для роли), при allowEnrol код подтверждает настройку и
+// возвращаются новые коды восстановления.
+func completeLoginChallenge(db *sql.DB, token, code, ip string, allowEnrol bool) (loginChallenge, []string, error) {
+	var ch loginChallenge
+	err := db.QueryRow(`
+        SELECT c.id, u.id, u.username, COALESCE(u.email, ''), u.role, u.status,
+               COALESCE(u.totp_secret, ''), u.totp_enabled_at IS NOT NULL
+        FROM login_challenges c
+        JOIN users u ON u.id = c.user_id
+        WHERE c.token_hash = $1 AND c.expires_at > NOW() AND c.attempts < $2
+    `, hashAccountToken(token), loginChallengeAttempts).Scan(&ch.id, &ch.user.ID, &ch.user.Username, &ch.user.Email,
+		&ch.user.Role, &ch.user.Status, &ch.secret, &ch.user.TwoFactorEnabled)
+	if err == sql.ErrNoRows || err == nil && ch.secret == "" {
+		return ch, nil, errLoginChallengeExpired
+	}
+	if err != nil {
+		return ch, nil, err
+	}
+	if !ch.user.TwoFactorEnabled && !allowEnrol {
+		return ch, nil, errTwoFactorSetupRequired
+	}
+
+	err = checkSecondFactor(db, ch.user.ID, ch.user.Username, ch.secret, code, ip, ch.user.TwoFactorEnabled)
+	if err == errInvalidTOTPCode {
+		if _, err := db.Exec(`UPDATE login_challenges SET attempts = attempts + 1 WHERE id = $1`, ch.id); err != nil {
+			return ch, nil, err
+		}
+		return ch, nil, errInvalidTOTPCode
+	}
+	if err != nil {
+		return ch, nil, err
+	}
+
+	if _, err := db.Exec(`DELETE FROM login_challenges WHERE id = $1 OR expires_at < NOW()`, ch.id); err != nil {
+		return ch, nil, err
+	}
+	if err := clearLoginFailures(db, ch.user.Username); err != nil {
+		return ch, nil, err
+	}
+	if ch.user.TwoFactorEnabled {
+		return ch, nil, nil
+	}
+	codes, err := enableTwoFactor(db, ch.user.ID)
+	if err != nil {
+		return ch, nil, err
+	}
+	ch.user.TwoFactorEnabled = true
+	return ch, codes, nil
+}
+
+// startTwoFactorLogin показывает второй шаг входа после проверки пароля. Если второй
+// фактор обязателен, но не настроен, на той же странице выдаётся QR-код для настройки.
+func startTwoFactorLogin(c *gin.Context, db *sql.DB, user models.User) {
+	var secret string
+	var err error
+	if !user.TwoFactorEnabled {
+		secret, err = prepareTOTPSecret(db, user.ID, user.Username)
+	}
+	var challenge string
+	if err == nil {
+		challenge, err = createLoginChallenge(db, user.ID)
+	}
+	if err != nil {
+		log.Printf("Ошибка начала второго шага входа: %v", err)
+		c.HTML(http.StatusInternalServerError, "login", gin.H{
+			"Title": "Авторизация",
+			"Error": "Ошибка входа, попробуйте позже",
+		})
+		return
+	}
+	renderLoginChallenge(c, http.StatusOK, challenge, user.Username, secret, "")
+}
+
+// renderLoginChallenge показывает форму ввода кода; setupSecret задаётся, когда
+// второй фактор настраивается при входе.
+func renderLoginChallenge(c *gin.Context, status int, challenge, username, setupSecret, errMsg string) {
+	data := gin.H{
+		"Title":     "Подтверждение входа",
+		"Challenge": challenge,
+		"Error":     errMsg,
+	}
+	if setupSecret != "" {
+		data["Setup"] = true
+		data["Secret"] = setupSecret
+		if qr, err := totpQRCode(username, setupSecret); err == nil {
+			data["QRCode"] = qr
+		}
+	}
+	c.HTML(status, "login_totp", data)
+}
+
+// LoginTOTPHandler — второй шаг входа: проверяет код из приложения или код
+// восстановления и только после этого выдаёт токены.
+func LoginTOTPHandler(c *gin.Context, db *sql.DB) {
+	challenge := c.PostForm("challenge")
+	ch, codes, err := completeLoginChallenge(db, challenge, c.PostForm("code"), c.ClientIP(), true)
+	if err == errInvalidTOTPCode {
+		var setupSecret string
+		if !ch.user.TwoFactorEnabled {
+			setupSecret = ch.secret
+		}
+		renderLoginChallenge(c, http.StatusUnauthorized, challenge, ch.user.Username, setupSecret, err.Error())
+		return
+	}
+	if err != nil {
+		status, msg := loginFailure(err)
+		c.HTML(status, "login", gin.H{
+			"Title": "Авторизация",
+			"Error": msg,
+		})
+		return
+	}
+	user := ch.user
+	if msg := accountStatusMessage(user.Status); msg != "" {
+		c.HTML(http.StatusForbidden, "login", gin.H{
+			"Title": "Авторизация",
+			"Error": msg,
+		})
+		return
+	}
+
+	token, refresh, err := middleware.IssueTokens(db, user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login", gin.H{
+			"Title": "Авторизация",
+			"Error": "Ошибка генерации токена",
+		})
+		return
+	}
+	middleware.SetAuthCookies(c, token, refresh)
+
+	if codes != nil {
+		c.HTML(http.StatusOK, "two_factor", gin.H{
+			"Title":         "Двухфакторная аутентификация",
+			"Role":          user.Role,
+			"Enabled":       true,
+			"Required":      true,
+			"RecoveryLeft":  len(codes),
+			"RecoveryCodes": codes,
+			"Alarm":         "Двухфакторная аутентификация настроена",
+		})
+		return
+	}
+	renderLoginHome(c, user, token)
+}
+
+// twoFactorSettings — состояние второго фактора для страницы настроек.
+type twoFactorSettings struct {
+	username     string
+	role         string
+	secret       string
+	enabled      bool
+	required     bool
+	recoveryLeft int
+}
+
+func loadTwoFactorSettings(db *sql.DB, userID int) (twoFactorSettings, error) {
+	var s twoFactorSettings
+	err := db.QueryRow(`
+        SELECT u.username, u.role, COALESCE(u.totp_secret, ''), u.totp_enabled_at IS NOT NULL,
+               EXISTS (SELECT 1 FROM two_factor_policy p WHERE p.role = u.role AND p.required),
+               (SELECT COUNT(*) FROM totp_recovery_codes r WHERE r.user_id = u.id AND r.used_at IS NULL)
+        FROM users u
+        WHERE u.id = $1
+    `, userID).Scan(&s.username, &s.role, &s.secret, &s.enabled, &s.required, &s.recoveryLeft)
+	return s, err
+}
+
+// renderTwoFactorPage показывает страницу настроек; extra дополняет данные шаблона
+// сообщениями и новыми кодами восстановления.
+func renderTwoFactorPage(c *gin.Context, status int, s twoFactorSettings, extra gin.H) {
+	data := gin.H{
+		"Title":        "Двухфакторная аутентификация",
+		"Role":         s.role,
+		"Enabled":      s.enabled,
+		"Required":     s.required,
+		"RecoveryLeft": s.recoveryLeft,
+	}
+	if !s.enabled && s.secret != "" {
+		data["Setup"] = true
+		data["Secret"] = s.secret
+		if qr, err := totpQRCode(s.username, s.secret); err == nil {
+			data["QRCode"] = qr
+		}
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	c.HTML(status, "two_factor", data)
+}
+
+func twoFactorRedirect(c *gin.Context, alarm string) {
+	c.Redirect(http.StatusSeeOther, "/two-factor?alarm="+url.QueryEscape(alarm))
+}
+
+// RenderTwoFactorPage показывает состояние второго фактора текущего пользователя.
+func RenderTwoFactorPage(c *gin.Context, db *sql.DB) {
+	userID, _, role := currentSession(c)
+	s, err := loadTwoFactorSettings(db, userID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "two_factor", gin.H{
+			"Title": "Двухфакторная аутентификация",
+			"Role":  role,
+			"Error": "Ошибка загрузки настроек: " + err.Error(),
+		})
+		return
+	}
+	renderTwoFactorPage(c, http.StatusOK, s, gin.H{"Alarm": c.Query("alarm")})
+}
+
+// SetupTwoFactorHandler создаёт секрет для приложения-аутентификатора. Второй фактор
+// включается только после ввода первого кода.
+func SetupTwoFactorHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	s, err := loadTwoFactorSettings(db, userID)
+	if err != nil {
+		twoFactorRedirect(c, "Ошибка загрузки настроек: "+err.Error())
+		return
+	}
+	if s.enabled {
+		twoFactorRedirect(c, "Двухфакторная аутентификация уже включена")
+		return
+	}
+	if _, err := prepareTOTPSecret(db, userID, s.username); err != nil {
+		twoFactorRedirect(c, "Ошибка настройки: "+err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/two-factor")
+}
+
+// EnableTwoFactorHandler подтверждает настройку кодом из приложения и показывает
+// коды восстановления — единственный раз.
+func EnableTwoFactorHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	s, err := loadTwoFactorSettings(db, userID)
+	if err != nil {
+		twoFactorRedirect(c, "Ошибка загрузки настроек: "+err.Error())
+		return
+	}
+	if s.enabled || s.secret == "" {
+		c.Redirect(http.StatusSeeOther, "/two-factor")
+		return
+	}
+	if err := checkSecondFactor(db, userID, s.username, s.secret, c.PostForm("code"), c.ClientIP(), false); err != nil {
+		status, msg := loginFailure(err)
+		renderTwoFactorPage(c, status, s, gin.H{"Error": msg})
+		return
+	}
+	codes, err := enableTwoFactor(db, userID)
+	if err != nil {
+		twoFactorRedirect(c, "Ошибка включения: "+err.Error())
+		return
+	}
+	s.enabled, s.recoveryLeft = true, len(codes)
+	renderTwoFactorPage(c, http.StatusOK, s, gin.H{
+		"Alarm":         "Двухфакторная аутентификация включена",
+		"RecoveryCodes": codes,
+	})
+}
+
+// RegenerateRecoveryCodesHandler выдаёт новый набор кодов восстановления; прежние
+// перестают действовать.
+func RegenerateRecoveryCodesHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	s, err := loadTwoFactorSettings(db, userID)
+	if err != nil {
+		twoFactorRedirect(c, "Ошибка загрузки настроек: "+err.Error())
+		return
+	}
+	if !s.enabled {
+		c.Redirect(http.StatusSeeOther, "/two-factor")
+		return
+	}
+	if err := checkSecondFactor(db, userID, s.username, s.secret, c.PostForm("code"), c.ClientIP(), true); err != nil {
+		status, msg := loginFailure(err)
+		renderTwoFactorPage(c, status, s, gin.H{"Error": msg})
+		return
+	}
+	codes, err := regenerateRecoveryCodes(db, userID)
+	if err != nil {
+		twoFactorRedirect(c, "Ошибка создания кодов: "+err.Error())
+		return
+	}
+	s.recoveryLeft = len(codes)
+	renderTwoFactorPage(c, http.StatusOK, s, gin.H{
+		"Alarm":         "Созданы новые коды восстановления",
+		"RecoveryCodes": codes,
+	})
+}
+
+// DisableTwoFactorHandler отключает второй фактор по коду из приложения или коду
+// восстановления, если он не обязателен для роли пользователя.
+func DisableTwoFactorHandler(c *gin.Context, db *sql.DB) {
+	userID, _, _ := currentSession(c)
+	s, err := loadTwoFactorSettings(db, userID)
+	if err != nil {
+		twoFactorRedirect(c, "Ошибка загрузки настроек: "+err.Error())
+		return
+	}
+	if s.required {
+		renderTwoFactorPage(c, http.StatusForbidden, s, gin.H{"Error": "Для вашей роли двухфакторная аутентификация обязательна"})
+		return
+	}
+	if !s.enabled {
+		if s.secret != "" {
+			// Незавершённую настройку можно отменить без кода.
+			if err := disableTwoFactor(db, userID); err != nil {
+				twoFactorRedirect(c, "Ошибка отмены настройки: "+err.Error())
+				return
+			}
+		}
+		c.Redirect(http.StatusSeeOther, "/two-factor")
+		return
+	}
+	if err := checkSecondFactor(db, userID, s.username, s.secret, c.PostForm("code"), c.ClientIP(), true); err != nil {
+		status, msg := loginFailure(err)
+		renderTwoFactorPage(c, status, s, gin.H{"Error": msg})
+		return
+	}
+	if err := disableTwoFactor(db, userID); err != nil {
+		twoFactorRedirect(c, "Ошибка отключения: "+err.Error())
+		return
+	}
+	twoFactorRedirect(c, "Двухфакторная аутентификация отключена")
+}
+
+// loadTwoFactorPolicy возвращает, для каких ролей второй фактор обязателен.
+func loadTwoFactorPolicy(db *sql.DB) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT role, required FROM two_factor_policy`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policy := make(map[string]bool)
+	for rows.Next() {
+		var role string
+		var required bool
+		if err := rows.Scan(&role, &required); err != nil {
+			return nil, err
+		}
+		policy[role] = required
+	}
+	return policy, rows.Err()
+}
+
+// UpdateTwoFactorPolicyHandler сохраняет роли, для которых второй фактор обязателен.
+// Сеансы пользователей этих ролей без настроенного второго фактора завершаются: иначе
+// они продлевались бы refresh-токеном, так и не пройдя второй фактор. Второй фактор
+// настраивается при следующем входе.
+func UpdateTwoFactorPolicyHandler(c *gin.Context, db *sql.DB) {
+	roles := c.PostFormArray("required_roles")
+	if roles == nil {
+		roles = []string{}
+	}
+	revoked, err := saveTwoFactorPolicy(db, roles)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка сохранения настроек входа: " + err.Error(),
+		})
+		return
+	}
+	alarm := "Настройки двухфакторной аутентификации сохранены"
+	if revoked > 0 {
+		alarm += fmt.Sprintf(". Завершено сеансов пользователей без второго фактора: %d", revoked)
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/users?alarm="+url.QueryEscape(alarm))
+}
+
+// saveTwoFactorPolicy сохраняет политику и завершает сеансы пользователей, для роли
+// которых второй фактор обязателен, но ещё не настроен. Возвращает число завершённых
+// сеансов.
+func saveTwoFactorPolicy(db *sql.DB, roles []string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE two_factor_policy SET required = (role = ANY($1))`, pq.Array(roles)); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`
+        UPDATE sessions s SET revoked_at = NOW()
+        FROM users u
+        WHERE u.id = s.user_id AND s.revoked_at IS NULL AND u.totp_enabled_at IS NULL
+          AND u.role IN (SELECT role FROM two_factor_policy WHERE required)
+    `)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), tx.Commit()
+}
+
+// ResetUserTwoFactorHandler отключает второй фактор пользователя, потерявшего телефон
+// и коды восстановления, и завершает его сеансы.
+func ResetUserTwoFactorHandler(c *gin.Context, db *sql.DB) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Неверный ID пользователя",
+		})
+		return
+	}
+	if err := resetUserTwoFactor(db, userID); err != nil {
+		c.HTML(http.StatusInternalServerError, "manage_users", gin.H{
+			"Title": "Управление пользователями",
+			"Error": "Ошибка сброса двухфакторной аутентификации: " + err.Error(),
+		})
+		return
+	}
+	alarm := fmt.Sprintf("Двухфакторная аутентификация пользователя %d сброшена", userID)
+	c.Redirect(http.StatusSeeOther, "/admin/users?alarm="+url.QueryEscape(alarm))
+}
+
+// ResetUserTwoFactorAPIHandler отключает второй фактор пользователя :id и завершает
+// его сеансы.
+func ResetUserTwoFactorAPIHandler(c *gin.Context, db *sql.DB) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+	if err := resetUserTwoFactor(db, userID); err != nil {
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,135 @@
+// Package ical формирует ленты расписания в формате iCalendar (RFC 5545)
+// для подписки из календарей телефонов и почтовых клиентов.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+// Время занятий хранится в БД как «настенное» время вуза без часового пояса.
+// В ленту оно выгружается в UTC, поэтому пояс вуза нужно знать: SCHEDULE_TIMEZONE,
+// по умолчанию Europe/Moscow.
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	name := os.Getenv("SCHEDULE_TIMEZONE")
+	if name == "" {
+		name = "Europe/Moscow"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Event — одно занятие в ленте.
+type Event struct {
+	UID         string
+	Sequence    int
+	Summary     string
+	Location    string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Cancelled   bool
+}
+
+// Calendar — лента событий с названием, которое клиент покажет в списке календарей.
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+const maxLineOctets = 75
+
+// Write выводит календарь в формате text/calendar с переводами строк CRLF.
+func (cal Calendar) Write(w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:-//scheduleApp//Расписание//RU")
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	lw.line("X-WR-CALNAME:" + escapeText(cal.Name))
+	lw.line("X-WR-TIMEZONE:" + Location.String())
+	lw.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	lw.line("X-PUBLISHED-TTL:PT1H")
+
+	stamp := formatUTC(time.Now())
+	for _, e := range cal.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		lw.line("DTSTAMP:" + stamp)
+		lw.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		lw.line("DTSTART:" + formatUTC(wallClock(e.Start)))
+		lw.line("DTEND:" + formatUTC(wallClock(e.End)))
+		summary := e.Summary
+		if e.Cancelled {
+			summary = "Отменено: " + summary
+			lw.line("STATUS:CANCELLED")
+		} else {
+			lw.line("STATUS:CONFIRMED")
+		}
+		lw.line("SUMMARY:" + escapeText(summary))
+		if e.Location != "" {
+			lw.line("LOCATION:" + escapeText(e.Location))
+		}
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		lw.line("END:VEVENT")
+	}
+	lw.line("END:VCALENDAR")
+	return lw.err
+}
+
+// wallClock трактует наивное время из БД как время в поясе вуза.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, Location)
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// lineWriter пишет строки содержимого, сворачивая длинные по 75 октетов
+// без разрыва многобайтовых символов UTF-8.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Продолжение начинается с пробела, который тоже занимает октет.
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
@@ -0,0 +1,138 @@
+// Package mailer отправляет служебные письма: подтверждение адреса, сброс пароля.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message — письмо в виде простого текста.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer доставляет письма.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// checkHeaders не даёт адресу получателя дописать в письмо свои заголовки.
+func (m Message) checkHeaders() error {
+	if strings.ContainsAny(m.To, "\r\n") {
+		return fmt.Errorf("адрес получателя %q содержит перевод строки", m.To)
+	}
+	return nil
+}
+
+// format собирает письмо в формате RFC 5322 с заголовками для UTF-8 текста.
+func (m Message) format(from string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// SMTP отправляет письма через SMTP-сервер. Если сервер поддерживает STARTTLS,
+// соединение шифруется; Username задаёт вход по PLAIN.
+type SMTP struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+func (m SMTP) Send(msg Message) error {
+	if err := msg.checkHeaders(); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("неверный адрес SMTP-сервера %q: %v", m.Addr, err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, msg.format(m.From)); err != nil {
+		return fmt.Errorf("не удалось отправить письмо на %s: %v", msg.To, err)
+	}
+	return nil
+}
+
+// File сохраняет письма в каталог Dir файлами .eml вместо отправки; при пустом
+// Dir письма пишутся в журнал. Предназначен для разработки.
+type File struct {
+	Dir  string
+	From string
+}
+
+func (m File) Send(msg Message) error {
+	if err := msg.checkHeaders(); err != nil {
+		return err
+	}
+	from := m.From
+	if from == "" {
+		from = "scheduleApp@localhost"
+	}
+	if m.Dir == "" {
+		log.Printf("Письмо для %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		return err
+	}
+	name := time.Now().Format("20060102-150405.000000000") + "-" + sanitizeFileName(msg.To) + ".eml"
+	return os.WriteFile(filepath.Join(m.Dir, name), msg.format(from), 0600)
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// FromEnv выбирает способ отправки по окружению:
+//
+//	SMTP_ADDR      адрес SMTP-сервера host:port; если не задан, письма не уходят наружу
+//	SMTP_FROM      адрес отправителя (обязателен вместе с SMTP_ADDR)
+//	SMTP_USERNAME  имя для входа на сервер, SMTP_PASSWORD — пароль
+//	MAIL_DIR       без SMTP_ADDR письма сохраняются в этот каталог, иначе пишутся в журнал
+func FromEnv() (Mailer, error) {
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			return nil, fmt.Errorf("SMTP_FROM не задан")
+		}
+		return SMTP{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}, nil
+	}
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		log.Println("ВНИМАНИЕ: SMTP_ADDR и MAIL_DIR не заданы, письма только пишутся в журнал")
+	}
+	return File{Dir: dir}, nil
+}
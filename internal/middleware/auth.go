@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -13,15 +16,46 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var SECRET_KEY = []byte("MY_SUPER_SECRET_KEY")
-
 type JWTClaims struct {
-	UserID int    `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// isAPIRequest сообщает, что запрос пришёл в REST API: вместо редиректа на страницу
+// входа такой клиент должен получить 401 с JSON-телом.
+func isAPIRequest(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, "/api/")
+}
+
+// rejectUnauthenticated прерывает запрос без действительного токена: браузер
+// перенаправляется на страницу входа с сообщением, клиент API получает 401.
+func rejectUnauthenticated(c *gin.Context, alarm string) {
+	if isAPIRequest(c) {
+		c.Header("WWW-Authenticate", `Bearer realm="scheduleApp"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": alarm})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/login?alarm="+url.QueryEscape(alarm))
+	c.Abort()
+}
+
+// AuthMiddleware проверяет JWT из заголовка Authorization или cookie, не обращаясь к базе.
 func AuthMiddleware(c *gin.Context) {
+	authenticate(c, nil)
+}
+
+// Authenticate — AuthMiddleware, который дополнительно сверяет токен с сеансом в базе
+// (завершённый сеанс или сменившаяся роль делают его недействительным), а для страниц
+// браузера продлевает JWT по refresh-токену из cookie.
+func Authenticate(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, db)
+	}
+}
+
+func authenticate(c *gin.Context, db *sql.DB) {
 	var tokenString string
 
 	authHeader := c.GetHeader("Authorization")
@@ -31,32 +65,35 @@ func AuthMiddleware(c *gin.Context) {
 			tokenString = parts[1]
 		}
 	}
-	if tokenString == "" {
+	// Клиенты API авторизуются только заголовком: cookie браузера не должна
+	// давать доступ к JSON-методам с чужих страниц.
+	if tokenString == "" && !isAPIRequest(c) {
 		if cookieToken, err := c.Cookie("token"); err == nil {
 			tokenString = cookieToken
 		}
 	}
-	if tokenString == "" {
-		log.Println("DEBUG: Токен не найден ни в заголовке, ни в куки.")
-		c.Redirect(http.StatusSeeOther, "/login?alarm=Токен+не+найден")
-		c.Abort()
-		return
-	}
 
+	alarm := ""
 	claims := &JWTClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		return SECRET_KEY, nil
-	})
-	if err != nil || !token.Valid {
+	if tokenString == "" {
+		log.Println("DEBUG: Токен не найден ни в заголовке, ни в куки.")
+		alarm = "Токен не найден"
+	} else if err := ParseJWT(tokenString, claims); err != nil {
 		log.Printf("DEBUG: Ошибка парсинга токена: %v", err)
+		alarm = "Неверный или недействительный токен"
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			c.Redirect(http.StatusSeeOther, "/login?alarm=Ваш+токен+истек,+войдите+снова")
-			c.Abort()
+			alarm = "Ваш токен истек, войдите снова"
+		}
+	} else if db != nil {
+		if err := checkSession(db, claims); err != nil {
+			alarm = sessionAlarm(err)
+		}
+	}
+	if alarm != "" {
+		if !refreshFromCookie(c, db, claims) {
+			rejectUnauthenticated(c, alarm)
 			return
 		}
-		c.Redirect(http.StatusSeeOther, "/login?alarm=Неверный+или+недействительный+токен")
-		c.Abort()
-		return
 	}
 	log.Printf("DEBUG: Parsed JWT claims: UserID=%d, Role=%q", claims.UserID, claims.Role)
 
@@ -68,20 +105,85 @@ func AuthMiddleware(c *gin.Context) {
 
 	c.Set("user_id", claims.UserID)
 	c.Set("role", claims.Role)
+	if claims.SessionID != 0 {
+		c.Set("session_id", claims.SessionID)
+	}
 	c.Next()
 }
 
-func GenerateJWT(user models.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// sessionAlarm возвращает сообщение для ошибки checkSession. Известные причины
+// показываются как есть, а ошибки базы только пишутся в журнал: клиент без
+// действительного сеанса не должен видеть их текст.
+func sessionAlarm(err error) string {
+	if errors.Is(err, errSessionEnded) || errors.Is(err, errRoleChanged) {
+		return err.Error()
+	}
+	log.Printf("Ошибка проверки сеанса: %v", err)
+	return "Сеанс недействителен"
+}
+
+// refreshFromCookie выдаёт странице браузера новый JWT по refresh-токену из cookie
+// и заполняет claims. Недействительный refresh-токен удаляется из cookie.
+func refreshFromCookie(c *gin.Context, db *sql.DB, claims *JWTClaims) bool {
+	if db == nil || isAPIRequest(c) {
+		return false
+	}
+	refresh, err := c.Cookie(refreshCookie)
+	if err != nil || refresh == "" {
+		return false
+	}
+	user, access, newRefresh, err := RefreshTokens(db, refresh)
+	if err != nil {
+		if err != ErrInvalidRefreshToken {
+			log.Printf("Ошибка продления сеанса: %v", err)
+		}
+		ClearAuthCookies(c)
+		return false
+	}
+	SetAuthCookies(c, access, newRefresh)
+	*claims = JWTClaims{}
+	if err := ParseJWT(access, claims); err != nil {
+		log.Printf("Ошибка разбора нового токена пользователя %d: %v", user.ID, err)
+		return false
+	}
+	return true
+}
+
+// ParseJWT проверяет подпись токена ключом из заголовка kid и срок его действия.
+func ParseJWT(tokenString string, claims *JWTClaims) error {
+	ring := keys
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		secret, ok := ring.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+		}
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("токен недействителен")
+	}
+	return nil
+}
+
+// GenerateJWT подписывает текущим ключом короткоживущий токен доступа сеанса sessionID.
+func GenerateJWT(user models.User, sessionID int) (string, error) {
+	ring := keys
+	now := time.Now()
 	claims := &JWTClaims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(SECRET_KEY)
+	token.Header["kid"] = ring.signingKID
+	return token.SignedString(ring.keys[ring.signingKID])
 }
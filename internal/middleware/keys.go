@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// minKeyLength — минимальная длина секрета HS256 из конфигурации.
+const minKeyLength = 32
+
+// keyRing — ключи подписи JWT. Токены подписываются ключом signingKID, а проверяются
+// любым ключом из keys по заголовку kid: так новый ключ вводится без выхода всех
+// пользователей, а старый удаляется, когда истекут подписанные им токены.
+type keyRing struct {
+	signingKID string
+	keys       map[string][]byte
+}
+
+var (
+	keys = ephemeralKeyRing()
+
+	// AccessTokenTTL — срок жизни JWT; продлевается он refresh-токеном.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL — срок жизни refresh-токена.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// ephemeralKeyRing создаёт случайный ключ на время работы процесса. Он используется,
+// пока ключи не заданы в конфигурации: после перезапуска все JWT становятся
+// недействительными и браузеры получают новые по refresh-токену.
+func ephemeralKeyRing() keyRing {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return keyRing{signingKID: "ephemeral", keys: map[string][]byte{"ephemeral": secret}}
+}
+
+// SetKeys задаёт ключи подписи; signingKID должен быть среди них.
+func SetKeys(signingKID string, secrets map[string][]byte) error {
+	if _, ok := secrets[signingKID]; !ok {
+		return fmt.Errorf("ключ подписи %q не найден среди ключей", signingKID)
+	}
+	ring := keyRing{signingKID: signingKID, keys: make(map[string][]byte, len(secrets))}
+	for kid, secret := range secrets {
+		if len(secret) < minKeyLength {
+			return fmt.Errorf("ключ %q короче %d байт", kid, minKeyLength)
+		}
+		ring.keys[kid] = secret
+	}
+	keys = ring
+	return nil
+}
+
+// LoadAuthConfig читает настройки токенов из окружения:
+//
+//	JWT_KEY_FILE       файл со строками «kid секрет»; первая строка — ключ подписи,
+//	                   остальные только проверяют ранее выданные токены
+//	JWT_SECRET         единственный секрет, если файла нет (kid — JWT_KID, по умолчанию primary)
+//	ACCESS_TOKEN_TTL   срок жизни JWT, например 15m
+//	REFRESH_TOKEN_TTL  срок жизни refresh-токена, например 720h
+//
+// Без ключей сервер запускается только при APP_ENV=development: временный ключ
+// выводит всех пользователей при каждом перезапуске, а копии сервера не принимают
+// токены друг друга.
+func LoadAuthConfig() error {
+	var err error
+	if AccessTokenTTL, err = durationEnv("ACCESS_TOKEN_TTL", AccessTokenTTL); err != nil {
+		return err
+	}
+	if RefreshTokenTTL, err = durationEnv("REFRESH_TOKEN_TTL", RefreshTokenTTL); err != nil {
+		return err
+	}
+
+	if path := os.Getenv("JWT_KEY_FILE"); path != "" {
+		signingKID, secrets, err := readKeyFile(path)
+		if err != nil {
+			return err
+		}
+		return SetKeys(signingKID, secrets)
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		kid := os.Getenv("JWT_KID")
+		if kid == "" {
+			kid = "primary"
+		}
+		return SetKeys(kid, map[string][]byte{kid: []byte(secret)})
+	}
+	if os.Getenv("APP_ENV") != "development" {
+		return fmt.Errorf("не заданы ключи подписи: укажите JWT_KEY_FILE или JWT_SECRET (для разработки — APP_ENV=development)")
+	}
+	log.Println("ВНИМАНИЕ: JWT_KEY_FILE и JWT_SECRET не заданы, токены подписываются временным ключом. " +
+		"После перезапуска все пользователи входят заново; не используйте это в рабочей установке")
+	return nil
+}
+
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("неверное значение %s: %q", key, val)
+	}
+	return d, nil
+}
+
+// readKeyFile разбирает файл ключей. Пустые строки и строки с # пропускаются.
+func readKeyFile(path string) (string, map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	var signingKID string
+	secrets := make(map[string][]byte)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return "", nil, fmt.Errorf("%s:%d: ожидается «kid секрет»", path, line)
+		}
+		if _, dup := secrets[fields[0]]; dup {
+			return "", nil, fmt.Errorf("%s:%d: ключ %q указан дважды", path, line, fields[0])
+		}
+		if signingKID == "" {
+			signingKID = fields[0]
+		}
+		secrets[fields[0]] = []byte(fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	if signingKID == "" {
+		return "", nil, fmt.Errorf("%s: нет ни одного ключа", path)
+	}
+	return signingKID, secrets, nil
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTrustedProxies задаёт, от каких адресов принимать X-Forwarded-For. Адрес клиента
+// (c.ClientIP()) — ключ ограничения попыток входа, поэтому по умолчанию заголовку не
+// верят: иначе каждый запрос с новым X-Forwarded-For обходил бы блокировку по IP.
+//
+//	TRUSTED_PROXIES  адреса или подсети обратных прокси через запятую, например 127.0.0.1,10.0.0.0/8
+func SetTrustedProxies(r *gin.Engine) error {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return r.SetTrustedProxies(proxies)
+}
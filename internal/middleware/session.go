@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"scheduleApp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidRefreshToken — refresh-токен не найден, истёк или его сеанс завершён.
+var ErrInvalidRefreshToken = errors.New("Сессия истекла, войдите снова")
+
+// rotationGrace — сколько после замены старый refresh-токен ещё принимается: браузер
+// может отправить несколько запросов с ним, пока не получил новую cookie.
+const rotationGrace = 30 * time.Second
+
+const refreshCookie = "refresh_token"
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertRefreshToken(db rowQuerier, userID, sessionID int) (int, string, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return 0, "", err
+	}
+	var id int
+	err = db.QueryRow(`
+        INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4) RETURNING id
+    `, userID, sessionID, hashRefreshToken(token), time.Now().Add(RefreshTokenTTL)).Scan(&id)
+	return id, token, err
+}
+
+// IssueTokens открывает новый сеанс пользователя и выдаёт для него JWT и refresh-токен.
+func IssueTokens(db *sql.DB, user models.User, userAgent, ip string) (string, string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	var sessionID int
+	err = tx.QueryRow(`INSERT INTO sessions (user_id, user_agent, ip_address) VALUES ($1, $2, $3) RETURNING id`,
+		user.ID, userAgent, ip).Scan(&sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	_, refresh, err := insertRefreshToken(tx, user.ID, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	access, err := GenerateJWT(user, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, tx.Commit()
+}
+
+// RefreshTokens обменивает refresh-токен на новый JWT и новый refresh-токен того же
+// сеанса; старый отзывается. Повторное предъявление уже заменённого токена после
+// rotationGrace считается кражей, и все сеансы пользователя завершаются. В пределах rotationGrace
+// возвращается только JWT, а refresh-токен — пустой. Если для роли пользователя второй
+// фактор обязателен, а он его не настроил (роль сменилась или второй фактор сброшен),
+// сеанс завершается: продлить его можно только новым входом со вторым фактором.
+func RefreshTokens(db *sql.DB, token string) (models.User, string, string, error) {
+	var user models.User
+	tx, err := db.Begin()
+	if err != nil {
+		return user, "", "", err
+	}
+	defer tx.Rollback()
+
+	var id, sessionID int
+	var expiresAt time.Time
+	var revokedAt, sessionRevokedAt sql.NullTime
+	var replacedBy sql.NullInt64
+	var secondFactorOK bool
+	err = tx.QueryRow(`
+        SELECT rt.id, rt.session_id, rt.expires_at, rt.revoked_at, rt.replaced_by, s.revoked_at,
+               u.id, u.username, COALESCE(u.email, ''), u.role, u.status,
+               u.totp_enabled_at IS NOT NULL
+                   OR NOT EXISTS (SELECT 1 FROM two_factor_policy p WHERE p.role = u.role AND p.required)
+        FROM refresh_tokens rt
+        JOIN sessions s ON s.id = rt.session_id
+        JOIN users u ON u.id = rt.user_id
+        WHERE rt.token_hash = $1
+        FOR UPDATE OF rt, s
+    `, hashRefreshToken(token)).Scan(&id, &sessionID, &expiresAt, &revokedAt, &replacedBy, &sessionRevokedAt,
+		&user.ID, &user.Username, &user.Email, &user.Role, &user.Status, &secondFactorOK)
+	if err == sql.ErrNoRows {
+		return user, "", "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return user, "", "", err
+	}
+
+	inGrace := revokedAt.Valid && replacedBy.Valid && time.Since(revokedAt.Time) < rotationGrace
+	switch {
+	case sessionRevokedAt.Valid, time.Now().After(expiresAt):
+		return user, "", "", ErrInvalidRefreshToken
+	case revokedAt.Valid && !inGrace, user.Status != "active":
+		if _, err := tx.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, user.ID); err != nil {
+			return user, "", "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return user, "", "", err
+		}
+		return user, "", "", ErrInvalidRefreshToken
+	case !secondFactorOK:
+		if _, err := tx.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE id = $1`, sessionID); err != nil {
+			return user, "", "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return user, "", "", err
+		}
+		return user, "", "", ErrInvalidRefreshToken
+	}
+
+	access, err := GenerateJWT(user, sessionID)
+	if err != nil {
+		return user, "", "", err
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET last_seen_at = NOW() WHERE id = $1`, sessionID); err != nil {
+		return user, "", "", err
+	}
+	if inGrace {
+		return user, access, "", tx.Commit()
+	}
+
+	newID, refresh, err := insertRefreshToken(tx, user.ID, sessionID)
+	if err != nil {
+		return user, "", "", err
+	}
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1`, id, newID); err != nil {
+		return user, "", "", err
+	}
+	return user, access, refresh, tx.Commit()
+}
+
+// RevokeRefreshToken завершает сеанс, которому принадлежит refresh-токен; неизвестный
+// токен не считается ошибкой.
+func RevokeRefreshToken(db *sql.DB, token string) error {
+	_, err := db.Exec(`
+        UPDATE sessions SET revoked_at = NOW()
+        WHERE revoked_at IS NULL AND id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)
+    `, hashRefreshToken(token))
+	return err
+}
+
+// RevokeSession завершает сеанс sessionID пользователя userID и сообщает, был ли
+// такой активный сеанс.
+func RevokeSession(db *sql.DB, userID, sessionID int) (bool, error) {
+	res, err := db.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// RevokeUserSessions завершает все сеансы пользователя и возвращает их число.
+func RevokeUserSessions(db *sql.DB, userID int) (int, error) {
+	res, err := db.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// errSessionEnded — сеанс токена завершён или учётная запись больше не активна.
+var errSessionEnded = errors.New("Сеанс завершён, войдите снова")
+
+// errRoleChanged — роль пользователя изменилась после выдачи токена.
+var errRoleChanged = errors.New("Роль пользователя изменилась, войдите снова")
+
+// checkSession сверяет JWT с сеансом в базе: сеанс не завершён, учётная запись
+// активна, а роль в токене совпадает с текущей.
+func checkSession(db *sql.DB, claims *JWTClaims) error {
+	var role, status string
+	var active bool
+	err := db.QueryRow(`
+        SELECT u.role, u.status, s.revoked_at IS NULL
+        FROM sessions s
+        JOIN users u ON u.id = s.user_id
+        WHERE s.id = $1 AND s.user_id = $2
+    `, claims.SessionID, claims.UserID).Scan(&role, &status, &active)
+	if err == sql.ErrNoRows || (err == nil && (!active || status != "active")) {
+		return errSessionEnded
+	}
+	if err != nil {
+		return err
+	}
+	if role != claims.Role {
+		return errRoleChanged
+	}
+	return nil
+}
+
+// SetAuthCookies сохраняет токены в cookie браузера; пустой refresh не меняет
+// уже сохранённый.
+func SetAuthCookies(c *gin.Context, access, refresh string) {
+	c.SetCookie("token", access, int(AccessTokenTTL.Seconds()), "/", "", false, true)
+	if refresh != "" {
+		c.SetCookie(refreshCookie, refresh, int(RefreshTokenTTL.Seconds()), "/", "", false, true)
+	}
+}
+
+// ClearAuthCookies удаляет cookie с токенами и возвращает refresh-токен, если он был.
+func ClearAuthCookies(c *gin.Context) string {
+	refresh, _ := c.Cookie(refreshCookie)
+	c.SetCookie("token", "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookie, "", -1, "/", "", false, true)
+	return refresh
+}
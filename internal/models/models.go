@@ -3,12 +3,32 @@ package models
 import "time"
 
 type User struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"-"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	GroupID  int    `json:"group_id,omitempty"`
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	Password     string `json:"-"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	Status       string `json:"status,omitempty"`
+	GroupID      int    `json:"group_id,omitempty"`
+	DepartmentID int    `json:"department_id,omitempty"`
+	// EmailVerified — пользователь перешёл по ссылке подтверждения из письма.
+	EmailVerified bool `json:"-"`
+	// TwoFactorEnabled — для входа кроме пароля нужен код из приложения-аутентификатора.
+	TwoFactorEnabled bool `json:"-"`
+	// TwoFactorRequired — администратор сделал второй фактор обязательным для роли пользователя.
+	TwoFactorRequired bool `json:"-"`
+}
+
+// PendingAccount — самостоятельно зарегистрированный преподаватель или администратор,
+// ожидающий подтверждения.
+type PendingAccount struct {
+	ID             int       `json:"id"`
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	Name           string    `json:"name,omitempty"`
+	DepartmentName string    `json:"department_name,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type Teacher struct {
@@ -25,17 +45,39 @@ type Student struct {
 	GroupID int    `json:"group_id"`
 }
 
+// Subject, Classroom и Group — записи справочников. LessonCount — число занятий
+// в расписании, которые на запись ссылаются; такую запись нельзя удалить.
 type Subject struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	LessonCount int    `json:"lesson_count"`
 }
 
 type Classroom struct {
-	ID         int    `json:"id"`
-	RoomNumber string `json:"room_number"`
-	Building   string `json:"building"`
-	Capacity   int    `json:"capacity"`
+	ID          int    `json:"id"`
+	RoomNumber  string `json:"room_number"`
+	Building    string `json:"building"`
+	Capacity    int    `json:"capacity"`
+	LessonCount int    `json:"lesson_count"`
+}
+
+// Department — кафедра. Заведующий (HeadTeacherID) видит и правит расписание
+// и запросы преподавателей своей кафедры.
+type Department struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	HeadTeacherID int    `json:"head_teacher_id,omitempty"`
+	HeadName      string `json:"head_name,omitempty"`
+	TeacherCount  int    `json:"teacher_count"`
+}
+
+type Group struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Course       string `json:"course"`
+	StudentCount int    `json:"student_count"`
+	LessonCount  int    `json:"lesson_count"`
 }
 
 type Schedule struct {
@@ -49,19 +91,80 @@ type Schedule struct {
 }
 
 type ScheduleDisplay struct {
-	ID          int       `json:"id"`
-	SubjectID   int       `json:"subject_id"`
-	TeacherID   int       `json:"teacher_id"`
-	ClassroomID int       `json:"classroom_id"`
-	GroupID     int       `json:"group_id"`
-	GroupNames  string    `json:"group_names"`
-	SubjectName string    `json:"subject_name"`
-	TeacherName string    `json:"teacher_name"`
-	RoomNumber  string    `json:"room_number"`
-	StartTime   time.Time `json:"start_time"`
-	EndTime     time.Time `json:"end_time"`
-	CreatedAt   time.Time `json:"created_at"`
-	Comments    []Comment
+	ID          int `json:"id"`
+	SubjectID   int `json:"subject_id"`
+	TeacherID   int `json:"teacher_id"`
+	ClassroomID int `json:"classroom_id"`
+	GroupID     int `json:"group_id"`
+	SeriesID    int `json:"series_id"`
+	PairNumber  int `json:"pair_number"`
+	// Число студентов во всех группах занятия и вместимость аудитории (0 — не указана).
+	StudentCount int       `json:"student_count"`
+	Capacity     int       `json:"capacity"`
+	GroupNames   string    `json:"group_names"`
+	SubjectName  string    `json:"subject_name"`
+	TeacherName  string    `json:"teacher_name"`
+	RoomNumber   string    `json:"room_number"`
+	Building     string    `json:"building"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Номер редакции занятия, растёт при каждом изменении (SEQUENCE в iCalendar).
+	Revision int `json:"revision"`
+	Comments []Comment
+}
+
+type LessonSeries struct {
+	ID              int         `json:"id"`
+	SubjectID       int         `json:"subject_id"`
+	TeacherID       int         `json:"teacher_id"`
+	ClassroomID     int         `json:"classroom_id"`
+	GroupIDs        []int       `json:"group_ids"`
+	StartDate       time.Time   `json:"start_date"`
+	EndDate         time.Time   `json:"end_date"`
+	StartClock      string      `json:"start_clock"`
+	PairNumber      int         `json:"pair_number"`
+	DurationMinutes int         `json:"duration_minutes"`
+	IntervalWeeks   int         `json:"interval_weeks"`
+	WeekParity      string      `json:"week_parity"`
+	WeekNumbers     []int       `json:"week_numbers"`
+	TermStart       time.Time   `json:"term_start"`
+	Exceptions      []time.Time `json:"exceptions"`
+	// Учебный календарь: праздники, каникулы и переносы, учитываемые при разворачивании серии.
+	Calendar []CalendarPeriod `json:"-"`
+}
+
+type LessonSeriesDisplay struct {
+	ID            int       `json:"id"`
+	SubjectName   string    `json:"subject_name"`
+	TeacherName   string    `json:"teacher_name"`
+	RoomNumber    string    `json:"room_number"`
+	GroupNames    string    `json:"group_names"`
+	StartDate     time.Time `json:"start_date"`
+	EndDate       time.Time `json:"end_date"`
+	StartClock    string    `json:"start_clock"`
+	IntervalWeeks int       `json:"interval_weeks"`
+	WeekParity    string    `json:"week_parity"`
+	WeekNumbers   string    `json:"week_numbers"`
+	Occurrences   int       `json:"occurrences"`
+}
+
+type AcademicTerm struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+// CalendarPeriod — запись учебного календаря: праздник, каникулы, сессия или
+// перенесённый рабочий день (в день StartDate проводятся занятия даты TransferFrom).
+type CalendarPeriod struct {
+	ID           int       `json:"id"`
+	Kind         string    `json:"kind"`
+	Name         string    `json:"name"`
+	StartDate    time.Time `json:"start_date"`
+	EndDate      time.Time `json:"end_date"`
+	TransferFrom time.Time `json:"transfer_from,omitempty"`
 }
 
 type Request struct {
@@ -70,6 +173,62 @@ type Request struct {
 	ScheduleID    int    `json:"schedule_id"`
 	DesiredChange string `json:"desired_change"`
 	Status        string `json:"status"`
+	// Структурированное предложение: тип изменения и его параметры.
+	Kind                string     `json:"kind"`
+	ProposedStart       *time.Time `json:"proposed_start,omitempty"`
+	ProposedClassroomID int        `json:"proposed_classroom_id,omitempty"`
+	ProposedTeacherID   int        `json:"proposed_teacher_id,omitempty"`
+	SwapScheduleID      int        `json:"swap_schedule_id,omitempty"`
+	// Человекочитаемое описание предложения для страниц запросов.
+	Proposal  string    `json:"proposal,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Автор запроса и занятие, которого он касается.
+	RequesterName string     `json:"requester_name"`
+	RequesterRole string     `json:"requester_role"`
+	SubjectName   string     `json:"subject_name"`
+	TeacherName   string     `json:"teacher_name"`
+	RoomNumber    string     `json:"room_number"`
+	GroupNames    string     `json:"group_names"`
+	LessonStart   *time.Time `json:"lesson_start,omitempty"`
+	// История запроса и причина отклонения (текст последнего события rejected).
+	Events         []RequestEvent `json:"events,omitempty"`
+	DecisionReason string         `json:"decision_reason,omitempty"`
+}
+
+// RequestEvent — событие в истории запроса: создание, комментарий, решение администратора,
+// применение изменения к расписанию или отзыв запроса автором.
+type RequestEvent struct {
+	ID        int       `json:"id"`
+	RequestID int       `json:"request_id"`
+	ActorID   int       `json:"actor_id"`
+	ActorName string    `json:"actor_name"`
+	ActorRole string    `json:"actor_role"`
+	Event     string    `json:"event"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session — сеанс входа пользователя: вход с одного устройства или выдача токена
+// клиенту API. LastSeenAt обновляется при продлении токена доступа.
+type Session struct {
+	ID         int       `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// LoginLockout — счётчик неудачных попыток входа по имени пользователя (Scope = "account")
+// или IP-адресу (Scope = "ip"). Пока Locked, вход с этим ключом отклоняется.
+type LoginLockout struct {
+	ID            int       `json:"id"`
+	Scope         string    `json:"scope"`
+	Key           string    `json:"key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+	Locked        bool      `json:"locked"`
 }
 
 type Comment struct {
@@ -87,8 +246,8 @@ type SubjectDisplay struct {
 }
 
 type GroupDisplay struct {
-	ID   int
-	Name string
+	ID   int    `json:"id"`
+	Name string `json:"name"`
 }
 
 type DepartmentDisplay struct {
@@ -104,6 +263,7 @@ type TeacherDisplay struct {
 type ClassroomDisplay struct {
 	ID         int    `json:"id"`
 	RoomNumber string `json:"room_number"`
+	Capacity   int    `json:"capacity"`
 }
 
 type RequestDisplay struct {
@@ -113,3 +273,74 @@ type RequestDisplay struct {
 	DesiredChange string `json:"desired_change"`
 	Status        string `json:"status"`
 }
+
+type BellPeriod struct {
+	ID         int    `json:"id"`
+	PairNumber int    `json:"pair_number"`
+	Building   string `json:"building"`
+	StartClock string `json:"start_clock"`
+	EndClock   string `json:"end_clock"`
+}
+
+// CurriculumItem — строка учебного плана: сколько академических часов в неделю
+// преподаватель ведёт предмет у группы (одна пара — два часа).
+type CurriculumItem struct {
+	ID           int `json:"id"`
+	GroupID      int `json:"group_id"`
+	SubjectID    int `json:"subject_id"`
+	TeacherID    int `json:"teacher_id"`
+	HoursPerWeek int `json:"hours_per_week"`
+}
+
+type CurriculumDisplay struct {
+	ID           int    `json:"id"`
+	GroupName    string `json:"group_name"`
+	SubjectName  string `json:"subject_name"`
+	TeacherName  string `json:"teacher_name"`
+	HoursPerWeek int    `json:"hours_per_week"`
+}
+
+// TeacherPreferenceDisplay — нежелательный для преподавателя слот (день недели 1–6 и пара).
+type TeacherPreferenceDisplay struct {
+	ID          int    `json:"id"`
+	TeacherName string `json:"teacher_name"`
+	Weekday     int    `json:"weekday"`
+	PairNumber  int    `json:"pair_number"`
+	Penalty     int    `json:"penalty"`
+}
+
+type TimetableDraft struct {
+	ID                       int        `json:"id"`
+	WeekStart                time.Time  `json:"week_start"`
+	Placed                   int        `json:"placed"`
+	Unplaced                 int        `json:"unplaced"`
+	GroupGaps                int        `json:"group_gaps"`
+	TeacherPreferencePenalty int        `json:"teacher_preference_penalty"`
+	BuildingChanges          int        `json:"building_changes"`
+	Score                    int        `json:"score"`
+	UnplacedDetails          string     `json:"unplaced_details"`
+	CreatedAt                time.Time  `json:"created_at"`
+	AppliedAt                *time.Time `json:"applied_at"`
+}
+
+type DraftLessonDisplay struct {
+	Weekday     int    `json:"weekday"`
+	PairNumber  int    `json:"pair_number"`
+	GroupName   string `json:"group_name"`
+	SubjectName string `json:"subject_name"`
+	TeacherName string `json:"teacher_name"`
+	RoomNumber  string `json:"room_number"`
+}
+
+// TeacherUnavailability — время, когда преподаватель не может вести занятия: либо
+// еженедельное окно (Weekday, StartClock, EndClock), либо разовый период (StartAt, EndAt).
+type TeacherUnavailability struct {
+	ID         int       `json:"id"`
+	TeacherID  int       `json:"teacher_id"`
+	Weekday    int       `json:"weekday,omitempty"`
+	StartClock string    `json:"start_clock,omitempty"`
+	EndClock   string    `json:"end_clock,omitempty"`
+	StartAt    time.Time `json:"start_at,omitempty"`
+	EndAt      time.Time `json:"end_at,omitempty"`
+	Reason     string    `json:"reason"`
+}
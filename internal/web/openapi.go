@@ -0,0 +1,8 @@
+package web
+
+import _ "embed"
+
+// OpenAPISpec — описание REST API /api/v1 в формате OpenAPI 3.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
@@ -6,6 +6,8 @@ import (
 	"html/template"
 	"log"
 	"time"
+
+	"scheduleApp/internal/calendar"
 )
 
 //go:embed templates/*.html
@@ -32,6 +34,16 @@ func dayFullDate(t time.Time) string {
 	return fmt.Sprintf("%s (%s)", dateStr, dayName)
 }
 
+// weekdayName возвращает название дня недели по номеру (1 — понедельник, 7 — воскресенье).
+func weekdayName(n int) string {
+	return weekdayMap[time.Weekday(n%7)]
+}
+
+// WeekdayName возвращает русское название дня недели, как на страницах расписания.
+func WeekdayName(d time.Weekday) string {
+	return weekdayMap[d]
+}
+
 func timeHHMM(t time.Time) string {
 	return t.Format("15:04")
 }
@@ -39,11 +51,18 @@ func timeHHMM(t time.Time) string {
 func InitTemplates() {
 	var err error
 	funcMap := template.FuncMap{
-		"dayFullDate": dayFullDate,
-		"timeHHMM":    timeHHMM,
+		"dayFullDate":  dayFullDate,
+		"timeHHMM":     timeHHMM,
+		"weekdayName":  weekdayName,
+		"weekLabel":    calendar.WeekLabel,
+		"dayNote":      calendar.DayNote,
+		"calendarKind": calendar.KindName,
 		"formatDate": func(t time.Time) string {
 			return t.Format("02.01.2006")
 		},
+		"formatDateTime": func(t time.Time) string {
+			return t.Format("02.01.2006 15:04")
+		},
 	}
 	Tmpl, err = template.New("").Funcs(funcMap).ParseFS(templatesFS, "templates/*.html")
 	if err != nil {
@@ -0,0 +1,151 @@
+package main_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/web"
+)
+
+// setupTestFormContext готовит POST-запрос с формой и подключает шаблоны страниц.
+func setupTestFormContext(target string, form url.Values) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	web.InitTemplates()
+	w := httptest.NewRecorder()
+	c, r := gin.CreateTestContext(w)
+	r.SetHTMLTemplate(web.Tmpl)
+	c.Request, _ = http.NewRequest("POST", target, strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c, w
+}
+
+func expectRegisterLists(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM groups").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "ИВТ-21"))
+	mock.ExpectQuery("FROM departments").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Кафедра физики"))
+}
+
+func TestRegisterFormHandler_TeacherWaitsForApproval(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	sent := useRecordingMailer(t)
+
+	expectRegisterLists(mock)
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("ivanov", sqlmock.AnyArg(), "ivanov@example.com", "teacher", "pending").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("INSERT INTO teachers").WithArgs(12, "Иванов И.И.", 3).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAccountToken(mock, 12, "verify_email")
+
+	c, w := setupTestFormContext("/register", url.Values{
+		"username":      {"ivanov"},
+		"password":      {"secret"},
+		"email":         {"ivanov@example.com"},
+		"name":          {"Иванов И.И."},
+		"role":          {"teacher"},
+		"department_id": {"3"},
+	})
+	handlers.RegisterFormHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "после подтверждения заявки администратором")
+	if assert.Len(t, sent.sent, 1) {
+		assert.Equal(t, "ivanov@example.com", sent.sent[0].To)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterFormHandler_RefusesAdminRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectRegisterLists(mock)
+
+	c, w := setupTestFormContext("/register", url.Values{
+		"username": {"mallory"},
+		"password": {"secret"},
+		"role":     {"admin"},
+	})
+	handlers.RegisterFormHandler(c, db)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterFormHandler_RejectsHeaderInjectionInEmail(t *testing.T) {
+	for _, email := range []string{
+		"ivanov@example.com\r\nBcc: victim@example.com",
+		"Иванов <ivanov@example.com>",
+		"ivanov@example.com, petrov@example.com",
+		"ivanov",
+	} {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+
+		expectRegisterLists(mock)
+
+		c, w := setupTestFormContext("/register", url.Values{
+			"username": {"ivanov"},
+			"password": {"secret"},
+			"email":    {email},
+		})
+		handlers.RegisterFormHandler(c, db)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, email)
+		assert.Contains(t, w.Body.String(), "Укажите адрес электронной почты", email)
+		assert.NoError(t, mock.ExpectationsWereMet(), email)
+		db.Close()
+	}
+}
+
+func TestCreateAPITokenHandler_PendingAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery("FROM users").WithArgs("ivanov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}).
+			AddRow(12, "ivanov", string(hash), "ivanov@example.com", "teacher", "pending", true, false, false))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("ivanov").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w := setupTestContextJSON("POST", "/api/v1/auth/token", `{"username": "ivanov", "password": "secret"}`)
+	handlers.CreateAPITokenHandler(c, db)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "ожидает подтверждения")
+	assert.NotContains(t, w.Body.String(), "token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReviewAccountHandler_Approve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE users SET status").WithArgs("active", 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := setupTestFormContext("/admin/users/12/review?_action=approve", url.Values{})
+	c.Params = gin.Params{{Key: "id", Value: "12"}}
+	handlers.ReviewAccountHandler(c, db, "approve")
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	assert.Equal(t, "/admin/users", w.Header().Get("Location"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
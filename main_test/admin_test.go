@@ -35,6 +35,7 @@ func TestCreateScheduleHandler_Success(t *testing.T) {
         "subject_id":   1,
         "teacher_id":   2,
         "classroom_id": 3,
+        "group_ids":    [5],
         "start_time":   "2025-09-01T08:00:00Z"
     }`
 
@@ -74,6 +75,7 @@ func TestCreateScheduleHandler_Collision(t *testing.T) {
         "subject_id": 1,
         "teacher_id": 2,
         "classroom_id": 3,
+        "group_ids": [5],
         "start_time": "2025-09-01T08:00:00Z"
     }`
 
@@ -97,6 +99,24 @@ func TestCreateScheduleHandler_Collision(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
+func TestCreateScheduleHandler_RequiresGroups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for _, groups := range []string{``, `"group_ids": [],`} {
+		c, w := setupTestContextJSON("POST", "/api/schedule", `{
+            "subject_id": 1, "teacher_id": 2, "classroom_id": 3, `+groups+`
+            "start_time": "2025-09-01T08:00:00Z"
+        }`)
+		handlers.CreateScheduleHandler(c, db)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "не указаны группы")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateScheduleHandler_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
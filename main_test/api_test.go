@@ -0,0 +1,126 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/web"
+)
+
+func TestAuthMiddleware_APIRequestWithoutTokenGets401(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/schedule", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "token", Value: "cookie-is-ignored"})
+	middleware.AuthMiddleware(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+	assert.JSONEq(t, `{"error":"Токен не найден"}`, w.Body.String())
+}
+
+func TestAuthMiddleware_PageRequestWithoutTokenRedirects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/schedules", nil)
+	middleware.AuthMiddleware(c)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	assert.Contains(t, w.Header().Get("Location"), "/login?alarm=")
+}
+
+func TestListScheduleAPIHandler_Paginates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM schedule s").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("LIMIT 2 OFFSET 2").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subject_id", "subject_name", "teacher_id", "teacher_name",
+			"classroom_id", "room_number", "group_ids", "group_names", "pair_number", "start_time", "end_time"}).
+			AddRow(12, 1, "Физика", 2, "Иванов И.И.", 3, "101", "{7}", "ИВТ-21", 1, start, start.Add(90*time.Minute)))
+
+	c, w := setupTestContextJSON("GET", "/api/v1/schedule?group=7&page=2&per_page=2", "")
+	handlers.ListScheduleAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []struct {
+			ID       int     `json:"id"`
+			GroupIDs []int64 `json:"group_ids"`
+		} `json:"data"`
+		Page    int `json:"page"`
+		PerPage int `json:"per_page"`
+		Total   int `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Page)
+	assert.Equal(t, 2, resp.PerPage)
+	assert.Equal(t, 3, resp.Total)
+	if assert.Len(t, resp.Data, 1) {
+		assert.Equal(t, 12, resp.Data[0].ID)
+		assert.Equal(t, []int64{7}, resp.Data[0].GroupIDs)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListScheduleAPIHandler_RejectsOversizedPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	c, w := setupTestContextJSON("GET", "/api/v1/schedule?per_page=1000", "")
+	handlers.ListScheduleAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"error"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRequestAPIHandler_HidesForeignRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM requests r").WithArgs(4).
+		WillReturnRows(requestRow(4, 10, "pending", "text", "Перенесите", start))
+	mock.ExpectQuery("FROM request_events").WillReturnRows(sqlmock.NewRows([]string{
+		"id", "request_id", "actor_id", "actor_name", "actor_role", "event", "message", "created_at"}))
+
+	c, w := setupTestContextJSON("GET", "/api/v1/requests/4", "")
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+	c.Set("user_id", 2)
+	c.Set("role", "student")
+	handlers.GetRequestAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"not found"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOpenAPISpecIsValidJSON(t *testing.T) {
+	var spec struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(web.OpenAPISpec, &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	for _, path := range []string{"/schedule", "/schedule/{id}", "/groups", "/teachers", "/classrooms",
+		"/subjects", "/requests", "/requests/{id}", "/comments"} {
+		assert.Contains(t, spec.Paths, path)
+	}
+}
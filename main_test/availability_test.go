@@ -0,0 +1,60 @@
+package main_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+)
+
+func TestUnavailabilityOverlaps_Weekly(t *testing.T) {
+	// Каждый вторник с 12:00 до 15:00.
+	u := models.TeacherUnavailability{Weekday: 2, StartClock: "12:00", EndClock: "15:00"}
+	tuesday := time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)
+
+	at := func(day time.Time, h, m int) time.Time {
+		return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+	}
+
+	assert.True(t, handlers.UnavailabilityOverlaps(u, at(tuesday, 11, 0), at(tuesday, 12, 30)))
+	assert.False(t, handlers.UnavailabilityOverlaps(u, at(tuesday, 10, 30), at(tuesday, 12, 0)))
+	assert.False(t, handlers.UnavailabilityOverlaps(u, at(tuesday, 15, 0), at(tuesday, 16, 30)))
+	wednesday := tuesday.AddDate(0, 0, 1)
+	assert.False(t, handlers.UnavailabilityOverlaps(u, at(wednesday, 12, 0), at(wednesday, 13, 30)))
+}
+
+func TestUnavailabilityOverlaps_Range(t *testing.T) {
+	u := models.TeacherUnavailability{
+		StartAt: time.Date(2025, 10, 6, 0, 0, 0, 0, time.UTC),
+		EndAt:   time.Date(2025, 10, 11, 0, 0, 0, 0, time.UTC),
+	}
+	lesson := time.Date(2025, 10, 8, 9, 0, 0, 0, time.UTC)
+	assert.True(t, handlers.UnavailabilityOverlaps(u, lesson, lesson.Add(90*time.Minute)))
+
+	after := time.Date(2025, 10, 13, 9, 0, 0, 0, time.UTC)
+	assert.False(t, handlers.UnavailabilityOverlaps(u, after, after.Add(90*time.Minute)))
+}
+
+func TestCheckScheduleCollision_TeacherUnavailable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 13, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teacher_unavailability").
+		WithArgs(2, end.Unix(), start.Unix(), 2, "14:30:00", "13:00:00").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	collision, err := handlers.CheckScheduleCollision(db, 2, 3, 4, start, end, 0)
+	assert.NoError(t, err)
+	assert.True(t, collision)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
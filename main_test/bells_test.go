@@ -0,0 +1,61 @@
+package main_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+)
+
+func TestResolveLessonTime_ByPair(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM bell_schedule").
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"start_clock", "end_clock"}).AddRow("11:40", "13:10"))
+
+	start, end, err := handlers.ResolveLessonTime(db, 7, date(2025, 9, 1), 3, time.Time{}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 9, 1, 11, 40, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 9, 1, 13, 10, 0, 0, time.UTC), end)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveLessonTime_DoublePairDuration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM bell_schedule").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"start_clock", "end_clock"}).AddRow("08:00", "09:30"))
+
+	_, end, err := handlers.ResolveLessonTime(db, 2, date(2025, 9, 1), 1, time.Time{}, 180)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 9, 1, 11, 0, 0, 0, time.UTC), end)
+}
+
+func TestResolveLessonTime_ManualStart(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 1, 15, 0, 0, 0, time.UTC)
+
+	_, end, err := handlers.ResolveLessonTime(db, 2, time.Time{}, 0, start, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(90*time.Minute), end)
+
+	_, end, err = handlers.ResolveLessonTime(db, 2, time.Time{}, 0, start, 45)
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(45*time.Minute), end)
+
+	_, _, err = handlers.ResolveLessonTime(db, 2, time.Time{}, 0, time.Time{}, 0)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+package main_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+)
+
+func TestWeekNumber(t *testing.T) {
+	// 1 сентября 2025 — понедельник, 3 сентября — среда той же недели.
+	termStart := date(2025, 9, 3)
+
+	assert.Equal(t, 1, calendar.WeekNumber(termStart, date(2025, 9, 1)))
+	assert.Equal(t, 1, calendar.WeekNumber(termStart, date(2025, 9, 7)))
+	assert.Equal(t, 2, calendar.WeekNumber(termStart, date(2025, 9, 8)))
+	assert.Equal(t, 5, calendar.WeekNumber(termStart, date(2025, 10, 1)))
+	assert.Equal(t, 0, calendar.WeekNumber(termStart, date(2025, 8, 31)))
+}
+
+func TestWeekLabel(t *testing.T) {
+	terms := []models.AcademicTerm{{StartDate: date(2025, 9, 1), EndDate: date(2025, 12, 31)}}
+
+	assert.Equal(t, "1-я неделя, числитель", calendar.WeekLabel(terms, date(2025, 9, 2)))
+	assert.Equal(t, "2-я неделя, знаменатель", calendar.WeekLabel(terms, date(2025, 9, 10)))
+	assert.Equal(t, "", calendar.WeekLabel(terms, date(2026, 2, 10)))
+}
+
+func TestParseWeekNumbers(t *testing.T) {
+	weeks, err := calendar.ParseWeekNumbers("1, 3,5-7")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3, 5, 6, 7}, weeks)
+
+	_, err = calendar.ParseWeekNumbers("4-2")
+	assert.Error(t, err)
+	_, err = calendar.ParseWeekNumbers("x")
+	assert.Error(t, err)
+	_, err = calendar.ParseWeekNumbers("50-54")
+	assert.Error(t, err)
+	_, err = calendar.ParseWeekNumbers("1-1000000000")
+	assert.Error(t, err)
+}
+
+func TestExpandLessonSeries_EvenWeeksOnly(t *testing.T) {
+	s := models.LessonSeries{
+		StartDate:     date(2025, 9, 2),
+		EndDate:       date(2025, 9, 30),
+		StartClock:    "12:00",
+		IntervalWeeks: 1,
+		WeekParity:    calendar.ParityEven,
+		TermStart:     date(2025, 9, 1),
+	}
+
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 9, 9, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 9, 23, 12, 0, 0, 0, time.UTC),
+	}, handlers.ExpandLessonSeries(s))
+}
@@ -0,0 +1,92 @@
+package main_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+)
+
+func TestCheckClassroomCapacity_SumsStudentsOfAllGroups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").
+		WithArgs(5, pq.Array([]int{1, 2, 3})).
+		WillReturnRows(sqlmock.NewRows([]string{"students", "capacity"}).AddRow(62, 20))
+
+	students, capacity, err := handlers.CheckClassroomCapacity(db, 5, []int{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 62, students)
+	assert.Equal(t, 20, capacity)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectLessonChecks ожидает проверки нового занятия преподавателя 2 в аудитории 3 для
+// группы 5: учебный календарь, коллизии, недоступность и вместимость аудитории.
+func expectLessonChecks(mock sqlmock.Sqlmock, students, capacity int) {
+	mock.ExpectQuery("FROM academic_calendar").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "name", "start_date", "end_date", "transfer_from"}))
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teacher_unavailability").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM students WHERE group_id").WithArgs(3, pq.Array([]int{5})).
+		WillReturnRows(sqlmock.NewRows([]string{"students", "capacity"}).AddRow(students, capacity))
+}
+
+// expectNewLessonGroups ожидает привязку нового занятия к группе 5.
+func expectNewLessonGroups(mock sqlmock.Sqlmock, scheduleID int) {
+	mock.ExpectExec("DELETE FROM schedule_groups").WithArgs(scheduleID, pq.Array([]int{5})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schedule_groups").WithArgs(scheduleID, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM dropped_lesson_groups").WithArgs(scheduleID, pq.Array([]int{5})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schedule SET revision").WithArgs(scheduleID).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestCreateScheduleHandler_RejectsOverCapacity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectLessonChecks(mock, 62, 20)
+
+	c, w := setupTestContextJSON("POST", "/api/v1/schedule", `{
+        "subject_id": 1, "teacher_id": 2, "classroom_id": 3, "group_ids": [5],
+        "start_time": "2025-09-01T08:00:00Z"
+    }`)
+	handlers.CreateScheduleHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Аудитория рассчитана на 20 мест")
+	assert.Contains(t, w.Body.String(), "capacity_override")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateScheduleHandler_CapacityOverride(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectLessonChecks(mock, 62, 20)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO schedule").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100))
+	expectNewLessonGroups(mock, 100)
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("POST", "/api/v1/schedule", `{
+        "subject_id": 1, "teacher_id": 2, "classroom_id": 3, "group_ids": [5],
+        "start_time": "2025-09-01T08:00:00Z", "capacity_override": true
+    }`)
+	handlers.CreateScheduleHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "превышена вместимость аудитории (62 студентов на 20 мест)")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
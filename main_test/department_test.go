@@ -0,0 +1,180 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+)
+
+func TestSaveDepartmentAPIHandler_CreateWithHead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM departments").WithArgs("Кафедра физики", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teachers t WHERE t.id").WithArgs(2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "other"}).AddRow("Иванов И.И.", ""))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO departments").WithArgs("Кафедра физики", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec("UPDATE teachers SET department_id").WithArgs(4, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("POST", "/api/v1/departments", `{"name": " Кафедра физики ", "head_teacher_id": 2}`)
+	handlers.SaveDepartmentAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var d models.Department
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.Equal(t, 4, d.ID)
+	assert.Equal(t, 2, d.HeadTeacherID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveDepartmentAPIHandler_HeadOfAnotherDepartment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM departments").WithArgs("Кафедра химии", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teachers t WHERE t.id").WithArgs(2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "other"}).AddRow("Иванов И.И.", "Кафедра физики"))
+
+	c, w := setupTestContextJSON("PUT", "/api/v1/departments/5", `{"name": "Кафедра химии", "head_teacher_id": 2}`)
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	handlers.SaveDepartmentAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "уже заведует кафедрой «Кафедра физики»")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteDepartmentLessonHandler_HeadCancelsDepartmentLesson(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM departments d").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "head_id", "head_name"}).AddRow(3, "Кафедра физики", 2, "Иванов И.И."))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(10, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO lesson_series_exceptions").WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schedule").WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("POST", "/teacher/department/lessons/10?_method=DELETE", "")
+	c.Params = gin.Params{{Key: "id", Value: "10"}}
+	c.Set("user_id", 7)
+	handlers.DeleteDepartmentLessonHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/teacher/department", location.Path)
+	assert.Equal(t, "Занятие отменено", location.Query().Get("alarm"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateDepartmentLessonHandler_RejectsOverCapacity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM departments d").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "head_id", "head_name"}).AddRow(3, "Кафедра физики", 2, "Иванов И.И."))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(10, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("FROM academic_calendar").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "name", "start_date", "end_date", "transfer_from"}))
+	mock.ExpectQuery("FROM schedule s").WithArgs(10).WillReturnRows(lessonRows(10, 2, 4, start))
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teacher_unavailability").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM students WHERE group_id").
+		WillReturnRows(sqlmock.NewRows([]string{"students", "capacity"}).AddRow(62, 20))
+	expectDepartmentPage(mock, 7)
+
+	c, w := setupTestFormContext("/teacher/department/lessons/10?_method=PUT", url.Values{
+		"teacher_id": {"2"}, "classroom_id": {"3"}, "start_time": {"2025-09-03T09:00"},
+	})
+	c.Params = gin.Params{{Key: "id", Value: "10"}}
+	c.Set("user_id", 7)
+	handlers.UpdateDepartmentLessonHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Аудитория рассчитана на 20 мест")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportSchedulesHandler_FiltersByDepartment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name FROM departments").WithArgs("3").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Кафедра физики"))
+	mock.ExpectQuery("s.teacher_id IN \\(SELECT id FROM teachers WHERE department_id = \\$1\\)").WithArgs("3").
+		WillReturnRows(sqlmock.NewRows(exportScheduleColumns))
+	mock.ExpectQuery("FROM bell_schedule").
+		WillReturnRows(sqlmock.NewRows(exportBellColumns))
+
+	c, w := setupTestContextJSON("GET", "/admin/schedules/export?format=xlsx&department=3", "")
+	handlers.ExportSchedulesHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectDepartmentPage ожидает загрузку страницы кафедры без данных.
+func expectDepartmentPage(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectQuery("FROM departments d").WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "head_id", "head_name"}).AddRow(3, "Кафедра физики", 2, "Иванов И.И."))
+	for i := 0; i < 5; i++ {
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+}
+
+func TestProcessDepartmentRequestHandler_CrossDepartmentProposalRefused(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM departments d").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "head_id", "head_name"}).AddRow(3, "Кафедра физики", 2, "Иванов И.И."))
+	// Запрос подал преподаватель кафедры...
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(3, 15).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	// ...но он касается занятия другой кафедры.
+	mock.ExpectQuery(`SELECT EXISTS .*r.swap_schedule_id`).WithArgs(3, 15).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	expectDepartmentPage(mock, 7)
+
+	c, w := setupTestFormContext("/teacher/department/requests/15?_action=approve", url.Values{})
+	c.Params = gin.Params{{Key: "id", Value: "15"}}
+	c.Set("user_id", 7)
+	handlers.ProcessDepartmentRequestHandler(c, db, "approve")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "может одобрить только администратор")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
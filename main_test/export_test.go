@@ -0,0 +1,100 @@
+package main_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+)
+
+var exportScheduleColumns = []string{"id", "subject_name", "subject_id", "teacher_name", "teacher_id",
+	"room_number", "building", "classroom_id", "start_time", "end_time", "created_at", "revision",
+	"group_names", "group_id"}
+
+var exportBellColumns = []string{"id", "pair_number", "building", "start_clock", "end_clock"}
+
+func TestExportSchedulesHandler_XLSXRoundTripsThroughImport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT name FROM groups").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ИВТ-21"))
+	mock.ExpectQuery("FROM schedule s").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows(exportScheduleColumns).
+			AddRow(10, "Физика", 1, "Иванов И.И.", 2, "101", "Главный", 3, start, start.Add(90*time.Minute), start, 1, "ИВТ-21, ИВТ-22", 5).
+			AddRow(11, "Химия", 1, "Петров П.П.", 4, "202", "", 6, start.Add(4*time.Hour), start.Add(5*time.Hour), start, 1, "ИВТ-21", 5))
+	mock.ExpectQuery("FROM bell_schedule").
+		WillReturnRows(sqlmock.NewRows(exportBellColumns).AddRow(1, 1, "", "09:00", "10:30"))
+
+	c, w := setupTestContextJSON("GET", "/admin/schedules/export?format=xlsx&group=5", "")
+	handlers.ExportSchedulesHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="schedule.xlsx"`)
+
+	rows, err := handlers.ParseTimetableFile("schedule.xlsx", bytes.NewReader(w.Body.Bytes()))
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "Физика", rows[0].Subject)
+		assert.Equal(t, "ИВТ-21, ИВТ-22", rows[0].Groups)
+		assert.Equal(t, "Главный", rows[0].Building)
+		assert.Equal(t, "1", rows[0].Pair)
+		assert.Equal(t, "Вторник", rows[0].Weekday)
+		// Занятие вне сетки звонков выгружается со временем, без номера пары.
+		assert.Equal(t, "", rows[1].Pair)
+		assert.Equal(t, "13:00", rows[1].Time)
+		assert.Equal(t, "14:00", rows[1].EndTime)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportSchedulesHandler_WeeklyPDF(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	monday := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	start := monday.Add(24*time.Hour + 9*time.Hour)
+	mock.ExpectQuery("SELECT name FROM teachers").WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Иванов И.И."))
+	mock.ExpectQuery("FROM schedule s").WithArgs("2", monday, monday.AddDate(0, 0, 7)).
+		WillReturnRows(sqlmock.NewRows(exportScheduleColumns).
+			AddRow(10, "Физика", 1, "Иванов И.И.", 2, "101", "Главный", 3, start, start.Add(90*time.Minute), start, 1, "ИВТ-21", 5))
+	mock.ExpectQuery("FROM bell_schedule").
+		WillReturnRows(sqlmock.NewRows(exportBellColumns).
+			AddRow(1, 1, "", "09:00", "10:30").
+			AddRow(2, 2, "", "10:40", "12:10"))
+	mock.ExpectQuery("FROM academic_calendar").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "name", "start_date", "end_date", "transfer_from"}))
+
+	c, w := setupTestContextJSON("GET", "/admin/schedules/export?format=pdf&teacher=2&week=2025-09-03", "")
+	handlers.ExportSchedulesHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="schedule-2025-09-01.pdf"`)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportSchedulesHandler_RejectsBadParams(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	c, w := setupTestContextJSON("GET", "/admin/schedules/export?format=doc", "")
+	handlers.ExportSchedulesHandler(c, db)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	c, w = setupTestContextJSON("GET", "/admin/schedules/export?format=pdf&week=03.09.2025", "")
+	handlers.ExportSchedulesHandler(c, db)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,131 @@
+package main_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/generator"
+)
+
+func assertNoHardConflicts(t *testing.T, p generator.Problem, placements []generator.Placement) {
+	t.Helper()
+	type key struct{ id, day, pair int }
+	teachers := map[key]bool{}
+	rooms := map[key]bool{}
+	groups := map[key]bool{}
+	capacity := map[int]int{}
+	for _, r := range p.Rooms {
+		capacity[r.ID] = r.Capacity
+	}
+	for _, pl := range placements {
+		tk := key{pl.Lesson.TeacherID, pl.Slot.Day, pl.Slot.Pair}
+		rk := key{pl.RoomID, pl.Slot.Day, pl.Slot.Pair}
+		gk := key{pl.Lesson.GroupID, pl.Slot.Day, pl.Slot.Pair}
+		assert.False(t, teachers[tk], "у преподавателя два занятия в одном слоте")
+		assert.False(t, rooms[rk], "в аудитории два занятия в одном слоте")
+		assert.False(t, groups[gk], "у группы два занятия в одном слоте")
+		assert.False(t, p.BusyTeachers[pl.Lesson.TeacherID][pl.Slot], "занятие поставлено в занятый слот преподавателя")
+		assert.LessOrEqual(t, pl.Lesson.GroupSize, capacity[pl.RoomID], "группа не помещается в аудиторию")
+		teachers[tk], rooms[rk], groups[gk] = true, true, true
+	}
+}
+
+func TestGenerate_PlacesAllWithoutConflicts(t *testing.T) {
+	p := generator.Problem{
+		Days:  2,
+		Pairs: []int{1, 2, 3},
+		Rooms: []generator.Room{
+			{ID: 1, Capacity: 30, Building: "A"},
+			{ID: 2, Capacity: 15, Building: "B"},
+		},
+		BusyTeachers: map[int]map[generator.Slot]bool{
+			1: {{Day: 0, Pair: 1}: true},
+		},
+	}
+	for i := 0; i < 3; i++ {
+		p.Lessons = append(p.Lessons,
+			generator.Lesson{CurriculumID: 1, GroupID: 1, SubjectID: 1, TeacherID: 1, GroupSize: 25},
+			generator.Lesson{CurriculumID: 2, GroupID: 2, SubjectID: 2, TeacherID: 2, GroupSize: 10},
+		)
+	}
+
+	result := generator.Generate(p, 1, 500)
+	assert.Empty(t, result.Unplaced)
+	assert.Len(t, result.Placements, 6)
+	assertNoHardConflicts(t, p, result.Placements)
+	assert.Equal(t, 0, result.Report.GroupGaps)
+}
+
+func TestGenerate_ReportsUnplacedWhenNoRoomFits(t *testing.T) {
+	p := generator.Problem{
+		Days:    1,
+		Pairs:   []int{1, 2},
+		Rooms:   []generator.Room{{ID: 1, Capacity: 10}},
+		Lessons: []generator.Lesson{{CurriculumID: 7, GroupID: 1, SubjectID: 1, TeacherID: 1, GroupSize: 40}},
+	}
+
+	result := generator.Generate(p, 1, 100)
+	assert.Empty(t, result.Placements)
+	assert.Len(t, result.Unplaced, 1)
+	assert.Equal(t, 1, result.Report.Unplaced)
+}
+
+func TestGenerate_AvoidsTeacherPenaltiesAndGaps(t *testing.T) {
+	p := generator.Problem{
+		Days:  1,
+		Pairs: []int{1, 2, 3, 4},
+		Rooms: []generator.Room{{ID: 1, Capacity: 30, Building: "A"}},
+		TeacherPenalties: map[int]map[generator.Slot]int{
+			1: {{Day: 0, Pair: 1}: 5, {Day: 0, Pair: 2}: 5},
+		},
+		Lessons: []generator.Lesson{
+			{CurriculumID: 1, GroupID: 1, SubjectID: 1, TeacherID: 1, GroupSize: 20},
+			{CurriculumID: 1, GroupID: 1, SubjectID: 1, TeacherID: 1, GroupSize: 20},
+		},
+	}
+
+	result := generator.Generate(p, 42, 500)
+	assert.Len(t, result.Placements, 2)
+	assert.Equal(t, 0, result.Report.TeacherPreferencePenalty)
+	assert.Equal(t, 0, result.Report.GroupGaps)
+	assert.Equal(t, 0, result.Report.Score)
+}
+
+func TestEvaluate_CountsGapsAndBuildingChanges(t *testing.T) {
+	p := generator.Problem{
+		Rooms: []generator.Room{{ID: 1, Building: "A"}, {ID: 2, Building: "B"}},
+	}
+	lesson := generator.Lesson{GroupID: 1, TeacherID: 1}
+	placements := []generator.Placement{
+		{Lesson: lesson, Slot: generator.Slot{Day: 0, Pair: 1}, RoomID: 1},
+		{Lesson: lesson, Slot: generator.Slot{Day: 0, Pair: 4}, RoomID: 2},
+	}
+
+	rep := generator.Evaluate(p, placements)
+	assert.Equal(t, 2, rep.GroupGaps)
+	assert.Equal(t, 1, rep.BuildingChanges)
+	assert.Equal(t, 2*generator.DefaultWeights.GroupGap+generator.DefaultWeights.BuildingChange, rep.Score)
+}
+
+func TestGenerate_SchoolSizedWeek(t *testing.T) {
+	// 20 групп по 15 занятий в неделю: оценка кандидатов пересчитывается только для
+	// затронутого дня группы, поэтому такая неделя строится за доли секунды.
+	p := generator.Problem{Days: 6, Pairs: []int{1, 2, 3, 4, 5, 6}}
+	for r := 1; r <= 25; r++ {
+		p.Rooms = append(p.Rooms, generator.Room{ID: r, Capacity: 20 + r, Building: string(rune('A' + r%3))})
+	}
+	for g := 1; g <= 20; g++ {
+		for i := 0; i < 15; i++ {
+			p.Lessons = append(p.Lessons, generator.Lesson{
+				CurriculumID: g*100 + i, GroupID: g, SubjectID: i, TeacherID: (g*7+i)%30 + 1, GroupSize: 25,
+			})
+		}
+	}
+
+	result := generator.Generate(p, 1, 3000)
+	assert.Empty(t, result.Unplaced)
+	assert.Len(t, result.Placements, 300)
+	assertNoHardConflicts(t, p, result.Placements)
+	assert.Equal(t, generator.Evaluate(p, result.Placements), result.Report)
+}
@@ -0,0 +1,67 @@
+package main_test
+
+import (
+	"testing"
+	"time"
+
+	"scheduleApp/internal/calendar"
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ноябрьские праздники 2025: 4 ноября — праздник, понедельник 3 ноября — выходной,
+// отработанный в субботу 1 ноября; новогодние каникулы с 29 декабря по 11 января.
+var autumnCalendar = []models.CalendarPeriod{
+	{Kind: calendar.KindHoliday, Name: "День народного единства", StartDate: date(2025, 11, 3), EndDate: date(2025, 11, 4)},
+	{Kind: calendar.KindWorkday, Name: "Перенос", StartDate: date(2025, 11, 1), EndDate: date(2025, 11, 1), TransferFrom: date(2025, 11, 3)},
+	{Kind: calendar.KindVacation, Name: "Новогодние каникулы", StartDate: date(2025, 12, 29), EndDate: date(2026, 1, 11)},
+}
+
+func TestNonTeachingPeriod(t *testing.T) {
+	p := calendar.NonTeachingPeriod(autumnCalendar, time.Date(2025, 11, 4, 10, 0, 0, 0, time.UTC))
+	if assert.NotNil(t, p) {
+		assert.Equal(t, calendar.KindHoliday, p.Kind)
+	}
+	assert.Nil(t, calendar.NonTeachingPeriod(autumnCalendar, date(2025, 11, 5)))
+	assert.Nil(t, calendar.NonTeachingPeriod(autumnCalendar, date(2025, 11, 1)))
+	assert.NotNil(t, calendar.NonTeachingPeriod(autumnCalendar, date(2026, 1, 11)))
+}
+
+func TestDayNote(t *testing.T) {
+	assert.Equal(t, "Праздник: День народного единства", calendar.DayNote(autumnCalendar, date(2025, 11, 4)))
+	assert.Equal(t, "Рабочий день (перенос) за 03.11.2025", calendar.DayNote(autumnCalendar, date(2025, 11, 1)))
+	assert.Equal(t, "", calendar.DayNote(autumnCalendar, date(2025, 11, 5)))
+}
+
+func TestExpandLessonSeries_SkipsHolidaysAndHonoursTransfers(t *testing.T) {
+	s := models.LessonSeries{
+		StartDate:     date(2025, 10, 27),
+		EndDate:       date(2025, 11, 10),
+		StartClock:    "09:00",
+		IntervalWeeks: 1,
+		Calendar:      autumnCalendar,
+	}
+
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 10, 27, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 11, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 11, 10, 9, 0, 0, 0, time.UTC),
+	}, handlers.ExpandLessonSeries(s))
+}
+
+func TestExpandLessonSeries_SkipsVacation(t *testing.T) {
+	s := models.LessonSeries{
+		StartDate:     date(2025, 12, 24),
+		EndDate:       date(2026, 1, 14),
+		StartClock:    "13:30",
+		IntervalWeeks: 1,
+		Calendar:      autumnCalendar,
+	}
+
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 12, 24, 13, 30, 0, 0, time.UTC),
+		time.Date(2026, 1, 14, 13, 30, 0, 0, time.UTC),
+	}, handlers.ExpandLessonSeries(s))
+}
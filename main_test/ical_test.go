@@ -0,0 +1,157 @@
+package main_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/ical"
+)
+
+var scheduleViewColumns = []string{"id", "subject_name", "subject_id", "teacher_name", "teacher_id",
+	"room_number", "building", "classroom_id", "start_time", "end_time", "created_at", "revision",
+	"group_names", "group_id"}
+
+func TestCalendarWrite_FoldsAndEscapes(t *testing.T) {
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	cal := ical.Calendar{Name: "Группа ИВТ-21", Events: []ical.Event{{
+		UID:         "schedule-10@scheduleApp",
+		Sequence:    2,
+		Summary:     "Математический анализ; лекция, поток",
+		Location:    "Ауд. 101, Главный корпус",
+		Description: "Преподаватель: Иванов И.И.\nГруппы: ИВТ-21, ИВТ-22, ИВТ-23, ИВТ-24, ИВТ-25",
+		Start:       start,
+		End:         start.Add(90 * time.Minute),
+	}}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, cal.Write(&buf))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "SEQUENCE:2\r\n")
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	assert.Contains(t, unfolded, `SUMMARY:Математический анализ\; лекция\, поток`)
+	assert.Contains(t, unfolded, `DESCRIPTION:Преподаватель: Иванов И.И.\nГруппы: ИВТ-21\, ИВТ-22`)
+}
+
+func TestGroupCalendarFeedHandler_IncludesCancellations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT name FROM groups").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ИВТ-21"))
+	mock.ExpectQuery("FROM schedule s").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(scheduleViewColumns).
+			AddRow(10, "Физика", 1, "Иванов И.И.", 2, "101", "Главный корпус", 3, start, start.Add(90*time.Minute), start, 1, "ИВТ-21", 5))
+	mock.ExpectQuery("FROM dropped_lesson_groups dg").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(scheduleViewColumns))
+	mock.ExpectQuery("FROM cancelled_lessons cl").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"schedule_id", "subject_name", "teacher_name", "room_number", "building",
+			"group_names", "start_time", "end_time", "revision"}).
+			AddRow(11, "Химия", "Петров П.П.", "202", "", "ИВТ-21", start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(90*time.Minute), 3))
+
+	c, w := setupTestContextJSON("GET", "/ical/group/5.ics", "")
+	c.Params = gin.Params{{Key: "file", Value: "5.ics"}}
+	handlers.GroupCalendarFeedHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/calendar")
+	body := strings.ReplaceAll(w.Body.String(), "\r\n ", "")
+	assert.Contains(t, body, "UID:schedule-10@scheduleApp")
+	assert.Contains(t, body, `LOCATION:Ауд. 101\, Главный корпус`)
+	assert.Contains(t, body, "UID:schedule-11@scheduleApp\r\nDTSTAMP:")
+	assert.Contains(t, body, "SEQUENCE:3\r\n")
+	assert.Contains(t, body, "STATUS:CANCELLED")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGroupCalendarFeedHandler_DroppedGroupSeesCancellation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT name FROM groups").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ИВТ-21"))
+	mock.ExpectQuery("FROM schedule s").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(scheduleViewColumns))
+	// Занятие осталось у группы ИВТ-22, а ИВТ-21 с него сняли.
+	mock.ExpectQuery("FROM dropped_lesson_groups dg").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(scheduleViewColumns).
+			AddRow(10, "Физика", 1, "Иванов И.И.", 2, "101", "", 3, start, start.Add(90*time.Minute), start, 2, "ИВТ-22", 6))
+	mock.ExpectQuery("FROM cancelled_lessons cl").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"schedule_id", "subject_name", "teacher_name", "room_number", "building",
+			"group_names", "start_time", "end_time", "revision"}))
+
+	c, w := setupTestContextJSON("GET", "/ical/group/5.ics", "")
+	c.Params = gin.Params{{Key: "file", Value: "5.ics"}}
+	handlers.GroupCalendarFeedHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := strings.ReplaceAll(w.Body.String(), "\r\n ", "")
+	assert.Contains(t, body, "UID:schedule-10@scheduleApp")
+	assert.Contains(t, body, "SEQUENCE:2\r\n")
+	assert.Contains(t, body, "STATUS:CANCELLED")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateScheduleHandler_DroppedGroupBumpsRevision(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectLessonChecks(mock, 20, 30)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO lesson_series_exceptions").WithArgs(55).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schedule SET series_id = NULL").WithArgs(55).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE schedule\\s+SET subject_id").WillReturnResult(sqlmock.NewResult(0, 1))
+	// Группа 6 снята с занятия: она запоминается для лент, редакция повышается.
+	mock.ExpectExec("DELETE FROM schedule_groups").WithArgs(55, pq.Array([]int{5})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO schedule_groups").WithArgs(55, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM dropped_lesson_groups").WithArgs(55, pq.Array([]int{5})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schedule SET revision").WithArgs(55).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("PUT", "/api/v1/schedule/55", `{
+        "subject_id": 1, "teacher_id": 2, "classroom_id": 3, "group_ids": [5],
+        "start_time": "2025-09-01T08:00:00Z"
+    }`)
+	c.Params = gin.Params{{Key: "id", Value: "55"}}
+	handlers.UpdateScheduleHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserCalendarFeedHandler_UnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM users WHERE calendar_token").WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role"}))
+
+	c, w := setupTestContextJSON("GET", "/ical/user/nope.ics", "")
+	c.Params = gin.Params{{Key: "file", Value: "nope.ics"}}
+	handlers.UserCalendarFeedHandler(c, db)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
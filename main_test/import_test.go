@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+
+	"scheduleApp/internal/handlers"
+)
+
+func TestParseTimetableFile_CSVWithRussianHeaders(t *testing.T) {
+	data := "\ufeffПредмет;Преподаватель;Аудитория;Группы;Дата;Пара\n" +
+		"Физика;Иванов И.И.;101;ИВТ-21, ИВТ-22;02.09.2025;1\n" +
+		";;;;;\n" +
+		"Химия;Петров П.П.;202;ИВТ-21;2025-09-03;2\n"
+
+	rows, err := handlers.ParseTimetableFile("timetable.csv", strings.NewReader(data))
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, handlers.ImportRow{Line: 2, Subject: "Физика", Teacher: "Иванов И.И.", Room: "101",
+			Groups: "ИВТ-21, ИВТ-22", Date: "02.09.2025", Pair: "1"}, rows[0])
+		assert.Equal(t, 4, rows[1].Line)
+	}
+}
+
+func TestParseTimetableFile_XLSXWithWeekdays(t *testing.T) {
+	f := excelize.NewFile()
+	for i, row := range [][]interface{}{
+		{"subject", "teacher", "room", "building", "groups", "weekday", "time", "end"},
+		{"Физика", "Иванов И.И.", "101", "Главный", "ИВТ-21", "Пн", "09:00", "10:30"},
+	} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		assert.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, f.Write(&buf))
+
+	rows, err := handlers.ParseTimetableFile("timetable.xlsx", &buf)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "Главный", rows[0].Building)
+		assert.Equal(t, "Пн", rows[0].Weekday)
+		assert.Equal(t, "10:30", rows[0].EndTime)
+	}
+}
+
+func TestParseTimetableFile_MissingColumns(t *testing.T) {
+	_, err := handlers.ParseTimetableFile("timetable.csv", strings.NewReader("subject,teacher,room\nФизика,Иванов,101\n"))
+	assert.Error(t, err)
+	_, err = handlers.ParseTimetableFile("timetable.ods", strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestImportTimetable_DryRunReportsRowErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name FROM subjects").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Физика"))
+	mock.ExpectQuery("SELECT id, name FROM teachers").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Иванов И.И."))
+	mock.ExpectQuery("SELECT id, name FROM groups").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "ИВТ-21"))
+	mock.ExpectQuery("FROM classrooms").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "room_number", "building"}).AddRow(3, "101", "Главный"))
+	mock.ExpectQuery("FROM academic_calendar").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "name", "start_date", "end_date", "transfer_from"}))
+	mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM students WHERE group_id").
+		WillReturnRows(sqlmock.NewRows([]string{"students", "capacity"}).AddRow(25, 30))
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").WithArgs(2, 3, 5, end.Unix(), start.Unix()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teacher_unavailability").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("INSERT INTO schedule").WithArgs(1, 2, 3, start, end, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100))
+	expectNewLessonGroups(mock, 100)
+	mock.ExpectRollback()
+
+	rows := []handlers.ImportRow{
+		{Line: 2, Subject: "физика", Teacher: "Иванов  И.И.", Room: "101", Groups: "ИВТ-21", Date: "2025-09-02", Time: "9:00"},
+		{Line: 3, Subject: "Физика", Teacher: "Сидоров", Room: "404", Groups: "ИВТ-99", Weekday: "Пн", Pair: "1"},
+	}
+	report, err := handlers.ImportTimetable(db, rows, handlers.ImportPeriod{}, true)
+	assert.NoError(t, err)
+
+	assert.False(t, report.Committed)
+	assert.Equal(t, 1, report.LessonCount)
+	assert.Equal(t, 1, report.ErrorCount)
+	assert.Empty(t, report.Rows[0].Errors)
+	assert.Equal(t, start, report.Rows[0].First)
+	assert.Contains(t, report.Rows[1].Errors, `преподаватель "Сидоров" не найден`)
+	assert.Contains(t, report.Rows[1].Errors, `аудитория "404" не найдена`)
+	assert.Contains(t, report.Rows[1].Errors, `группа "ИВТ-99" не найдена`)
+	assert.Contains(t, report.Rows[1].Errors, "для строк с днём недели укажите период импорта")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportTimetable_ReportsEmptyGroupsAndOverbookedRooms(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name FROM subjects").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Физика"))
+	mock.ExpectQuery("SELECT id, name FROM teachers").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Иванов И.И."))
+	mock.ExpectQuery("SELECT id, name FROM groups").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "ИВТ-21").AddRow(6, "ИВТ-22"))
+	mock.ExpectQuery("FROM classrooms").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "room_number", "building"}).AddRow(3, "101", "Главный"))
+	mock.ExpectQuery("FROM academic_calendar").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "name", "start_date", "end_date", "transfer_from"}))
+	// Первая строка отклоняется ещё до обращения к расписанию, вторая — по вместимости.
+	mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM students WHERE group_id").
+		WillReturnRows(sqlmock.NewRows([]string{"students", "capacity"}).AddRow(52, 30))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	rows := []handlers.ImportRow{
+		{Line: 2, Subject: "Физика", Teacher: "Иванов И.И.", Room: "101", Groups: ",,", Date: "2025-09-02", Time: "9:00"},
+		{Line: 3, Subject: "Физика", Teacher: "Иванов И.И.", Room: "101", Groups: "ИВТ-21, ИВТ-22", Date: "2025-09-02", Time: "9:00"},
+	}
+	report, err := handlers.ImportTimetable(db, rows, handlers.ImportPeriod{}, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, report.ErrorCount)
+	assert.Equal(t, 0, report.LessonCount)
+	assert.Equal(t, []string{"не указаны группы"}, report.Rows[0].Errors)
+	assert.Equal(t, []string{"аудитория рассчитана на 30 мест, а в группах занятия 52 студентов"}, report.Rows[1].Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
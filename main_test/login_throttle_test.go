@@ -0,0 +1,188 @@
+package main_test
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/middleware"
+)
+
+// expectLoginFailure ожидает запись неудачной попытки: failures — новое значение
+// счётчика по имени пользователя, по IP-адресу счётчик равен 1.
+func expectLoginFailure(mock sqlmock.Sqlmock, username string, failures int, delaySeconds float64) {
+	mock.ExpectQuery("INSERT INTO login_throttle").WithArgs("account", username, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(failures))
+	if delaySeconds > 0 {
+		mock.ExpectExec("UPDATE login_throttle SET locked_until").WithArgs("account", username, delaySeconds).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery("INSERT INTO login_throttle").WithArgs("ip", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(1))
+}
+
+func TestLoginFormHandler_UniformErrorForUnknownUserAndWrongPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ghost", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery("FROM users").WithArgs("ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}))
+	expectLoginFailure(mock, "ghost", 1, 0)
+
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery("FROM users").WithArgs("ivanov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}).
+			AddRow(12, "ivanov", string(hash), "", "teacher", "active", true, false, false))
+	expectLoginFailure(mock, "ivanov", 4, 1)
+
+	var bodies []string
+	for _, username := range []string{"ghost", "ivanov"} {
+		c, w := setupTestFormContext("/login", url.Values{"username": {username}, "password": {"wrong"}})
+		handlers.LoginFormHandler(c, db)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Неверный логин или пароль")
+		bodies = append(bodies, w.Body.String())
+	}
+	assert.Equal(t, bodies[0], bodies[1], "ответ не должен выдавать, существует ли пользователь")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPITokenHandler_LockedOut(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(600.2))
+
+	c, w := setupTestContextJSON("POST", "/api/v1/auth/token", `{"username": "ivanov", "password": "secret"}`)
+	handlers.CreateAPITokenHandler(c, db)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "601", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Повторите через 11 мин")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetTrustedProxies_ForgedForwardedForKeepsThrottleKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gin.SetMode(gin.TestMode)
+	newRouter := func() *gin.Engine {
+		r := gin.New()
+		assert.NoError(t, middleware.SetTrustedProxies(r))
+		r.POST("/api/v1/auth/token", func(c *gin.Context) { handlers.CreateAPITokenHandler(c, db) })
+		return r
+	}
+	login := func(r *gin.Engine, forwardedFor string) int {
+		req, _ := http.NewRequest("POST", "/api/v1/auth/token", strings.NewReader(`{"username": "ivanov", "password": "secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "192.0.2.10:40000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Без TRUSTED_PROXIES ключ — адрес соединения, какой бы X-Forwarded-For ни прислали.
+	r := newRouter()
+	for _, forged := range []string{"203.0.113.1", "203.0.113.2"} {
+		mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", "192.0.2.10").
+			WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(600))
+		assert.Equal(t, http.StatusTooManyRequests, login(r, forged))
+	}
+
+	// Заголовку от настроенного прокси верят.
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.10")
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", "203.0.113.1").
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(600))
+	assert.Equal(t, http.StatusTooManyRequests, login(newRouter(), "203.0.113.1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClearLoginLockoutHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM login_throttle WHERE id").WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := setupTestContextJSON("POST", "/admin/login-lockouts/3?_method=DELETE", "")
+	c.Params = gin.Params{{Key: "id", Value: "3"}}
+	handlers.ClearLoginLockoutHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/admin/users", location.Path)
+	assert.Equal(t, "Блокировка снята", location.Query().Get("alarm"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// capturedArg запоминает значение аргумента запроса, чтобы использовать его дальше в тесте.
+type capturedArg struct {
+	value *string
+}
+
+func (a capturedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*a.value = s
+	return ok
+}
+
+func TestLoginFormHandler_CLIUserWithoutEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// server user create --username root --role admin, без --email.
+	var hash string
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").WithArgs("root", capturedArg{&hash}, "", "admin").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	userID, err := handlers.CreateAccount(db, handlers.NewAccount{Username: "root", Password: "secret", Role: "admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, userID)
+
+	// В базе email = NULL; запрос входа должен превращать его в пустую строку.
+	mock.ExpectQuery("FROM login_throttle").WithArgs("root", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery(`COALESCE\(email, ''\).*FROM users`).WithArgs("root").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}).
+			AddRow(1, "root", hash, "", "admin", "active", true, false, false))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("root").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO sessions").WithArgs(1, "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(1, 3, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	c, w := setupTestFormContext("/login", url.Values{"username": {"root"}, "password": {"secret"}})
+	handlers.LoginFormHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Привет, Администратор!")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,104 @@
+package main_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/mailer"
+)
+
+// startSMTPSink запускает простейший SMTP-сервер, который принимает одно письмо и
+// передаёт в канал его текст после DATA.
+func startSMTPSink(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 sink ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 sink")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 end with .")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSMTPMailer_DeliversToLocalSink(t *testing.T) {
+	addr, received := startSMTPSink(t)
+
+	m := mailer.SMTP{Addr: addr, From: "schedule@example.com"}
+	err := m.Send(mailer.Message{To: "ivanov@example.com", Subject: "Сброс пароля", Body: "Ссылка:\nhttps://schedule.example/reset"})
+	assert.NoError(t, err)
+
+	data := <-received
+	assert.Contains(t, data, "From: schedule@example.com\r\n")
+	assert.Contains(t, data, "To: ivanov@example.com\r\n")
+	assert.Contains(t, data, "Subject: =?utf-8?q?")
+	assert.Contains(t, data, "charset=UTF-8")
+	assert.Contains(t, data, "Ссылка:\r\nhttps://schedule.example/reset")
+}
+
+func TestFileMailer_WritesEmlFile(t *testing.T) {
+	dir := t.TempDir()
+	m := mailer.File{Dir: dir}
+	assert.NoError(t, m.Send(mailer.Message{To: "ivanov@example.com", Subject: "Проверка", Body: "Текст"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*-ivanov@example.com.eml"))
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		content, err := os.ReadFile(files[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "Текст")
+	}
+}
+
+func TestFileMailer_RejectsNewlineInRecipient(t *testing.T) {
+	dir := t.TempDir()
+	m := mailer.File{Dir: dir}
+	err := m.Send(mailer.Message{To: "ivanov@example.com\r\nBcc: victim@example.com", Subject: "Проверка", Body: "Текст"})
+	assert.Error(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
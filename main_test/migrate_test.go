@@ -0,0 +1,55 @@
+package main_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/db"
+)
+
+func TestLoadMigrations_OrderedAndPaired(t *testing.T) {
+	migrations, err := db.LoadMigrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, "пустой up у миграции %d", m.Version)
+		assert.NotEmpty(t, m.Down, "пустой down у миграции %d", m.Version)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestMigrateUp_AppliesOnlyPending(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	migrations, err := db.LoadMigrations()
+	assert.NoError(t, err)
+	last := migrations[len(migrations)-1]
+
+	applied := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, m := range migrations[:len(migrations)-1] {
+		applied.AddRow(m.Version, time.Now())
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(applied)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(last.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(last.Version, last.Name).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, db.MigrateUp(conn))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
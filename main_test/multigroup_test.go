@@ -0,0 +1,67 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+)
+
+func TestCheckScheduleCollisionForGroups_ChecksEveryGroup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").
+		WithArgs(2, 3, 10, end.Unix(), start.Unix()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teacher_unavailability").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").
+		WithArgs(2, 3, 11, end.Unix(), start.Unix()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	collision, err := handlers.CheckScheduleCollisionForGroups(db, 2, 3, []int{10, 11, 12}, start, end, 0)
+	assert.NoError(t, err)
+	assert.True(t, collision)
+	// Третья группа не проверяется: коллизия уже найдена.
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetScheduleJSON_ReturnsAllGroups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	c, w := setupTestContextJSON("GET", "/admin/schedules/77/json", "")
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "77"})
+
+	start := time.Date(2025, 9, 2, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT .* FROM schedule").
+		WithArgs("77").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "subject_id", "teacher_id", "classroom_id", "start_time", "end_time",
+			"pair_number", "group_id", "group_ids", "series_id",
+		}).AddRow(77, 1, 2, 3, start, start.Add(90*time.Minute), 0, 4, "{4,5,9}", 0))
+
+	handlers.GetScheduleJSON(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		GroupID  int   `json:"group_id"`
+		GroupIDs []int `json:"group_ids"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 4, body.GroupID)
+	assert.Equal(t, []int{4, 5, 9}, body.GroupIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,194 @@
+package main_test
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/mailer"
+)
+
+// recordingMailer запоминает письма вместо отправки.
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func useRecordingMailer(t *testing.T) *recordingMailer {
+	m := &recordingMailer{}
+	assert.NoError(t, handlers.ConfigureMail(m, "https://schedule.example"))
+	t.Cleanup(func() { handlers.ConfigureMail(mailer.File{}, "https://schedule.example") })
+	return m
+}
+
+// expectAccountToken ожидает выдачу нового токена для письма пользователю userID.
+func expectAccountToken(mock sqlmock.Sqlmock, userID int, purpose string) {
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(userID, purpose, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE account_tokens SET used_at").WithArgs(userID, purpose).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO account_tokens").WithArgs(userID, purpose, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+func TestForgotPasswordHandler_SendsSingleUseLink(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	sent := useRecordingMailer(t)
+
+	mock.ExpectQuery("FROM users").WithArgs("ivanov@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}).AddRow(12, "ivanov", "ivanov@example.com"))
+	expectAccountToken(mock, 12, "reset_password")
+
+	c, w := setupTestFormContext("/forgot-password", url.Values{"login": {" ivanov@example.com "}})
+	handlers.ForgotPasswordHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "отправлена ссылка для сброса пароля")
+	if assert.Len(t, sent.sent, 1) {
+		assert.Equal(t, "ivanov@example.com", sent.sent[0].To)
+		assert.Regexp(t, regexp.MustCompile(`https://schedule\.example/reset-password\?token=[\w-]{43}`), sent.sent[0].Body)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForgotPasswordHandler_IgnoresForgedHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	sent := useRecordingMailer(t)
+
+	mock.ExpectQuery("FROM users").WithArgs("ivanov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}).AddRow(12, "ivanov", "ivanov@example.com"))
+	expectAccountToken(mock, 12, "reset_password")
+
+	c, _ := setupTestFormContext("/forgot-password", url.Values{"login": {"ivanov"}})
+	c.Request.Host = "attacker.example"
+	c.Request.Header.Set("X-Forwarded-Proto", "https")
+	handlers.ForgotPasswordHandler(c, db)
+
+	if assert.Len(t, sent.sent, 1) {
+		assert.Contains(t, sent.sent[0].Body, "https://schedule.example/reset-password?token=")
+		assert.NotContains(t, sent.sent[0].Body, "attacker.example")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigureMail_SMTPRequiresBaseURL(t *testing.T) {
+	assert.Error(t, handlers.ConfigureMail(mailer.SMTP{Addr: "smtp.example:25", From: "schedule@example.com"}, ""))
+	assert.NoError(t, handlers.ConfigureMail(mailer.File{}, ""))
+	t.Cleanup(func() { handlers.ConfigureMail(mailer.File{}, "https://schedule.example") })
+}
+
+func TestForgotPasswordHandler_UnknownUserGetsSameAnswer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	sent := useRecordingMailer(t)
+
+	mock.ExpectQuery("FROM users").WithArgs("ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}))
+
+	c, w := setupTestFormContext("/forgot-password", url.Values{"login": {"ghost"}})
+	handlers.ForgotPasswordHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "отправлена ссылка для сброса пароля")
+	assert.Empty(t, sent.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPasswordHandler_SetsPasswordAndEndsSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE account_tokens SET used_at").WithArgs(sqlmock.AnyArg(), "reset_password").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(12))
+	mock.ExpectQuery("UPDATE users SET password").WithArgs(sqlmock.AnyArg(), 12).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("ivanov"))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("ivanov").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE sessions SET revoked_at").WithArgs(12).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	c, w := setupTestFormContext("/reset-password", url.Values{
+		"token":            {"reset-token"},
+		"password":         {"new-secret"},
+		"password_confirm": {"new-secret"},
+	})
+	handlers.ResetPasswordHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/login", location.Path)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPasswordHandler_UsedTokenRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE account_tokens SET used_at").WithArgs(sqlmock.AnyArg(), "reset_password").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectRollback()
+
+	c, w := setupTestFormContext("/reset-password", url.Values{
+		"token":            {"used-token"},
+		"password":         {"new-secret"},
+		"password_confirm": {"new-secret"},
+	})
+	handlers.ResetPasswordHandler(c, db)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Ссылка недействительна или устарела")
+	assert.NotContains(t, w.Body.String(), `name="password"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPITokenHandler_UnverifiedEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	sent := useRecordingMailer(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM login_throttle").WithArgs("petrov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery("FROM users").WithArgs("petrov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}).
+			AddRow(14, "petrov", string(hash), "petrov@example.com", "student", "active", false, false, false))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("petrov").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectAccountToken(mock, 14, "verify_email")
+
+	c, w := setupTestContextJSON("POST", "/api/v1/auth/token", `{"username": "petrov", "password": "secret"}`)
+	handlers.CreateAPITokenHandler(c, db)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "не подтверждён")
+	assert.NotContains(t, w.Body.String(), "refresh_token")
+	if assert.Len(t, sent.sent, 1) {
+		assert.Contains(t, sent.sent[0].Body, "https://schedule.example/verify-email?token=")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
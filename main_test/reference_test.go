@@ -0,0 +1,129 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+)
+
+func TestSaveSubjectAPIHandler_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM subjects").WithArgs("Физика", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("INSERT INTO subjects").WithArgs("Физика", "Общий курс").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	c, w := setupTestContextJSON("POST", "/api/v1/subjects", `{"name": "  Физика ", "description": "Общий курс"}`)
+	handlers.SaveSubjectAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var s models.Subject
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, 7, s.ID)
+	assert.Equal(t, "Физика", s.Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveGroupAPIHandler_RejectsDuplicateName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM groups").WithArgs("ИВТ-21", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	c, w := setupTestContextJSON("PUT", "/api/v1/groups/3", `{"name": "ИВТ-21", "course": "2"}`)
+	c.Params = gin.Params{{Key: "id", Value: "3"}}
+	handlers.SaveGroupAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "уже есть")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveClassroomAPIHandler_Validation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for _, body := range []string{
+		`{"room_number": "  ", "capacity": 30}`,
+		`{"room_number": "101", "capacity": -1}`,
+	} {
+		c, w := setupTestContextJSON("POST", "/api/v1/classrooms", body)
+		handlers.SaveClassroomAPIHandler(c, db)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveClassroomAPIHandler_UpdateNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM classrooms").WithArgs("101", "Главный", 99).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE classrooms").WithArgs(99, "101", "Главный", 40).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w := setupTestContextJSON("PUT", "/api/v1/classrooms/99", `{"room_number": "101", "building": "Главный", "capacity": 40}`)
+	c.Params = gin.Params{{Key: "id", Value: "99"}}
+	handlers.SaveClassroomAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteSubjectAPIHandler_RefusesWhenScheduled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM schedule WHERE subject_id").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("FROM lesson_series WHERE subject_id").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM curriculum WHERE subject_id").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	c, w := setupTestContextJSON("DELETE", "/api/v1/subjects/5", "")
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	handlers.DeleteSubjectAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "занятий в расписании — 3")
+	assert.Contains(t, w.Body.String(), "строк учебного плана — 1")
+	assert.NotContains(t, w.Body.String(), "серий")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteGroupAPIHandler_Unused(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for _, table := range []string{"schedule_groups", "lesson_series_groups", "curriculum", "students"} {
+		mock.ExpectQuery("FROM " + table + " WHERE group_id").WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	}
+	mock.ExpectExec("DELETE FROM groups").WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, _ := setupTestContextJSON("DELETE", "/api/v1/groups/4", "")
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+	handlers.DeleteGroupAPIHandler(c, db)
+
+	// Без тела ответа заголовок записывает сам gin после обработчика.
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
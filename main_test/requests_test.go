@@ -0,0 +1,204 @@
+package main_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+)
+
+func lessonRows(id, teacherID, classroomID int, start time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "teacher_id", "classroom_id", "pair_number", "start_time", "end_time", "group_ids"}).
+		AddRow(id, teacherID, classroomID, 0, start, start.Add(90*time.Minute), "{5}")
+}
+
+func requestRow(id, scheduleID int, status, kind, text string, lessonStart time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "user_id", "schedule_id", "desired_change", "status", "kind", "proposed_start",
+		"proposed_classroom_id", "proposed_teacher_id", "swap_schedule_id", "proposed_room", "proposed_teacher",
+		"created_at", "requester_name", "requester_role", "subject_name", "teacher_name", "room_number",
+		"lesson_start", "group_names",
+	}).AddRow(id, 1, scheduleID, text, status, kind, nil, 0, 0, 0, "", "",
+		lessonStart.AddDate(0, 0, -3), "Иванов И.И.", "teacher", "Физика", "Иванов И.И.", "101",
+		lessonStart, "ИВТ-21")
+}
+
+func TestCreateRequestHandler_RejectsConflictingClassroom(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM schedule s").WithArgs(10).WillReturnRows(lessonRows(10, 2, 3, start))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(10, 1).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM schedule s").WithArgs(10).WillReturnRows(lessonRows(10, 2, 3, start))
+	mock.ExpectExec("INSERT INTO lesson_series_exceptions").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schedule SET series_id = NULL").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE schedule SET classroom_id").WithArgs(7, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM schedule s").WithArgs(10).WillReturnRows(lessonRows(10, 2, 7, start))
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	c, w := setupTestContextJSON("POST", "/api/requests", `{
+        "schedule_id": 10,
+        "kind": "classroom",
+        "proposed_classroom_id": 7
+    }`)
+	c.Set("user_id", 1)
+	c.Set("role", "teacher")
+	handlers.CreateRequestHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Коллизия")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateRequestHandler_RefusesProposalForOtherLesson(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM schedule s").WithArgs(10).WillReturnRows(lessonRows(10, 2, 3, start))
+	// Студент не учится ни в одной из групп занятия.
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(10, 8).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	c, w := setupTestContextJSON("POST", "/api/requests", `{"schedule_id": 10, "kind": "cancel"}`)
+	c.Set("user_id", 8)
+	c.Set("role", "student")
+	handlers.CreateRequestHandler(c, db)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "только по своим занятиям")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessRequestFormHandler_AppliesCancellation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM requests r").WithArgs(4).
+		WillReturnRows(requestRow(4, 10, "pending", "cancel", "Заболел", start))
+	mock.ExpectQuery("FROM schedule s").WithArgs(10).WillReturnRows(lessonRows(10, 2, 3, start))
+	mock.ExpectExec("INSERT INTO lesson_series_exceptions").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schedule").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE requests SET status").WithArgs("approved", 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO request_events").WithArgs(4, 99, "approved", "").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO request_events").WithArgs(4, 99, "applied", "Отмена занятия").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/admin/requests/4?_action=approve", nil)
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+	c.Set("user_id", 99)
+	handlers.ProcessRequestFormHandler(c, db, "approve")
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawRequestHandler_OwnPendingRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT user_id, status FROM requests").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(1, "pending"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE requests SET status = 'withdrawn'").WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO request_events").WithArgs(4, 1, "withdrawn", "").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/student/requests/4/withdraw", nil)
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+	c.Set("user_id", 1)
+	c.Set("role", "student")
+	handlers.WithdrawRequestHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	assert.Contains(t, w.Header().Get("Location"), "/student/requests")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawRequestHandler_ForeignRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT user_id, status FROM requests").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(2, "pending"))
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/teacher/requests/4/withdraw", nil)
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+	c.Set("user_id", 1)
+	c.Set("role", "teacher")
+	handlers.WithdrawRequestHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBulkProcessRequestsHandler_ContinuesPastFailures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM requests r").WithArgs(4).
+		WillReturnRows(requestRow(4, 10, "pending", "text", "Перенесите, пожалуйста", start))
+	mock.ExpectExec("UPDATE requests SET status").WithArgs("rejected", 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO request_events").WithArgs(4, 99, "rejected", "Нет свободных аудиторий").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM requests r").WithArgs(5).
+		WillReturnRows(requestRow(5, 11, "approved", "text", "Уже решено", start))
+	mock.ExpectRollback()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	form := url.Values{
+		"request_id": {"4", "5"},
+		"reason":     {"Нет свободных аудиторий"},
+		"filters":    {"status=pending&role=teacher"},
+	}
+	c.Request, _ = http.NewRequest("POST", "/admin/requests/bulk?_action=reject", strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.Set("user_id", 99)
+	handlers.BulkProcessRequestsHandler(c, db, "reject")
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "teacher", location.Query().Get("role"))
+	assert.Contains(t, location.Query().Get("alarm"), "Обработано запросов: 1")
+	assert.Contains(t, location.Query().Get("alarm"), "Запрос #5 уже обработан")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
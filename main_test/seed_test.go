@@ -0,0 +1,115 @@
+package main_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+)
+
+func TestParseSeedFile_YAMLMatchesJSON(t *testing.T) {
+	f, err := os.Open("../deployment/seed.example.yaml")
+	assert.NoError(t, err)
+	defer f.Close()
+	fromYAML, err := handlers.ParseSeedFile("seed.example.yaml", f)
+	assert.NoError(t, err)
+
+	fromJSON, err := handlers.ParseSeedFile("seed.json", strings.NewReader(`{
+        "departments": [{"name": "Кафедра информатики"}],
+        "groups": [{"name": "ИВТ-21", "course": "2"}],
+        "subjects": [{"name": "Программирование", "description": "Основы программирования"}],
+        "classrooms": [{"room_number": "101", "building": "Главный", "capacity": 30}],
+        "bells": [{"pair_number": 1, "start_clock": "09:00", "end_clock": "10:30"},
+                  {"pair_number": 2, "start_clock": "10:40", "end_clock": "12:10"}]
+    }`))
+	assert.NoError(t, err)
+	assert.Equal(t, fromJSON, fromYAML)
+	assert.Equal(t, "101", fromYAML.Classrooms[0].RoomNumber)
+}
+
+func TestParseSeedFile_RejectsUnknownKeysAndFormats(t *testing.T) {
+	_, err := handlers.ParseSeedFile("seed.yaml", strings.NewReader("teachers:\n  - name: Иванов\n"))
+	assert.Error(t, err)
+
+	_, err = handlers.ParseSeedFile("seed.txt", strings.NewReader("{}"))
+	assert.Error(t, err)
+}
+
+func TestSeedReferenceData_UpdatesExistingAndAddsNew(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	// Группа уже есть — обновляется по найденному id.
+	mock.ExpectQuery("SELECT id FROM groups").WithArgs("ИВТ-21").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM groups").WithArgs("ИВТ-21", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE groups").WithArgs(5, "ИВТ-21", "3").WillReturnResult(sqlmock.NewResult(0, 1))
+	// Звонка ещё нет — добавляется.
+	mock.ExpectQuery("FROM bell_schedule").WithArgs("", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("INSERT INTO bell_schedule").WithArgs(1, "", "09:00", "10:30").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := handlers.SeedReferenceData(db, handlers.SeedData{
+		Groups: []models.Group{{Name: "ИВТ-21", Course: "3"}},
+		Bells:  []models.BellPeriod{{PairNumber: 1, StartClock: "09:00", EndClock: "10:30"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Updated["группы"])
+	assert.Equal(t, 1, report.Created["пары"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSeedReferenceData_InvalidBellRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err = handlers.SeedReferenceData(db, handlers.SeedData{
+		Bells: []models.BellPeriod{{PairNumber: 1, StartClock: "10:30", EndClock: "09:00"}},
+	})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAccount_StudentInUnknownGroup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("student1", sqlmock.AnyArg(), "", "student").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT id FROM groups").WithArgs("ИВТ-99").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	_, err = handlers.CreateAccount(db, handlers.NewAccount{
+		Username: "student1", Password: "secret", Role: "student", Group: "ИВТ-99",
+	})
+	assert.EqualError(t, err, "группа «ИВТ-99» не найдена")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAccount_RejectsUnknownRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = handlers.CreateAccount(db, handlers.NewAccount{Username: "root", Password: "secret", Role: "superuser"})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
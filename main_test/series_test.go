@@ -0,0 +1,169 @@
+package main_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestExpandLessonSeries_Weekly(t *testing.T) {
+	s := models.LessonSeries{
+		StartDate:     date(2025, 9, 1),
+		EndDate:       date(2025, 9, 29),
+		StartClock:    "10:15",
+		IntervalWeeks: 1,
+		Exceptions:    []time.Time{date(2025, 9, 15)},
+	}
+
+	got := handlers.ExpandLessonSeries(s)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 9, 1, 10, 15, 0, 0, time.UTC),
+		time.Date(2025, 9, 8, 10, 15, 0, 0, time.UTC),
+		time.Date(2025, 9, 22, 10, 15, 0, 0, time.UTC),
+		time.Date(2025, 9, 29, 10, 15, 0, 0, time.UTC),
+	}, got)
+}
+
+func TestExpandLessonSeries_BiWeekly(t *testing.T) {
+	s := models.LessonSeries{
+		StartDate:     date(2025, 9, 3),
+		EndDate:       date(2025, 10, 14),
+		StartClock:    "08:00",
+		IntervalWeeks: 2,
+	}
+
+	got := handlers.ExpandLessonSeries(s)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 9, 3, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, 9, 17, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC),
+	}, got)
+}
+
+func TestExpandLessonSeries_InvalidClock(t *testing.T) {
+	s := models.LessonSeries{
+		StartDate:  date(2025, 9, 1),
+		EndDate:    date(2025, 9, 30),
+		StartClock: "25:99",
+	}
+	assert.Empty(t, handlers.ExpandLessonSeries(s))
+}
+
+func TestDeleteScheduleAPIHandler_KeepsExceptionAndDeleteTogether(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO lesson_series_exceptions").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM schedule").WithArgs(10).WillReturnError(errors.New("connection reset"))
+	// Исключение серии не остаётся без удалённого занятия.
+	mock.ExpectRollback()
+
+	c, w := setupTestContextJSON("DELETE", "/api/v1/schedule/10", "")
+	c.Params = gin.Params{{Key: "id", Value: "10"}}
+	handlers.DeleteScheduleAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateScheduleHandler_DetachesOccurrenceFromSeries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectLessonChecks(mock, 20, 30)
+	mock.ExpectBegin()
+	// Дата занятия уходит в исключения серии, а само занятие — из серии, поэтому
+	// следующее изменение всей серии его не перезапишет.
+	mock.ExpectExec("INSERT INTO lesson_series_exceptions").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE schedule SET series_id = NULL").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE schedule\\s+SET subject_id").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM schedule_groups").WithArgs(10, pq.Array([]int{5})).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schedule_groups").WithArgs(10, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("PUT", "/api/v1/schedule/10", `{
+        "subject_id": 1, "teacher_id": 2, "classroom_id": 3, "group_ids": [5],
+        "start_time": "2025-09-01T08:00:00Z"
+    }`)
+	c.Params = gin.Params{{Key: "id", Value: "10"}}
+	handlers.UpdateScheduleHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateScheduleFormHandler_SeriesScopeSplitsOffPastOccurrences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	oldStart := time.Date(2030, 9, 9, 9, 0, 0, 0, time.UTC)
+	newStart := time.Date(2030, 9, 10, 10, 0, 0, 0, time.UTC)
+	lastPast := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM students WHERE group_id").
+		WillReturnRows(sqlmock.NewRows([]string{"students", "capacity"}).AddRow(10, 30))
+	mock.ExpectQuery("SELECT COALESCE\\(series_id, 0\\), start_time FROM schedule").WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"series_id", "start_time"}).AddRow(4, oldStart))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT MAX\\(start_time\\) FROM schedule").WithArgs(4, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(lastPast))
+	// Прошедшее занятие 01.09.2025 остаётся в серии 4, продолжение с 08.09.2025 — серия 9.
+	mock.ExpectQuery("SELECT start_date, end_date, interval_weeks FROM lesson_series").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"start_date", "end_date", "interval_weeks"}).
+			AddRow(date(2025, 9, 1), date(2030, 12, 29), 1))
+	mock.ExpectQuery("INSERT INTO lesson_series").WithArgs(4, date(2025, 9, 8)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO lesson_series_groups").WithArgs(4, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE lesson_series_exceptions SET series_id").WithArgs(4, 9, date(2025, 9, 8)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE lesson_series SET end_date").WithArgs(4, date(2025, 9, 1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE lesson_series\\s+SET subject_id").WithArgs(1, 2, 3, "10:00", nil, 1, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE lesson_series_exceptions SET exception_date").WithArgs(1, 9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE schedule").
+		WithArgs(1, 2, 3, nil, newStart.Sub(oldStart).Seconds(), 4, 10, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_time", "end_time"}).
+			AddRow(10, newStart, newStart.Add(90*time.Minute)))
+	mock.ExpectExec("DELETE FROM lesson_series_groups").WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO lesson_series_groups").WithArgs(9, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Состав групп не изменился — редакция занятия не повышается.
+	mock.ExpectExec("DELETE FROM schedule_groups").WithArgs(10, pq.Array([]int{5})).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schedule_groups").WithArgs(10, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM academic_calendar").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "name", "start_date", "end_date", "transfer_from"}))
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT s.id\\)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM teacher_unavailability").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectCommit()
+
+	c, _ := setupTestFormContext("/admin/schedules/10", url.Values{
+		"subject_id": {"1"}, "teacher_id": {"2"}, "classroom_id": {"3"}, "group_id": {"5"},
+		"start_time": {"2030-09-10T10:00"}, "scope": {"series"},
+	})
+	c.Params = gin.Params{{Key: "id", Value: "10"}}
+	handlers.UpdateScheduleFormHandler(c, db)
+
+	assert.Equal(t, "Серия занятий успешно обновлена.", c.GetString("Alarm"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,143 @@
+package main_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/models"
+)
+
+func TestAuthenticate_RejectsRevokedSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, middleware.SetKeys("test", map[string][]byte{"test": newTestKey}))
+	token, err := middleware.GenerateJWT(models.User{ID: 7, Role: "teacher"}, 5)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM sessions s").WithArgs(5, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "status", "active"}).AddRow("teacher", "active", false))
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/schedule", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	middleware.Authenticate(db)(c)
+
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Сеанс завершён")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthenticate_HidesDatabaseErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, middleware.SetKeys("test", map[string][]byte{"test": newTestKey}))
+	token, err := middleware.GenerateJWT(models.User{ID: 7, Role: "teacher"}, 5)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM sessions s").WithArgs(5, 7).
+		WillReturnError(errors.New(`pq: relation "sessions" does not exist`))
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/schedule", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	middleware.Authenticate(db)(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Сеанс недействителен")
+	assert.NotContains(t, w.Body.String(), "relation")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthenticate_RoleChangeRefreshesPageToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, middleware.SetKeys("test", map[string][]byte{"test": newTestKey}))
+	token, err := middleware.GenerateJWT(models.User{ID: 7, Role: "teacher"}, 5)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM sessions s").WithArgs(5, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "status", "active"}).AddRow("admin", "active", true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM refresh_tokens rt").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(1, 5, time.Now().Add(time.Hour), nil, nil, nil, 7, "ivanov", "", "admin", "active", true))
+	mock.ExpectExec("UPDATE sessions SET last_seen_at").WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(7, 5, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = NOW\\(\\), replaced_by").WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/schedules", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "token", Value: token})
+	c.Request.AddCookie(&http.Cookie{Name: "refresh_token", Value: "refresh-1"})
+	middleware.Authenticate(db)(c)
+
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, "admin", c.GetString("role"), "роль берётся из базы, а не из старого токена")
+	assert.Equal(t, 5, c.GetInt("session_id"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeAllSessionsHandler_LogsOutEverywhere(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE sessions SET revoked_at = NOW\\(\\) WHERE user_id").WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	c, w := setupTestContextJSON("POST", "/sessions/revoke-all", "")
+	c.Set("user_id", 7)
+	c.Set("session_id", 5)
+	handlers.RevokeAllSessionsHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/login", location.Path)
+	cookies := strings.Join(w.Header().Values("Set-Cookie"), "\n")
+	assert.Contains(t, cookies, "token=;")
+	assert.Contains(t, cookies, "refresh_token=;")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeSessionAPIHandler_OtherUsersSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE sessions SET revoked_at = NOW\\(\\) WHERE id").WithArgs(9, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w := setupTestContextJSON("DELETE", "/api/v1/sessions/9", "")
+	c.Params = gin.Params{{Key: "id", Value: "9"}}
+	c.Set("user_id", 7)
+	handlers.RevokeSessionAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,223 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"scheduleApp/internal/handlers"
+	"scheduleApp/internal/middleware"
+	"scheduleApp/internal/models"
+)
+
+var (
+	oldTestKey = []byte("old-secret-old-secret-old-secret!")
+	newTestKey = []byte("new-secret-new-secret-new-secret!")
+)
+
+func TestJWTKeyRotation(t *testing.T) {
+	user := models.User{ID: 3, Role: "teacher"}
+	assert.NoError(t, middleware.SetKeys("2025-01", map[string][]byte{"2025-01": oldTestKey}))
+	oldToken, err := middleware.GenerateJWT(user, 1)
+	assert.NoError(t, err)
+
+	// Новый ключ подписывает, старый ещё принимается.
+	assert.NoError(t, middleware.SetKeys("2025-06", map[string][]byte{"2025-06": newTestKey, "2025-01": oldTestKey}))
+	newToken, err := middleware.GenerateJWT(user, 1)
+	assert.NoError(t, err)
+	for _, token := range []string{oldToken, newToken} {
+		claims := &middleware.JWTClaims{}
+		assert.NoError(t, middleware.ParseJWT(token, claims))
+		assert.Equal(t, 3, claims.UserID)
+	}
+
+	// Старый ключ удалён — подписанные им токены больше не действуют.
+	assert.NoError(t, middleware.SetKeys("2025-06", map[string][]byte{"2025-06": newTestKey}))
+	assert.Error(t, middleware.ParseJWT(oldToken, &middleware.JWTClaims{}))
+	assert.NoError(t, middleware.ParseJWT(newToken, &middleware.JWTClaims{}))
+}
+
+func TestSetKeys_Validation(t *testing.T) {
+	assert.Error(t, middleware.SetKeys("a", map[string][]byte{"a": []byte("short")}))
+	assert.Error(t, middleware.SetKeys("missing", map[string][]byte{"a": newTestKey}))
+}
+
+func TestLoadAuthConfig_KeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt.keys")
+	content := "# первая строка подписывает\n2025-06 " + string(newTestKey) + "\n\n2025-01 " + string(oldTestKey) + "\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	t.Setenv("JWT_KEY_FILE", path)
+	t.Setenv("ACCESS_TOKEN_TTL", "5m")
+	defer func() { middleware.AccessTokenTTL = 15 * time.Minute }()
+
+	assert.NoError(t, middleware.LoadAuthConfig())
+	assert.Equal(t, 5*time.Minute, middleware.AccessTokenTTL)
+
+	token, err := middleware.GenerateJWT(models.User{ID: 1, Role: "admin"}, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, middleware.SetKeys("2025-06", map[string][]byte{"2025-06": newTestKey}))
+	assert.NoError(t, middleware.ParseJWT(token, &middleware.JWTClaims{}), "токен подписан первым ключом файла")
+
+	t.Setenv("ACCESS_TOKEN_TTL", "soon")
+	assert.Error(t, middleware.LoadAuthConfig())
+}
+
+func TestLoadAuthConfig_RequiresKeysOutsideDevelopment(t *testing.T) {
+	t.Setenv("JWT_KEY_FILE", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("APP_ENV", "")
+	assert.Error(t, middleware.LoadAuthConfig())
+
+	t.Setenv("APP_ENV", "development")
+	assert.NoError(t, middleware.LoadAuthConfig())
+}
+
+// refreshTokenColumns — строка, которую RefreshTokens читает по refresh-токену.
+var refreshTokenColumns = []string{"id", "session_id", "expires_at", "revoked_at", "replaced_by", "session_revoked_at",
+	"user_id", "username", "email", "role", "status", "second_factor_ok"}
+
+func TestAuthenticate_RefreshesExpiredPageSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM refresh_tokens rt").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(1, 5, time.Now().Add(time.Hour), nil, nil, nil, 7, "ivanov", "", "teacher", "active", true))
+	mock.ExpectExec("UPDATE sessions SET last_seen_at").WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(7, 5, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = NOW\\(\\), replaced_by").WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/teacher/schedule", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "token", Value: "expired.jwt.value"})
+	c.Request.AddCookie(&http.Cookie{Name: "refresh_token", Value: "refresh-1"})
+	middleware.Authenticate(db)(c)
+
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, 7, c.GetInt("user_id"))
+	assert.Equal(t, "teacher", c.GetString("role"))
+	assert.Equal(t, 5, c.GetInt("session_id"))
+	cookies := strings.Join(w.Header().Values("Set-Cookie"), "\n")
+	assert.Contains(t, cookies, "token=")
+	assert.Contains(t, cookies, "refresh_token=")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokens_ReuseRevokesAllSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM refresh_tokens rt").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(1, 5, time.Now().Add(time.Hour), time.Now().Add(-time.Hour), 2, nil, 7, "ivanov", "", "teacher", "active", true))
+	mock.ExpectExec("UPDATE sessions SET revoked_at = NOW\\(\\) WHERE user_id").WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	_, _, _, err = middleware.RefreshTokens(db, "stolen")
+	assert.Equal(t, middleware.ErrInvalidRefreshToken, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokens_RoleRequiringSecondFactorEndsSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Преподавателя назначили администратором, а второй фактор он ещё не настроил.
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM refresh_tokens rt").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(1, 5, time.Now().Add(time.Hour), nil, nil, nil, 7, "ivanov", "", "admin", "active", false))
+	mock.ExpectExec("UPDATE sessions SET revoked_at = NOW\\(\\) WHERE id").WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, access, refresh, err := middleware.RefreshTokens(db, "refresh-1")
+	assert.Equal(t, middleware.ErrInvalidRefreshToken, err)
+	assert.Empty(t, access)
+	assert.Empty(t, refresh)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshAPITokenHandler_JustRotatedTokenConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Токен заменён секунду назад другим запросом того же клиента.
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM refresh_tokens rt").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(1, 5, time.Now().Add(time.Hour), time.Now().Add(-time.Second), 2, nil, 7, "ivanov", "", "teacher", "active", true))
+	mock.ExpectExec("UPDATE sessions SET last_seen_at").WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("POST", "/api/v1/auth/refresh", `{"refresh_token": "refresh-1"}`)
+	handlers.RefreshAPITokenHandler(c, db)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NotContains(t, w.Body.String(), "refresh-1")
+	assert.NotContains(t, w.Body.String(), `"token"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPITokenHandler_ReturnsRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery("FROM users").WithArgs("ivanov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}).
+			AddRow(12, "ivanov", string(hash), "", "teacher", "active", true, false, false))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("ivanov").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO sessions").WithArgs(12, "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(12, 4, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	c, w := setupTestContextJSON("POST", "/api/v1/auth/token", `{"username": "ivanov", "password": "secret"}`)
+	handlers.CreateAPITokenHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Equal(t, int(middleware.AccessTokenTTL.Seconds()), resp.ExpiresIn)
+	claims := &middleware.JWTClaims{}
+	assert.NoError(t, middleware.ParseJWT(resp.Token, claims))
+	assert.Equal(t, 4, claims.SessionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
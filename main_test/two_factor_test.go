@@ -0,0 +1,256 @@
+package main_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"scheduleApp/internal/handlers"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+var challengeColumns = []string{"id", "user_id", "username", "email", "role", "status", "totp_secret", "totp_enabled"}
+
+// expectPasswordLogin ожидает проверку верного пароля пользователя ivanov с заданным
+// состоянием второго фактора.
+func expectPasswordLogin(t *testing.T, mock sqlmock.Sqlmock, role string, enabled, required bool) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectQuery("FROM users").WithArgs("ivanov").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "status", "email_verified", "totp_enabled", "totp_required"}).
+			AddRow(12, "ivanov", string(hash), "", role, "active", true, enabled, required))
+}
+
+func TestLoginFormHandler_AsksForSecondFactor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectPasswordLogin(t, mock, "admin", true, false)
+	mock.ExpectExec("INSERT INTO login_challenges").WithArgs(12, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := setupTestFormContext("/login", url.Values{"username": {"ivanov"}, "password": {"secret"}})
+	handlers.LoginFormHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `action="/login/totp"`)
+	assert.Contains(t, w.Body.String(), `name="challenge"`)
+	assert.NotContains(t, w.Body.String(), "data:image/png")
+	assert.Empty(t, w.Header().Get("Set-Cookie"), "токены выдаются только после второго шага")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginFormHandler_RequiredRoleEnrolsOnLogin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectPasswordLogin(t, mock, "teacher", false, true)
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("ivanov").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE users SET totp_secret").WithArgs(12, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"totp_secret"}).AddRow(testTOTPSecret))
+	mock.ExpectExec("INSERT INTO login_challenges").WithArgs(12, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := setupTestFormContext("/login", url.Values{"username": {"ivanov"}, "password": {"secret"}})
+	handlers.LoginFormHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `src="data:image/png;base64,`)
+	assert.Contains(t, w.Body.String(), testTOTPSecret)
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginTOTPHandler_ValidCodeIssuesTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now())
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("FROM login_challenges").WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows(challengeColumns).AddRow(7, 12, "ivanov", "", "admin", "active", testTOTPSecret, true))
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectExec("UPDATE users SET totp_last_step").WithArgs(12, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM login_challenges").WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("ivanov").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO sessions").WithArgs(12, "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(12, 4, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	c, w := setupTestFormContext("/login/totp", url.Values{"challenge": {"challenge-token"}, "code": {code}})
+	handlers.LoginTOTPHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Привет, Администратор!")
+	if cookies := w.Header().Values("Set-Cookie"); assert.Len(t, cookies, 2) {
+		assert.Regexp(t, "^token=.+", cookies[0])
+		assert.Regexp(t, "^refresh_token=.+", cookies[1])
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginTOTPHandler_ReusedCodeRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now())
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("FROM login_challenges").WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows(challengeColumns).AddRow(7, 12, "ivanov", "", "admin", "active", testTOTPSecret, true))
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	// Интервал этого кода уже принят при предыдущем входе.
+	mock.ExpectExec("UPDATE users SET totp_last_step").WithArgs(12, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLoginFailure(mock, "ivanov", 1, 0)
+	mock.ExpectExec("UPDATE login_challenges SET attempts").WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := setupTestFormContext("/login/totp", url.Values{"challenge": {"challenge-token"}, "code": {code}})
+	handlers.LoginTOTPHandler(c, db)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Неверный код подтверждения")
+	assert.Contains(t, w.Body.String(), `value="challenge-token"`)
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPITokenTOTPHandler_RecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectPasswordLogin(t, mock, "teacher", true, true)
+	mock.ExpectExec("INSERT INTO login_challenges").WithArgs(12, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := setupTestContextJSON("POST", "/api/v1/auth/token", `{"username": "ivanov", "password": "secret"}`)
+	handlers.CreateAPITokenHandler(c, db)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var first struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		Challenge         string `json:"challenge"`
+		RefreshToken      string `json:"refresh_token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	assert.True(t, first.TwoFactorRequired)
+	assert.NotEmpty(t, first.Challenge)
+	assert.Empty(t, first.RefreshToken)
+
+	sum := sha256.Sum256([]byte("abcdefghij"))
+	mock.ExpectQuery("FROM login_challenges").WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows(challengeColumns).AddRow(7, 12, "ivanov", "", "teacher", "active", testTOTPSecret, true))
+	mock.ExpectQuery("FROM login_throttle").WithArgs("ivanov", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wait"}).AddRow(0))
+	mock.ExpectExec("UPDATE totp_recovery_codes SET used_at").WithArgs(12, hex.EncodeToString(sum[:])).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM login_challenges").WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM login_throttle").WithArgs("ivanov").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO sessions").WithArgs(12, "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(12, 4, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	body := `{"challenge": "` + first.Challenge + `", "code": "ABCDE-FGHIJ"}`
+	c, w = setupTestContextJSON("POST", "/api/v1/auth/token/totp", body)
+	handlers.CreateAPITokenTOTPHandler(c, db)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh_token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisableTwoFactorHandler_RefusedWhenRequiredForRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM users u").WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "role", "totp_secret", "enabled", "required", "recovery_left"}).
+			AddRow("ivanov", "admin", testTOTPSecret, true, true, 8))
+
+	c, w := setupTestFormContext("/two-factor/disable", url.Values{"code": {"abcde-fghij"}})
+	c.Set("user_id", 12)
+	c.Set("role", "admin")
+	handlers.DisableTwoFactorHandler(c, db)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Для вашей роли двухфакторная аутентификация обязательна")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTwoFactorPolicyHandler_RevokesSessionsWithoutSecondFactor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE two_factor_policy").WithArgs(pq.Array([]string{"admin", "teacher"})).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	// Преподаватели без второго фактора не должны продлевать уже открытые сеансы.
+	mock.ExpectExec("UPDATE sessions s SET revoked_at").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	c, w := setupTestFormContext("/admin/two-factor-policy", url.Values{"required_roles": {"admin", "teacher"}})
+	handlers.UpdateTwoFactorPolicyHandler(c, db)
+
+	assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Contains(t, location.Query().Get("alarm"), "Завершено сеансов пользователей без второго фактора: 2")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetUserTwoFactorAPIHandler_EndsSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET totp_secret = NULL").WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM totp_recovery_codes").WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 10))
+	// Устройство потеряно: открытые на нём сеансы не должны продлеваться.
+	mock.ExpectExec("UPDATE sessions SET revoked_at").WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	c, _ := setupTestContextJSON("POST", "/api/v1/users/12/two-factor/reset", "")
+	c.Params = gin.Params{{Key: "id", Value: "12"}}
+	handlers.ResetUserTwoFactorAPIHandler(c, db)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}